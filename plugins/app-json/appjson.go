@@ -2,17 +2,27 @@ package appjson
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/dokku/dokku/plugins/common"
+	"github.com/hashicorp/go-multierror"
 	"github.com/tailscale/hujson"
 	"k8s.io/utils/ptr"
 )
 
 var (
+	// EnvVarTypes are the types an env var can be declared as
+	EnvVarTypes = []string{"string", "int", "bool", "url", "enum"}
+
 	// DefaultProperties is a map of all valid app-json properties with corresponding default property values
 	DefaultProperties = map[string]string{
 		"appjson-path": "",
@@ -41,6 +51,15 @@ type EnvVarValue struct {
 
 	// Sync indicates if the value should be set on every deploy, not just first
 	Sync bool `json:"sync,omitempty"`
+
+	// Type is the type values must conform to, one of string, int, bool, url or enum
+	Type string `json:"type,omitempty"`
+
+	// Pattern is a regular expression values must match in full
+	Pattern string `json:"pattern,omitempty"`
+
+	// Enum is the list of allowed values
+	Enum []string `json:"enum,omitempty"`
 }
 
 // UnmarshalJSON handles both string and object formats for env vars
@@ -63,6 +82,94 @@ func (e *EnvVarValue) IsRequired() bool {
 	return *e.Required
 }
 
+// ValidateDefinition checks that the type, pattern and enum of the env var can be enforced
+func (e *EnvVarValue) ValidateDefinition() error {
+	switch e.Type {
+	case "", "string", "int", "bool", "url":
+	case "enum":
+		if len(e.Enum) == 0 {
+			return errors.New("type enum requires a list of enum values")
+		}
+	default:
+		return fmt.Errorf("invalid type '%s', expected one of: %s", e.Type, strings.Join(EnvVarTypes, ", "))
+	}
+
+	if e.Pattern != "" {
+		if _, err := regexp.Compile(e.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateValue checks a value against the type, pattern and enum of the env var.
+// The value itself is never included in the error, as it may be a secret.
+func (e *EnvVarValue) ValidateValue(value string) error {
+	switch e.Type {
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("expected an integer")
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.New("expected a boolean")
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || (u.Host == "" && u.Path == "") {
+			return errors.New("expected a url")
+		}
+	}
+
+	if len(e.Enum) > 0 && !slices.Contains(e.Enum, value) {
+		return fmt.Errorf("expected one of: %s", strings.Join(e.Enum, ", "))
+	}
+
+	if e.Pattern != "" {
+		r, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", e.Pattern))
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !r.MatchString(value) {
+			return fmt.Errorf("expected a value matching the pattern %s", e.Pattern)
+		}
+	}
+	return nil
+}
+
+// ValidateEnv checks config values against the env definitions of an app.json,
+// returning a multierror holding every invalid value. Secret references are skipped as
+// their values are only known once resolved. If checkRequired is true, required
+// env vars that are unset and would not be filled in on deploy are reported too.
+func ValidateEnv(definitions map[string]EnvVarValue, env map[string]string, checkRequired bool) error {
+	keys := []string{}
+	for key := range definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result *multierror.Error
+	for _, key := range keys {
+		envVar := definitions[key]
+		value, ok := env[key]
+		if !ok {
+			if checkRequired && envVar.IsRequired() && envVar.Value == "" && envVar.Generator == "" {
+				result = multierror.Append(result, fmt.Errorf("Missing value for required env var %s", key))
+			}
+			continue
+		}
+
+		if strings.HasPrefix(value, "secretref://") {
+			continue
+		}
+
+		if err := envVar.ValidateValue(value); err != nil {
+			result = multierror.Append(result, fmt.Errorf("Invalid value for %s: %w", key, err))
+		}
+	}
+	return result.ErrorOrNil()
+}
+
 // AppJSON is a struct that represents an app.json file as understood by Dokku
 type AppJSON struct {
 	// Buildpacks is a list of buildpacks to use for the app
@@ -115,6 +222,15 @@ type CronTask struct {
 
 	// ConcurrencyPolicy is the concurrency policy for the cron command
 	ConcurrencyPolicy string `json:"concurrency_policy"`
+
+	// Timezone is the tz database name the schedule is evaluated in
+	Timezone string `json:"timezone,omitempty"`
+
+	// Retries is the number of times a failed cron task is retried
+	Retries int `json:"retries,omitempty"`
+
+	// RetryBackoffSeconds is the number of seconds to wait before the first retry
+	RetryBackoffSeconds *int `json:"retry_backoff_seconds,omitempty"`
 }
 
 // Formation is a struct that represents the scale for a process from an app.json file
@@ -335,5 +451,11 @@ func ReadAppJSON(path string) (AppJSON, error) {
 		return AppJSON{}, fmt.Errorf("Cannot parse app.json: %v", err)
 	}
 
+	for key, envVar := range appJSON.Env {
+		if err := envVar.ValidateDefinition(); err != nil {
+			return AppJSON{}, fmt.Errorf("Invalid env definition for %s in app.json: %v", key, err)
+		}
+	}
+
 	return appJSON, nil
 }
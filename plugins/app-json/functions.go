@@ -137,7 +137,7 @@ func cleanupDeploymentContainer(containerID string, phase string) error {
 	return nil
 }
 
-func executeScript(appName string, image string, imageTag string, phase string) error {
+func executeScript(appName string, image string, imageTag string, phase string) (err error) {
 	common.LogInfo1(fmt.Sprintf("Checking for %s task", phase))
 	command := ""
 	phaseSource := ""
@@ -157,6 +157,17 @@ func executeScript(appName string, image string, imageTag string, phase string)
 		return nil
 	}
 
+	if phase == "predeploy" || phase == "postdeploy" {
+		recordBuildPhase(appName, phase, "start", "")
+		defer func() {
+			outcome := "succeeded"
+			if err != nil {
+				outcome = "failed"
+			}
+			recordBuildPhase(appName, phase, "end", outcome)
+		}()
+	}
+
 	if phase == "predeploy" {
 		common.LogVerbose(fmt.Sprintf("Executing %s task from %s: %s", phase, phaseSource, command))
 	} else {
@@ -324,6 +335,20 @@ func executeScript(appName string, image string, imageTag string, phase string)
 	return nil
 }
 
+// recordBuildPhase notes the start or end of an app.json task on the in-flight
+// build record. Timing is informational, so failures are ignored.
+func recordBuildPhase(appName string, phase string, event string, outcome string) {
+	buildID := os.Getenv("DOKKU_BUILD_ID")
+	if buildID == "" {
+		return
+	}
+
+	common.CallPlugnTrigger(common.PlugnTriggerInput{
+		Trigger: "builds-record-phase",
+		Args:    []string{appName, buildID, phase, event, outcome},
+	})
+}
+
 func getEntrypointFromImage(image string) (string, error) {
 	output, err := common.DockerInspect(image, "{{json .Config.Entrypoint}}")
 	if err != nil {
@@ -639,6 +664,18 @@ func processAppJSONEnv(appName string) error {
 		}
 	}
 
+	// Validate the values the app will be deployed with against the env definitions
+	deployEnv := map[string]string{}
+	for key, value := range currentConfig {
+		deployEnv[key] = value
+	}
+	for key, value := range varsToSet {
+		deployEnv[key] = value
+	}
+	if err := ValidateEnv(appJSON.Env, deployEnv, false); err != nil {
+		return err
+	}
+
 	if len(varsToSet) == 0 {
 		common.LogVerbose("No env vars to set from app.json")
 		if !isFirstDeploy {
@@ -3,6 +3,7 @@ package builds
 import (
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -24,11 +25,27 @@ const (
 
 var (
 	DefaultProperties = map[string]string{
-		"retention": strconv.Itoa(DefaultRetention),
+		"retention":      strconv.Itoa(DefaultRetention),
+		"queue-mode":     "false",
+		"queue-coalesce": "false",
+		"log-format":     LogFormatText,
+
+		"retention-max-age":   "",
+		"retention-max-bytes": "",
+		"compress-after":      "",
+
+		"webhook-url":    "",
+		"webhook-secret": "",
 	}
 
 	GlobalProperties = map[string]bool{
-		"retention": true,
+		"retention":           true,
+		"retention-max-age":   true,
+		"retention-max-bytes": true,
+		"compress-after":      true,
+		"log-format":          true,
+		"webhook-url":         true,
+		"webhook-secret":      true,
 	}
 )
 
@@ -51,6 +68,7 @@ func (k BuildKind) Valid() bool {
 type BuildStatus string
 
 const (
+	BuildStatusQueued    BuildStatus = "queued"
 	BuildStatusRunning   BuildStatus = "running"
 	BuildStatusSucceeded BuildStatus = "succeeded"
 	BuildStatusFailed    BuildStatus = "failed"
@@ -61,12 +79,18 @@ const (
 // Valid reports whether the status is a persistable value.
 func (s BuildStatus) Valid() bool {
 	switch s {
-	case BuildStatusRunning, BuildStatusSucceeded, BuildStatusFailed, BuildStatusCanceled:
+	case BuildStatusQueued, BuildStatusRunning, BuildStatusSucceeded, BuildStatusFailed, BuildStatusCanceled:
 		return true
 	}
 	return false
 }
 
+// IsInFlight reports whether the status represents a build that has not yet
+// been finalized, either because it is waiting on the deploy lock or running.
+func (s BuildStatus) IsInFlight() bool {
+	return s == BuildStatusQueued || s == BuildStatusRunning
+}
+
 // IsTerminal reports whether the status represents a finalized build.
 func (s BuildStatus) IsTerminal() bool {
 	switch s {
@@ -91,6 +115,7 @@ const (
 	BuildSourceGitFromArchive BuildSource = "git:from-archive"
 	BuildSourceGitFromImage   BuildSource = "git:from-image"
 	BuildSourceGitLoadImage   BuildSource = "git:load-image"
+	BuildSourceRollback       BuildSource = "builds:rollback"
 	BuildSourceUnknown        BuildSource = "unknown"
 )
 
@@ -105,6 +130,7 @@ var allBuildSources = []BuildSource{
 	BuildSourceGitFromArchive,
 	BuildSourceGitFromImage,
 	BuildSourceGitLoadImage,
+	BuildSourceRollback,
 	BuildSourceUnknown,
 }
 
@@ -145,17 +171,39 @@ type Build struct {
 	Status     BuildStatus `json:"status"`
 	Source     BuildSource `json:"source"`
 	ExitCode   *int        `json:"exit_code,omitempty"`
+	Image      string      `json:"image,omitempty"`
+	ImageID    string      `json:"image_id,omitempty"`
+	GitSHA     string      `json:"git_sha,omitempty"`
+	Builder    string      `json:"builder,omitempty"`
+	User       string      `json:"user,omitempty"`
+
+	// SupersededBy is set on queued builds that were coalesced into a newer
+	// queued build before they acquired the deploy lock.
+	SupersededBy string `json:"superseded_by,omitempty"`
+
+	// LogFormat is set to LogFormatJSONL when the build's output was captured
+	// as jsonl entries rather than plain text.
+	LogFormat string `json:"log_format,omitempty"`
+
+	// Phases holds the per-step timing of the build, in the order the steps
+	// started.
+	Phases []BuildPhase `json:"phases,omitempty"`
 }
 
 // DisplayStatus returns the status the operator should see, computing
-// BuildStatusAbandoned for running records whose PID is no longer alive.
+// BuildStatusAbandoned for in-flight records whose PID is no longer alive.
 func (b Build) DisplayStatus() BuildStatus {
-	if b.Status == BuildStatusRunning && !CheckPIDAlive(b.PID) {
+	if b.Status.IsInFlight() && !CheckPIDAlive(b.PID) {
 		return BuildStatusAbandoned
 	}
 	return b.Status
 }
 
+// IsLive reports whether the record is in flight and its process is alive.
+func (b Build) IsLive() bool {
+	return b.Status.IsInFlight() && CheckPIDAlive(b.PID)
+}
+
 // Duration returns the elapsed time between start and finish (or now, for
 // in-flight builds).
 func (b Build) Duration() time.Duration {
@@ -174,6 +222,20 @@ func (b Build) LogPath() string {
 	return filepath.Join(common.GetAppDataDirectory("builds", b.App), b.ID+".log")
 }
 
+// ShortSHA returns the abbreviated git sha used in tabular output.
+func (b Build) ShortSHA() string {
+	if len(b.GitSHA) > 7 {
+		return b.GitSHA[:7]
+	}
+	return b.GitSHA
+}
+
+// RollbackImageTag returns the per-build tag the deployed image is retained
+// under so builds:rollback can find it after later builds move :latest.
+func RollbackImageTag(buildID string) string {
+	return common.RetainedImageTagPrefix + buildID
+}
+
 // GenerateBuildID produces a sortable base36 ULID-style id.
 //
 // Format: <8 base36 chars of ms timestamp><6 base36 chars of randomness>.
@@ -372,8 +434,8 @@ func parseRetention(raw string, scope string) (int, bool) {
 	return n, true
 }
 
-// ReapAbandonedBuilds finalizes any record whose on-disk status is running but
-// whose PID is no longer alive. Records are written with status=failed,
+// ReapAbandonedBuilds finalizes any record whose on-disk status is queued or
+// running but whose PID is no longer alive. Records are written with status=failed,
 // exit_code=-1, finished_at=now. Returns the number of records reaped.
 func ReapAbandonedBuilds(appName string) (int, error) {
 	builds, err := FetchBuilds(appName)
@@ -385,7 +447,7 @@ func ReapAbandonedBuilds(appName string) (int, error) {
 	exitCode := -1
 	reaped := 0
 	for _, b := range builds {
-		if b.Status != BuildStatusRunning {
+		if !b.Status.IsInFlight() {
 			continue
 		}
 		if CheckPIDAlive(b.PID) {
@@ -404,8 +466,9 @@ func ReapAbandonedBuilds(appName string) (int, error) {
 }
 
 // PruneAppBuilds reaps abandoned records, then prunes finalized records beyond
-// the retention cap. Live in-flight builds (status=running with alive PID) are
-// always preserved.
+// the retention cap, age and size limits and compresses the logs selected by
+// the compress-after policy. Live in-flight builds (status=queued or running
+// with alive PID) are always preserved.
 func PruneAppBuilds(appName string) error {
 	if _, err := ReapAbandonedBuilds(appName); err != nil {
 		return err
@@ -418,20 +481,51 @@ func PruneAppBuilds(appName string) error {
 
 	retention := ResolveRetention(appName)
 
+	var liveBytes int64
 	finalized := make([]Build, 0, len(builds))
 	for _, b := range builds {
-		if b.Status == BuildStatusRunning && CheckPIDAlive(b.PID) {
+		if b.IsLive() {
+			liveBytes += buildSize(appName, b.ID)
 			continue
 		}
 		finalized = append(finalized, b)
 	}
 
-	if len(finalized) <= retention {
+	if len(finalized) > retention {
+		for _, b := range finalized[retention:] {
+			removeBuildFiles(appName, b.ID)
+			removeRollbackImage(appName, b)
+		}
+		finalized = finalized[:retention]
+	}
+
+	now := time.Now().UTC()
+	policy := ResolveCompressPolicy(appName)
+	for i, b := range finalized {
+		if !policy.shouldCompress(b, i, now) {
+			continue
+		}
+		if err := CompressBuildLog(appName, b.ID); err != nil {
+			common.LogWarn(fmt.Sprintf("Could not compress build log %s/%s.log: %s", appName, b.ID, err))
+		}
+	}
+
+	maxAge := ResolveRetentionMaxAge(appName)
+	maxBytes := ResolveRetentionMaxBytes(appName)
+	if maxAge == 0 && maxBytes == 0 {
 		return nil
 	}
 
-	for _, b := range finalized[retention:] {
+	usedBytes := liveBytes
+	sizes := make([]int64, len(finalized))
+	for i, b := range finalized {
+		sizes[i] = buildSize(appName, b.ID)
+		usedBytes += sizes[i]
+	}
+	keep := applyRetentionLimits(finalized, sizes, usedBytes, maxAge, maxBytes, now)
+	for _, b := range finalized[keep:] {
 		removeBuildFiles(appName, b.ID)
+		removeRollbackImage(appName, b)
 	}
 	return nil
 }
@@ -443,4 +537,108 @@ func removeBuildFiles(appName, buildID string) {
 	if err := os.Remove(LogPathFor(appName, buildID)); err != nil && !os.IsNotExist(err) {
 		common.LogWarn(fmt.Sprintf("Could not remove build log %s/%s.log: %s", appName, buildID, err))
 	}
+	if err := os.Remove(LogPathFor(appName, buildID) + CompressedLogSuffix); err != nil && !os.IsNotExist(err) {
+		common.LogWarn(fmt.Sprintf("Could not remove build log %s/%s.log%s: %s", appName, buildID, CompressedLogSuffix, err))
+	}
+	if err := os.Remove(InputsPathFor(appName, buildID)); err != nil && !os.IsNotExist(err) {
+		common.LogWarn(fmt.Sprintf("Could not remove build inputs %s/%s%s: %s", appName, buildID, InputsSuffix, err))
+	}
+}
+
+// removeRollbackImage drops the per-build tag of a pruned build so the image
+// can be cleaned up once nothing else references it. Builds whose image could
+// not be tagged recorded the deployed image instead, which is left alone.
+func removeRollbackImage(appName string, b Build) {
+	if b.Image != fmt.Sprintf("%s:%s", common.GetAppImageRepo(appName), RollbackImageTag(b.ID)) {
+		return
+	}
+	if !common.VerifyImage(b.Image) {
+		return
+	}
+	if err := common.RemoveImages([]string{b.Image}); err != nil {
+		common.LogWarn(fmt.Sprintf("Could not remove image %s for build %s/%s: %s", b.Image, appName, b.ID, err))
+	}
+}
+
+// FindRollbackTarget picks the build to roll back to from a newest-first list
+// of records. An explicit build id must reference a succeeded build with a
+// recorded image. Otherwise the build that produced the deployed image is
+// located, and the newest older succeeded build with a different image is
+// chosen. Rollback records only redeploy an earlier image, so they are never
+// picked implicitly; this keeps repeated rollbacks stepping further back
+// instead of toggling between two images.
+func FindRollbackTarget(builds []Build, buildID string, deployedImageID string) (Build, error) {
+	if buildID != "" {
+		for _, b := range builds {
+			if b.ID != buildID {
+				continue
+			}
+			if b.Status != BuildStatusSucceeded {
+				return Build{}, fmt.Errorf("Build %s did not succeed (status=%s)", buildID, b.DisplayStatus())
+			}
+			if b.Image == "" {
+				return Build{}, fmt.Errorf("Build %s has no recorded image", buildID)
+			}
+			return b, nil
+		}
+		return Build{}, fmt.Errorf("No build record found for %s", buildID)
+	}
+
+	candidates := make([]Build, 0, len(builds))
+	for _, b := range builds {
+		if b.Status != BuildStatusSucceeded || b.Image == "" {
+			continue
+		}
+		if deployedImageID == "" {
+			deployedImageID = b.ImageID
+		}
+		if b.Source != BuildSourceRollback {
+			candidates = append(candidates, b)
+		}
+	}
+
+	current := 0
+	if deployedImageID != "" {
+		for i, b := range candidates {
+			if b.ImageID == deployedImageID {
+				current = i
+				break
+			}
+		}
+	}
+
+	if len(candidates) > 0 {
+		for _, b := range candidates[current+1:] {
+			if deployedImageID == "" || b.ImageID != deployedImageID {
+				return b, nil
+			}
+		}
+	}
+	return Build{}, errors.New("No previous successful build with a recorded image to roll back to")
+}
+
+// ResolveGitSHA returns the commit currently checked out in the app's repo, or
+// an empty string for apps that are not deployed from git.
+func ResolveGitSHA(appName string) string {
+	result, err := common.CallExecCommand(common.ExecCommandInput{
+		Command:          "git",
+		Args:             []string{"rev-parse", "HEAD"},
+		WorkingDirectory: common.AppRoot(appName),
+	})
+	if err != nil || result.ExitCode != 0 {
+		return ""
+	}
+	return result.StdoutContents()
+}
+
+// ResolveBuilder returns the builder used for the app, preferring the detected
+// builder over the per-app and global selections.
+func ResolveBuilder(appName string) string {
+	if builder := common.PropertyGet("builder", appName, "detected"); builder != "" {
+		return builder
+	}
+	if builder := common.PropertyGet("builder", appName, "selected"); builder != "" {
+		return builder
+	}
+	return common.PropertyGet("builder", "--global", "selected")
 }
@@ -2,6 +2,10 @@ package builds
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -17,6 +21,7 @@ func setupTestRoot(t *testing.T) string {
 		t.Fatalf("mkdirtemp: %v", err)
 	}
 	t.Setenv("DOKKU_LIB_ROOT", tmpDir)
+	t.Setenv("DOKKU_ROOT", filepath.Join(tmpDir, "home"))
 	t.Setenv("PLUGIN_PATH", filepath.Join(tmpDir, "plugins"))
 	t.Setenv("PLUGIN_ENABLED_PATH", filepath.Join(tmpDir, "plugins", "enabled"))
 	t.Cleanup(func() { os.RemoveAll(tmpDir) })
@@ -39,6 +44,7 @@ func TestBuildKindValid(t *testing.T) {
 
 func TestBuildStatusValid(t *testing.T) {
 	cases := map[BuildStatus]bool{
+		BuildStatusQueued:    true,
 		BuildStatusRunning:   true,
 		BuildStatusSucceeded: true,
 		BuildStatusFailed:    true,
@@ -56,6 +62,7 @@ func TestBuildStatusValid(t *testing.T) {
 
 func TestBuildStatusIsTerminal(t *testing.T) {
 	cases := map[BuildStatus]bool{
+		BuildStatusQueued:    false,
 		BuildStatusRunning:   false,
 		BuildStatusSucceeded: true,
 		BuildStatusFailed:    true,
@@ -89,7 +96,7 @@ func TestBuildSourceDefaultKind(t *testing.T) {
 	}
 	deploy := []BuildSource{
 		BuildSourcePsRestart, BuildSourcePsStart, BuildSourceDeploy,
-		BuildSourceConfigRedeploy, BuildSourceUnknown,
+		BuildSourceConfigRedeploy, BuildSourceRollback, BuildSourceUnknown,
 	}
 	for _, s := range build {
 		if got := s.DefaultKind(); got != BuildKindBuild {
@@ -609,3 +616,839 @@ func TestBuildJSONShape(t *testing.T) {
 		t.Errorf("kind not serialized as enum string: %s", string(raw))
 	}
 }
+
+func TestFindRollbackTarget(t *testing.T) {
+	base := time.Now().UTC().Truncate(time.Second)
+	builds := []Build{
+		{ID: "d", StartedAt: base, Status: BuildStatusFailed, Image: "dokku/app:build-d"},
+		{ID: "c", StartedAt: base.Add(-1 * time.Hour), Status: BuildStatusSucceeded, Image: "dokku/app:build-c"},
+		{ID: "b", StartedAt: base.Add(-2 * time.Hour), Status: BuildStatusSucceeded},
+		{ID: "a", StartedAt: base.Add(-3 * time.Hour), Status: BuildStatusSucceeded, Image: "dokku/app:build-a"},
+	}
+
+	got, err := FindRollbackTarget(builds, "", "")
+	if err != nil {
+		t.Fatalf("implicit target: %v", err)
+	}
+	if got.ID != "a" {
+		t.Errorf("implicit target = %q, want a (previous succeeded build with an image)", got.ID)
+	}
+
+	got, err = FindRollbackTarget(builds, "c", "")
+	if err != nil {
+		t.Fatalf("explicit target: %v", err)
+	}
+	if got.ID != "c" {
+		t.Errorf("explicit target = %q, want c", got.ID)
+	}
+
+	for _, bad := range []string{"d", "b", "missing"} {
+		if _, err := FindRollbackTarget(builds, bad, ""); err == nil {
+			t.Errorf("expected an error rolling back to %q", bad)
+		}
+	}
+
+	if _, err := FindRollbackTarget(builds[:2], "", ""); err == nil {
+		t.Errorf("expected an error when only one succeeded build has an image")
+	}
+}
+
+func TestFindRollbackTargetRepeated(t *testing.T) {
+	base := time.Now().UTC().Truncate(time.Second)
+	builds := []Build{
+		{ID: "c", StartedAt: base.Add(-1 * time.Hour), Status: BuildStatusSucceeded, Source: BuildSourceGitHook, Image: "dokku/app:build-c", ImageID: "sha256:c"},
+		{ID: "b", StartedAt: base.Add(-2 * time.Hour), Status: BuildStatusSucceeded, Source: BuildSourceGitHook, Image: "dokku/app:build-b", ImageID: "sha256:b"},
+		{ID: "a", StartedAt: base.Add(-3 * time.Hour), Status: BuildStatusSucceeded, Source: BuildSourceGitHook, Image: "dokku/app:build-a", ImageID: "sha256:a"},
+	}
+
+	got, err := FindRollbackTarget(builds, "", "sha256:c")
+	if err != nil {
+		t.Fatalf("first rollback: %v", err)
+	}
+	if got.ID != "b" {
+		t.Fatalf("first rollback target = %q, want b", got.ID)
+	}
+
+	builds = append([]Build{{ID: "r1", StartedAt: base, Status: BuildStatusSucceeded, Source: BuildSourceRollback, Image: "dokku/app:build-r1", ImageID: "sha256:b"}}, builds...)
+	got, err = FindRollbackTarget(builds, "", "sha256:b")
+	if err != nil {
+		t.Fatalf("second rollback: %v", err)
+	}
+	if got.ID != "a" {
+		t.Errorf("second rollback target = %q, want a rather than toggling back to c", got.ID)
+	}
+
+	got, err = FindRollbackTarget(builds, "", "")
+	if err != nil {
+		t.Fatalf("second rollback without a deployed image id: %v", err)
+	}
+	if got.ID != "a" {
+		t.Errorf("second rollback target = %q, want a when the deployed image id comes from the records", got.ID)
+	}
+
+	builds = append([]Build{{ID: "r2", StartedAt: base.Add(time.Hour), Status: BuildStatusSucceeded, Source: BuildSourceRollback, Image: "dokku/app:build-r2", ImageID: "sha256:a"}}, builds...)
+	if _, err := FindRollbackTarget(builds, "", "sha256:a"); err == nil {
+		t.Error("expected an error once the oldest build is deployed")
+	}
+}
+
+func TestFindRollbackTargetSkipsSameImage(t *testing.T) {
+	base := time.Now().UTC().Truncate(time.Second)
+	builds := []Build{
+		{ID: "c", StartedAt: base, Status: BuildStatusSucceeded, Source: BuildSourcePsRebuild, Image: "dokku/app:build-c", ImageID: "sha256:b"},
+		{ID: "b", StartedAt: base.Add(-1 * time.Hour), Status: BuildStatusSucceeded, Source: BuildSourceGitHook, Image: "dokku/app:build-b", ImageID: "sha256:b"},
+		{ID: "a", StartedAt: base.Add(-2 * time.Hour), Status: BuildStatusSucceeded, Source: BuildSourceGitHook, Image: "dokku/app:build-a", ImageID: "sha256:a"},
+	}
+
+	got, err := FindRollbackTarget(builds, "", "sha256:b")
+	if err != nil {
+		t.Fatalf("FindRollbackTarget: %v", err)
+	}
+	if got.ID != "a" {
+		t.Errorf("target = %q, want a since b deployed the same image", got.ID)
+	}
+}
+
+func TestListFilterMatches(t *testing.T) {
+	setupTestRoot(t)
+	v := toListView(Build{
+		ID:      "f",
+		App:     "myapp",
+		Kind:    BuildKindBuild,
+		Status:  BuildStatusSucceeded,
+		GitSHA:  "0123456789abcdef",
+		Builder: "herokuish",
+		User:    "admin",
+	})
+
+	cases := []struct {
+		filter ListFilter
+		want   bool
+	}{
+		{ListFilter{}, true},
+		{ListFilter{SHA: "0123456"}, true},
+		{ListFilter{SHA: "abcdef"}, false},
+		{ListFilter{Builder: "herokuish", User: "admin"}, true},
+		{ListFilter{Builder: "dockerfile"}, false},
+		{ListFilter{User: "deployer"}, false},
+		{ListFilter{Kind: "build", Status: "succeeded"}, true},
+		{ListFilter{Kind: "deploy"}, false},
+	}
+	for _, c := range cases {
+		if got := c.filter.matches(v); got != c.want {
+			t.Errorf("%+v.matches() = %v, want %v", c.filter, got, c.want)
+		}
+	}
+}
+
+func TestRecordStartCapturesUser(t *testing.T) {
+	setupTestRoot(t)
+	t.Setenv("SSH_NAME", "deployer")
+	app := "who"
+	id := GenerateBuildID()
+	if err := TriggerBuildsRecordStart(app, id, strconv.Itoa(os.Getpid()), string(BuildSourceGitHook)); err != nil {
+		t.Fatalf("record-start: %v", err)
+	}
+	got, err := ReadBuild(app, id)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got.User != "deployer" {
+		t.Errorf("User = %q, want deployer", got.User)
+	}
+}
+
+func TestWaitResult(t *testing.T) {
+	exit := func(code int) *int { return &code }
+	cases := []struct {
+		build    Build
+		done     bool
+		exitCode int
+	}{
+		{Build{ID: "ok", Status: BuildStatusSucceeded, ExitCode: exit(0)}, true, 0},
+		{Build{ID: "fail", Status: BuildStatusFailed, ExitCode: exit(2)}, true, 2},
+		{Build{ID: "reaped", Status: BuildStatusFailed, ExitCode: exit(-1)}, true, WaitExitCodeAbandoned},
+		{Build{ID: "cancel", Status: BuildStatusCanceled, ExitCode: exit(-1)}, true, WaitExitCodeCanceled},
+		{Build{ID: "ghost", Status: BuildStatusRunning, PID: pickDeadPID(t)}, true, WaitExitCodeAbandoned},
+		{Build{ID: "live", Status: BuildStatusRunning, PID: os.Getpid()}, false, 0},
+	}
+	for _, c := range cases {
+		done, err := waitResult(c.build)
+		if done != c.done {
+			t.Errorf("%s: done = %v, want %v", c.build.ID, done, c.done)
+			continue
+		}
+		got := 0
+		if err != nil {
+			waitErr, ok := err.(*WaitFailed)
+			if !ok {
+				t.Fatalf("%s: unexpected error type %T", c.build.ID, err)
+			}
+			got = waitErr.ExitCode()
+		}
+		if got != c.exitCode {
+			t.Errorf("%s: exit code = %d, want %d", c.build.ID, got, c.exitCode)
+		}
+	}
+}
+
+func TestCopyLogFrom(t *testing.T) {
+	tmp := setupTestRoot(t)
+	logPath := filepath.Join(tmp, "build.log")
+
+	var out strings.Builder
+	offset, err := copyLogFrom(logPath, 0, &out)
+	if err != nil || offset != 0 {
+		t.Fatalf("missing log: offset=%d err=%v", offset, err)
+	}
+
+	if err := os.WriteFile(logPath, []byte("one\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if offset, err = copyLogFrom(logPath, offset, &out); err != nil {
+		t.Fatalf("first copy: %v", err)
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	f.WriteString("two\n")
+	f.Close()
+
+	if offset, err = copyLogFrom(logPath, offset, &out); err != nil {
+		t.Fatalf("second copy: %v", err)
+	}
+	if out.String() != "one\ntwo\n" || offset != 8 {
+		t.Errorf("copied %q (offset %d), want %q (offset 8)", out.String(), offset, "one\ntwo\n")
+	}
+}
+
+func TestQueueFIFOAndCoalesce(t *testing.T) {
+	tmp := setupTestRoot(t)
+	app := "queued"
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, id := range []string{"q1", "q2", "q3"} {
+		b := Build{
+			ID:        id,
+			App:       app,
+			Kind:      BuildKindBuild,
+			PID:       os.Getpid(),
+			StartedAt: base.Add(time.Duration(i) * time.Second),
+			Status:    BuildStatusRunning,
+			Source:    BuildSourceGitHook,
+		}
+		if err := WriteBuild(b); err != nil {
+			t.Fatalf("write %s: %v", id, err)
+		}
+	}
+
+	for _, id := range []string{"q1", "q2"} {
+		if err := EnqueueBuild(app, id); err != nil {
+			t.Fatalf("enqueue %s: %v", id, err)
+		}
+	}
+	for id, want := range map[string]string{"q1": "0", "q2": "1"} {
+		got, err := QueuePosition(app, id)
+		if err != nil {
+			t.Fatalf("position %s: %v", id, err)
+		}
+		if got != want {
+			t.Errorf("position %s = %q, want %q", id, got, want)
+		}
+	}
+
+	if err := DequeueBuild(app, "q1"); err != nil {
+		t.Fatalf("dequeue q1: %v", err)
+	}
+	if got, _ := QueuePosition(app, "q2"); got != "0" {
+		t.Errorf("position q2 after q1 dequeued = %q, want 0", got)
+	}
+
+	appDir := filepath.Join(tmp, "config", "builds", app)
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("mkdir app: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "queue-coalesce"), []byte("true"), 0644); err != nil {
+		t.Fatalf("write queue-coalesce: %v", err)
+	}
+	if err := EnqueueBuild(app, "q3"); err != nil {
+		t.Fatalf("enqueue q3: %v", err)
+	}
+	if got, _ := QueuePosition(app, "q2"); got != QueuePositionSuperseded {
+		t.Errorf("position q2 after coalesce = %q, want %q", got, QueuePositionSuperseded)
+	}
+	if got, _ := QueuePosition(app, "q3"); got != "0" {
+		t.Errorf("position q3 after coalesce = %q, want 0", got)
+	}
+	superseded, err := ReadBuild(app, "q2")
+	if err != nil {
+		t.Fatalf("read q2: %v", err)
+	}
+	if superseded.Status != BuildStatusCanceled || superseded.SupersededBy != "q3" {
+		t.Errorf("q2 = %s superseded_by=%q, want canceled superseded_by=q3", superseded.Status, superseded.SupersededBy)
+	}
+}
+
+func TestPhaseRecording(t *testing.T) {
+	setupTestRoot(t)
+	app := "phased"
+	id := GenerateBuildID()
+	t.Setenv("DOKKU_BUILD_ID", id)
+
+	if err := TriggerBuildsRecordStart(app, id, strconv.Itoa(os.Getpid()), string(BuildSourceGitHook)); err != nil {
+		t.Fatalf("record-start: %v", err)
+	}
+
+	// extract is never explicitly started, build is, and healthchecks fire
+	// once per container
+	steps := []func(string) error{
+		func(app string) error { return TriggerCorePostExtract(app, "0123456789abcdef") },
+		func(app string) error { return TriggerPreBuild("dockerfile", app) },
+		TriggerPostBuild,
+		TriggerPreReleaseBuilder,
+		TriggerPostReleaseBuilder,
+		func(app string) error { return TriggerSchedulerDeploy(app, "") },
+		TriggerCheckDeploy,
+		TriggerCheckDeploy,
+	}
+	for _, step := range steps {
+		if err := step(app); err != nil {
+			t.Fatalf("phase trigger: %v", err)
+		}
+	}
+
+	b, err := ReadBuild(app, id)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if b.GitSHA != "0123456789abcdef" || b.Builder != "dockerfile" {
+		t.Errorf("git_sha=%q builder=%q, want the extracted revision and build builder", b.GitSHA, b.Builder)
+	}
+	wantNames := []string{PhaseExtract, PhaseBuild, PhaseRelease, PhaseSchedulerDeploy, PhaseHealthchecks}
+	if len(b.Phases) != len(wantNames) {
+		t.Fatalf("phases = %+v, want %v", b.Phases, wantNames)
+	}
+	for i, name := range wantNames {
+		if b.Phases[i].Name != name {
+			t.Errorf("phase[%d] = %q, want %q", i, b.Phases[i].Name, name)
+		}
+	}
+	if !b.Phases[0].StartedAt.Equal(b.StartedAt) {
+		t.Errorf("extract started at %v, want build start %v", b.Phases[0].StartedAt, b.StartedAt)
+	}
+	if b.Phases[3].Outcome != PhaseOutcomeSucceeded || b.Phases[3].FinishedAt == nil {
+		t.Errorf("scheduler-deploy = %+v, want closed as succeeded", b.Phases[3])
+	}
+	if b.Phases[4].Outcome != PhaseOutcomeRunning || b.Phases[4].FinishedAt != nil {
+		t.Errorf("healthchecks = %+v, want running", b.Phases[4])
+	}
+
+	if err := TriggerBuildsRecordPhase(app, id, PhaseHealthchecks, "end", "bogus"); err == nil {
+		t.Errorf("expected invalid outcome to be rejected")
+	}
+
+	if err := TriggerBuildsRecordFinalize(app, id, "1"); err != nil {
+		t.Fatalf("finalize: %v", err)
+	}
+	b, err = ReadBuild(app, id)
+	if err != nil {
+		t.Fatalf("read after finalize: %v", err)
+	}
+	last := b.Phases[len(b.Phases)-1]
+	if last.Outcome != PhaseOutcomeFailed || last.FinishedAt == nil || !last.FinishedAt.Equal(*b.FinishedAt) {
+		t.Errorf("open phase after failed finalize = %+v, want failed at %v", last, b.FinishedAt)
+	}
+
+	// phases are not recorded on finalized builds
+	if err := StartPhase(app, id, PhaseProxyRebuild); err != nil {
+		t.Fatalf("start after finalize: %v", err)
+	}
+	if after, _ := ReadBuild(app, id); len(after.Phases) != len(wantNames) {
+		t.Errorf("phases after finalize = %d, want %d", len(after.Phases), len(wantNames))
+	}
+}
+
+func TestAnnotateLogRendersJSONL(t *testing.T) {
+	setupTestRoot(t)
+	app := "jsonl"
+	b := Build{
+		ID:        GenerateBuildID(),
+		App:       app,
+		Kind:      BuildKindBuild,
+		PID:       os.Getpid(),
+		StartedAt: time.Now().UTC(),
+		Status:    BuildStatusRunning,
+		Source:    BuildSourceGitHook,
+		LogFormat: LogFormatJSONL,
+	}
+	b.startPhase(PhaseBuild, b.StartedAt)
+	if err := WriteBuild(b); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := AnnotateLog(app, b.ID, "stdout", strings.NewReader("-----> Building\nno trailing newline")); err != nil {
+		t.Fatalf("annotate stdout: %v", err)
+	}
+	if err := AnnotateLog(app, b.ID, "stderr", strings.NewReader("warning\n")); err != nil {
+		t.Fatalf("annotate stderr: %v", err)
+	}
+
+	body, err := os.ReadFile(b.LogPath())
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("log has %d lines, want 3: %q", len(lines), body)
+	}
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(lines[2]), &entry); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if entry.Stream != "stderr" || entry.Phase != PhaseBuild || entry.Line != "warning" || entry.Timestamp.IsZero() {
+		t.Errorf("entry = %+v, want stderr line in the build phase", entry)
+	}
+
+	var rendered strings.Builder
+	offset, err := copyBuildLogFrom(b, 0, &rendered, false)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if rendered.String() != "-----> Building\nno trailing newline\nwarning\n" {
+		t.Errorf("rendered %q", rendered.String())
+	}
+	if offset != int64(len(body)) {
+		t.Errorf("offset = %d, want %d", offset, len(body))
+	}
+
+	var raw strings.Builder
+	if _, err := copyBuildLogFrom(b, 0, &raw, true); err != nil {
+		t.Fatalf("raw: %v", err)
+	}
+	if raw.String() != string(body) {
+		t.Errorf("raw output differs from the log file")
+	}
+}
+
+func TestParseRetentionLimits(t *testing.T) {
+	ages := map[string]time.Duration{"30d": 30 * 24 * time.Hour, "12h": 12 * time.Hour, "2w": 14 * 24 * time.Hour}
+	for value, want := range ages {
+		if got, err := ParseAge(value); err != nil || got != want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "30", "0d", "-1d", "30y", "d"} {
+		if _, err := ParseAge(value); err == nil {
+			t.Errorf("ParseAge(%q) expected error", value)
+		}
+	}
+
+	sizes := map[string]int64{"1024": 1024, "500M": 500 << 20, "2g": 2 << 30}
+	for value, want := range sizes {
+		if got, err := ParseSize(value); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0", "1.5G", "10MB", "-5"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("ParseSize(%q) expected error", value)
+		}
+	}
+
+	if p, err := ParseCompressPolicy("3"); err != nil || p.Count != 3 || p.Age != 0 || !p.Enabled() {
+		t.Errorf("ParseCompressPolicy(3) = %+v, %v", p, err)
+	}
+	if p, err := ParseCompressPolicy("7d"); err != nil || p.Age != 7*24*time.Hour || !p.Enabled() {
+		t.Errorf("ParseCompressPolicy(7d) = %+v, %v", p, err)
+	}
+	if _, err := ParseCompressPolicy("-1"); err == nil {
+		t.Errorf("ParseCompressPolicy(-1) expected error")
+	}
+}
+
+func TestPruneAppBuildsAgeSizeAndCompression(t *testing.T) {
+	tmp := setupTestRoot(t)
+	app := "limits"
+	now := time.Now().UTC()
+
+	propertyDir := filepath.Join(tmp, "config", "builds", app)
+	if err := os.MkdirAll(propertyDir, 0755); err != nil {
+		t.Fatalf("mkdir properties: %v", err)
+	}
+	writeProperty := func(name, value string) {
+		if err := os.WriteFile(filepath.Join(propertyDir, name), []byte(value), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	exit := 0
+	for i := 0; i < 6; i++ {
+		finished := now.Add(-time.Duration(i)*24*time.Hour + time.Hour)
+		b := Build{
+			ID:         "b" + strconv.Itoa(i),
+			App:        app,
+			Kind:       BuildKindBuild,
+			PID:        9000 + i,
+			StartedAt:  finished.Add(-time.Minute),
+			FinishedAt: &finished,
+			Status:     BuildStatusSucceeded,
+			Source:     BuildSourceGitHook,
+			ExitCode:   &exit,
+		}
+		if err := WriteBuild(b); err != nil {
+			t.Fatalf("write %s: %v", b.ID, err)
+		}
+		if err := os.WriteFile(b.LogPath(), []byte(strings.Repeat("log line "+b.ID+"\n", 200)), 0644); err != nil {
+			t.Fatalf("write log %s: %v", b.ID, err)
+		}
+	}
+
+	// b5 is pruned by age, b2-b4 have their logs compressed
+	writeProperty("retention-max-age", "4d")
+	writeProperty("compress-after", "2")
+	if err := PruneAppBuilds(app); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	remaining, _ := FetchBuilds(app)
+	if got := remainingIDs(remaining); strings.Join(got, ",") != "b0,b1,b2,b3,b4" {
+		t.Fatalf("remaining after age prune = %v", got)
+	}
+	for i := 0; i < 5; i++ {
+		id := "b" + strconv.Itoa(i)
+		_, plainErr := os.Stat(LogPathFor(app, id))
+		_, gzErr := os.Stat(LogPathFor(app, id) + CompressedLogSuffix)
+		compressed := i >= 2
+		if compressed != (plainErr != nil && gzErr == nil) {
+			t.Errorf("%s compressed = %v, want %v", id, !compressed, compressed)
+		}
+	}
+
+	var out strings.Builder
+	if _, err := copyLogFrom(LogPathFor(app, "b3"), 0, &out); err != nil {
+		t.Fatalf("read compressed log: %v", err)
+	}
+	if out.String() != strings.Repeat("log line b3\n", 200) {
+		t.Errorf("compressed log read back as %q", out.String())
+	}
+	if got := existingLogPath(LogPathFor(app, "b3")); got != LogPathFor(app, "b3")+CompressedLogSuffix {
+		t.Errorf("existingLogPath = %q", got)
+	}
+
+	// a size limit below the uncompressed logs prunes down to the newest build
+	writeProperty("retention-max-bytes", "1K")
+	if err := PruneAppBuilds(app); err != nil {
+		t.Fatalf("prune by size: %v", err)
+	}
+	remaining, _ = FetchBuilds(app)
+	if got := remainingIDs(remaining); strings.Join(got, ",") != "b0" {
+		t.Fatalf("remaining after size prune = %v", got)
+	}
+	if _, err := os.Stat(LogPathFor(app, "b3") + CompressedLogSuffix); !os.IsNotExist(err) {
+		t.Errorf("compressed log of pruned build was not removed: %v", err)
+	}
+
+	usage, err := DiskUsage(app)
+	if err != nil || usage != buildSize(app, "b0") {
+		t.Errorf("DiskUsage = %d, %v; want %d", usage, err, buildSize(app, "b0"))
+	}
+}
+
+func TestInputsKey(t *testing.T) {
+	setupTestRoot(t)
+
+	first, err := inputsKey("alpha")
+	if err != nil {
+		t.Fatalf("inputs key: %v", err)
+	}
+	again, err := inputsKey("alpha")
+	if err != nil {
+		t.Fatalf("inputs key: %v", err)
+	}
+	if hashEnvValue(first, "SECRET", "hunter2") != hashEnvValue(again, "SECRET", "hunter2") {
+		t.Error("expected the inputs key to be generated once and reused")
+	}
+
+	other, err := inputsKey("beta")
+	if err != nil {
+		t.Fatalf("inputs key: %v", err)
+	}
+	if hashEnvValue(first, "SECRET", "hunter2") == hashEnvValue(other, "SECRET", "hunter2") {
+		t.Error("expected apps to hash values with different keys")
+	}
+
+	info, err := os.Stat(filepath.Join(AppDataDir("alpha"), inputsKeyFile))
+	if err != nil {
+		t.Fatalf("stat inputs key: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("inputs key mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestDiffInputs(t *testing.T) {
+	setupTestRoot(t)
+	app := "diffed"
+	secret, err := inputsKey(app)
+	if err != nil {
+		t.Fatalf("inputs key: %v", err)
+	}
+
+	a := BuildInputs{
+		BuildID: "a",
+		ImageID: "sha256:aaa",
+		Env: map[string]string{
+			"SECRET":  hashEnvValue(secret, "SECRET", "hunter2"),
+			"SAME":    hashEnvValue(secret, "SAME", "1"),
+			"REMOVED": hashEnvValue(secret, "REMOVED", "x"),
+		},
+		AppJSON:       `{"scripts":{"dokku":{"predeploy":"make migrate"}}}`,
+		Procfile:      "web: ./server\nworker: ./worker\n",
+		Scale:         map[string]string{"web": "1", "worker": "1"},
+		DockerOptions: map[string][]string{"_default_.deploy": {"-v /data:/data"}},
+	}
+	b := BuildInputs{
+		BuildID: "b",
+		ImageID: "sha256:aaa",
+		Env: map[string]string{
+			"SECRET": hashEnvValue(secret, "SECRET", "hunter3"),
+			"SAME":   hashEnvValue(secret, "SAME", "1"),
+			"ADDED":  hashEnvValue(secret, "ADDED", "y"),
+		},
+		AppJSON:       `{"scripts":{"dokku":{"predeploy":"make migrate"}}}`,
+		Procfile:      "web: ./server --port $PORT\nworker: ./worker\n",
+		Scale:         map[string]string{"web": "2", "worker": "1"},
+		DockerOptions: map[string][]string{"_default_.deploy": {"-v /data:/data"}},
+	}
+	for _, inputs := range []BuildInputs{a, b} {
+		if err := WriteInputs(app, inputs); err != nil {
+			t.Fatalf("write inputs: %v", err)
+		}
+	}
+
+	readA, err := ReadInputs(app, "a")
+	if err != nil {
+		t.Fatalf("read inputs: %v", err)
+	}
+	readB, _ := ReadInputs(app, "b")
+	diff := DiffInputs(readA, readB)
+
+	wantEnv := []KeyChange{
+		{Key: "ADDED", Status: InputStatusAdded},
+		{Key: "REMOVED", Status: InputStatusRemoved},
+		{Key: "SAME", Status: InputStatusUnchanged},
+		{Key: "SECRET", Status: InputStatusChanged},
+	}
+	if fmt.Sprint(diff.Env) != fmt.Sprint(wantEnv) {
+		t.Errorf("env diff = %v, want %v", diff.Env, wantEnv)
+	}
+	if diff.Image != nil || diff.AppJSON != nil || diff.DockerOptions != nil {
+		t.Errorf("unchanged inputs reported as changed: %+v", diff)
+	}
+	wantProcfile := []string{"- web: ./server", "+ web: ./server --port $PORT"}
+	if strings.Join(diff.Procfile, "\n") != strings.Join(wantProcfile, "\n") {
+		t.Errorf("procfile diff = %q, want %q", diff.Procfile, wantProcfile)
+	}
+	if len(diff.Scale) != 1 || diff.Scale[0] != (ValueChange{Name: "web", From: "1", To: "2"}) {
+		t.Errorf("scale diff = %+v", diff.Scale)
+	}
+
+	body, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(body), "hunter") {
+		t.Errorf("diff leaked an env value: %s", body)
+	}
+	if diff.Empty() || !DiffInputs(readA, readA).Empty() {
+		t.Errorf("Empty() did not reflect the diff")
+	}
+
+	removeBuildFiles(app, "a")
+	if _, err := os.Stat(InputsPathFor(app, "a")); !os.IsNotExist(err) {
+		t.Errorf("inputs snapshot was not removed with the build: %v", err)
+	}
+}
+
+func TestDeliverWebhookRetriesAndSigns(t *testing.T) {
+	tmp := setupTestRoot(t)
+	app := "hooked"
+
+	previousInterval := WebhookRetryInterval
+	WebhookRetryInterval = time.Millisecond
+	t.Cleanup(func() { WebhookRetryInterval = previousInterval })
+
+	var received [][]byte
+	var signatures []string
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, body)
+		signatures = append(signatures, r.Header.Get(WebhookSignatureHeader))
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	propertyDir := filepath.Join(tmp, "config", "builds", app)
+	if err := os.MkdirAll(propertyDir, 0755); err != nil {
+		t.Fatalf("mkdir properties: %v", err)
+	}
+	os.WriteFile(filepath.Join(propertyDir, "webhook-url"), []byte(server.URL), 0644)
+	os.WriteFile(filepath.Join(propertyDir, "webhook-secret"), []byte("s3cret"), 0644)
+
+	b := Build{ID: "w1", App: app, Kind: BuildKindBuild, Status: BuildStatusSucceeded, Source: BuildSourceGitHook, GitSHA: "abc123"}
+	deliveryID, err := queueWebhook(b, WebhookEventFinished)
+	if err != nil || deliveryID == "" {
+		t.Fatalf("queue webhook = %q, %v", deliveryID, err)
+	}
+	if err := DeliverWebhook(app, deliveryID); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("received %d requests, want 3", len(received))
+	}
+	if signatures[2] != SignWebhookPayload("s3cret", received[2]) {
+		t.Errorf("signature = %q, want %q", signatures[2], SignWebhookPayload("s3cret", received[2]))
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(received[2], &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if payload.Event != WebhookEventFinished || payload.App != app || payload.GitSHA != "abc123" || payload.Build.ID != "w1" {
+		t.Errorf("payload = %+v", payload)
+	}
+	if _, err := os.Stat(webhookPendingPath(app, deliveryID)); !os.IsNotExist(err) {
+		t.Errorf("pending delivery was not removed: %v", err)
+	}
+
+	entries, err := ReadWebhookLog(app)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	deliveries := summarizeWebhookLog(entries)
+	if len(entries) != 3 || len(deliveries) != 1 {
+		t.Fatalf("log entries = %+v", entries)
+	}
+	if d := deliveries[0]; d.Outcome != WebhookOutcomeDelivered || d.Attempts != 3 || d.StatusCode != http.StatusNoContent {
+		t.Errorf("delivery = %+v", d)
+	}
+	if entries[0].Outcome != WebhookOutcomeRetrying || entries[0].StatusCode != http.StatusBadGateway {
+		t.Errorf("first attempt = %+v", entries[0])
+	}
+
+	// a delivery that never succeeds is given up on after the last attempt
+	failures = WebhookMaxAttempts
+	deliveryID, _ = queueWebhook(b, WebhookEventStarted)
+	if err := DeliverWebhook(app, deliveryID); err == nil {
+		t.Fatalf("expected the delivery to fail")
+	}
+	entries, _ = ReadWebhookLog(app)
+	if last := entries[len(entries)-1]; last.Outcome != WebhookOutcomeFailed || last.Attempt != WebhookMaxAttempts {
+		t.Errorf("last attempt = %+v", last)
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	start := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	build := func(id string, status BuildStatus, source BuildSource, startOffset, seconds int) Build {
+		started := start.Add(time.Duration(startOffset) * time.Hour)
+		finished := started.Add(time.Duration(seconds) * time.Second)
+		b := Build{ID: id, Kind: source.DefaultKind(), Status: status, Source: source, StartedAt: started}
+		if status.IsTerminal() {
+			b.FinishedAt = &finished
+		}
+		return b
+	}
+
+	builds := []Build{
+		build("s1", BuildStatusSucceeded, BuildSourceGitHook, 0, 10),
+		build("s2", BuildStatusSucceeded, BuildSourceGitHook, 1, 20),
+		build("s3", BuildStatusSucceeded, BuildSourcePsRestart, 2, 30),
+		build("f1", BuildStatusFailed, BuildSourceGitHook, 3, 40),
+		build("c1", BuildStatusCanceled, BuildSourcePsRestart, 4, 100),
+		build("r1", BuildStatusRunning, BuildSourceGitHook, 5, 0),
+	}
+
+	report := ComputeStats(builds, time.Time{})
+	all := report.All
+	if all.Total != 5 || all.Succeeded != 3 || all.Failed != 1 || all.Canceled != 1 {
+		t.Fatalf("all = %+v", all)
+	}
+	if all.SuccessRate != 0.6 || all.FailureRate != 0.2 || all.CancelRate != 0.2 {
+		t.Errorf("rates = %v/%v/%v", all.SuccessRate, all.FailureRate, all.CancelRate)
+	}
+	if all.P50Seconds != 30 || all.P95Seconds != 100 || all.MaxSeconds != 100 {
+		t.Errorf("durations = p50 %v p95 %v max %v", all.P50Seconds, all.P95Seconds, all.MaxSeconds)
+	}
+
+	gitHook := report.BySource[string(BuildSourceGitHook)]
+	if gitHook == nil || gitHook.Total != 3 || gitHook.Failed != 1 || gitHook.MaxSeconds != 40 {
+		t.Errorf("git-hook = %+v", gitHook)
+	}
+	deploys := report.ByKind[string(BuildKindDeploy)]
+	if deploys == nil || deploys.Total != 2 || deploys.Canceled != 1 {
+		t.Errorf("deploy kind = %+v", deploys)
+	}
+
+	// a plain deploy has the same source and kind, and counts towards both
+	deploy := build("d1", BuildStatusSucceeded, BuildSourceDeploy, 0, 10)
+	deploy.Kind = BuildKindDeploy
+	collided := ComputeStats([]Build{deploy}, time.Time{})
+	if s := collided.BySource[string(BuildSourceDeploy)]; s == nil || s.Total != 1 {
+		t.Errorf("deploy source = %+v", s)
+	}
+	if s := collided.ByKind[string(BuildKindDeploy)]; s == nil || s.Total != 1 {
+		t.Errorf("deploy kind = %+v", s)
+	}
+
+	recent := ComputeStats(builds, start.Add(150*time.Minute))
+	if recent.All.Total != 2 || recent.All.Failed != 1 || recent.All.Canceled != 1 {
+		t.Errorf("since filter = %+v", recent.All)
+	}
+
+	since, err := parseSince("7d", start)
+	if err != nil || !since.Equal(start.Add(-7*24*time.Hour)) {
+		t.Errorf("parseSince(7d) = %v, %v", since, err)
+	}
+	if _, err := parseSince("last week", start); err == nil {
+		t.Errorf("expected an invalid --since to be rejected")
+	}
+}
+
+func TestRecordStartCopiesRollbackTarget(t *testing.T) {
+	setupTestRoot(t)
+	app := "rolled"
+	target := Build{
+		ID:        "target",
+		App:       app,
+		Kind:      BuildKindBuild,
+		StartedAt: time.Now().UTC(),
+		Status:    BuildStatusSucceeded,
+		Source:    BuildSourceGitHook,
+		GitSHA:    "fedcba9876543210",
+		Builder:   "herokuish",
+	}
+	if err := WriteBuild(target); err != nil {
+		t.Fatalf("write target: %v", err)
+	}
+
+	t.Setenv("DOKKU_ROLLBACK_BUILD_ID", target.ID)
+	id := GenerateBuildID()
+	if err := TriggerBuildsRecordStart(app, id, strconv.Itoa(os.Getpid()), string(BuildSourceRollback)); err != nil {
+		t.Fatalf("record-start: %v", err)
+	}
+
+	b, err := ReadBuild(app, id)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if b.GitSHA != target.GitSHA || b.Builder != target.Builder {
+		t.Errorf("git_sha=%q builder=%q, want %q %q from the rollback target", b.GitSHA, b.Builder, target.GitSHA, target.Builder)
+	}
+}
@@ -0,0 +1,527 @@
+package builds
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/ryanuber/columnize"
+)
+
+// InputsSuffix is the file suffix of a build's deploy inputs snapshot. It is
+// deliberately not .json so that FetchBuilds does not treat it as a record.
+const InputsSuffix = ".inputs"
+
+// inputsKeyFile is the name of the per-app file holding the key env value
+// hashes are computed with.
+const inputsKeyFile = ".inputs-key"
+
+// InputStatus describes how a single input differs between two builds.
+type InputStatus string
+
+const (
+	InputStatusAdded     InputStatus = "added"
+	InputStatusRemoved   InputStatus = "removed"
+	InputStatusChanged   InputStatus = "changed"
+	InputStatusUnchanged InputStatus = "unchanged"
+)
+
+// BuildInputs is the snapshot of everything that went into a deploy. Env
+// values are never stored, only a keyed hash of each value.
+type BuildInputs struct {
+	BuildID       string              `json:"build_id"`
+	CapturedAt    time.Time           `json:"captured_at"`
+	ImageID       string              `json:"image_id,omitempty"`
+	Env           map[string]string   `json:"env"`
+	AppJSON       string              `json:"app_json,omitempty"`
+	Procfile      string              `json:"procfile,omitempty"`
+	Scale         map[string]string   `json:"scale"`
+	DockerOptions map[string][]string `json:"docker_options"`
+}
+
+// InputsPathFor returns the absolute path of a build's inputs snapshot.
+func InputsPathFor(appName, buildID string) string {
+	return filepath.Join(AppDataDir(appName), buildID+InputsSuffix)
+}
+
+// WriteInputs persists an inputs snapshot atomically.
+func WriteInputs(appName string, inputs BuildInputs) error {
+	if err := os.MkdirAll(AppDataDir(appName), 0755); err != nil {
+		return fmt.Errorf("create builds data dir: %w", err)
+	}
+	body, err := json.MarshalIndent(inputs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := InputsPathFor(appName, inputs.BuildID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadInputs loads the inputs snapshot of a build.
+func ReadInputs(appName, buildID string) (BuildInputs, error) {
+	var inputs BuildInputs
+	body, err := os.ReadFile(InputsPathFor(appName, buildID))
+	if err != nil {
+		return inputs, err
+	}
+	if err := json.Unmarshal(body, &inputs); err != nil {
+		return inputs, fmt.Errorf("parse inputs %s: %w", buildID, err)
+	}
+	return inputs, nil
+}
+
+// inputsKey returns the app's key for hashing env values. A key per app keeps
+// hashes comparable between builds of the same app only.
+func inputsKey(appName string) ([]byte, error) {
+	return common.ValueHashKey(filepath.Join(AppDataDir(appName), inputsKeyFile))
+}
+
+// hashEnvValue returns the hash stored in place of an env value. The env key
+// is mixed in so that two keys sharing a value do not share a hash.
+func hashEnvValue(secret []byte, key, value string) string {
+	return common.HashValue(secret, key, value)
+}
+
+// CaptureInputs snapshots the deploy inputs of an app. Each input is read
+// independently and a failure to read one is logged and leaves it empty, as
+// the snapshot must never fail the deploy.
+func CaptureInputs(appName, buildID, imageTag string) BuildInputs {
+	inputs := BuildInputs{
+		BuildID:       buildID,
+		CapturedAt:    time.Now().UTC(),
+		Env:           map[string]string{},
+		Scale:         map[string]string{},
+		DockerOptions: map[string][]string{},
+	}
+
+	warn := func(input string, err error) {
+		common.LogWarn(fmt.Sprintf("Unable to snapshot %s for build %s: %s", input, buildID, err))
+	}
+
+	if env, err := captureEnv(appName); err != nil {
+		warn("env", err)
+	} else if secret, err := inputsKey(appName); err != nil {
+		warn("env", err)
+	} else {
+		for key, value := range env {
+			inputs.Env[key] = hashEnvValue(secret, key, value)
+		}
+	}
+
+	if appJSON, err := triggerOutput("app-json-get-content", appName); err != nil {
+		warn("app.json", err)
+	} else if appJSON != "{}" {
+		inputs.AppJSON = appJSON
+	}
+
+	procfile, err := captureProcfile(appName)
+	if err != nil {
+		warn("Procfile", err)
+	}
+	inputs.Procfile = procfile
+
+	if scale, err := triggerOutput("ps-current-scale", appName); err != nil {
+		warn("scale", err)
+	} else {
+		inputs.Scale = parseScale(scale)
+	}
+
+	if options, err := captureDockerOptions(appName); err != nil {
+		warn("docker-options", err)
+	} else {
+		inputs.DockerOptions = options
+	}
+
+	if imageTag != "" {
+		if image, err := common.GetDeployingAppImageName(appName, imageTag, ""); err != nil {
+			warn("image id", err)
+		} else if imageID, err := common.DockerInspect(image, "{{.Id}}"); err != nil {
+			warn("image id", err)
+		} else {
+			inputs.ImageID = strings.TrimSpace(imageID)
+		}
+	}
+
+	return inputs
+}
+
+// SnapshotInputs captures and persists the deploy inputs of the build in
+// DOKKU_BUILD_ID. Deploys may run the scheduler once per process type, so
+// only the first call for a build writes the snapshot.
+func SnapshotInputs(appName, imageTag string) {
+	buildID := os.Getenv("DOKKU_BUILD_ID")
+	if appName == "" || buildID == "" {
+		return
+	}
+	if _, err := os.Stat(InputsPathFor(appName, buildID)); err == nil {
+		return
+	}
+
+	if err := WriteInputs(appName, CaptureInputs(appName, buildID, imageTag)); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to snapshot inputs for build %s: %s", buildID, err))
+	}
+}
+
+func triggerOutput(trigger string, args ...string) (string, error) {
+	result, err := common.CallPlugnTrigger(common.PlugnTriggerInput{
+		Trigger: trigger,
+		Args:    args,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.StdoutContents()), nil
+}
+
+func captureEnv(appName string) (map[string]string, error) {
+	output, err := triggerOutput("config-export", appName, "false", "true", "json")
+	if err != nil {
+		return nil, err
+	}
+	env := map[string]string{}
+	if output == "" {
+		return env, nil
+	}
+	if err := json.Unmarshal([]byte(output), &env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// captureProcfile returns the Procfile being deployed, preferring the copy
+// extracted for the current deploy over the one from the previous deploy.
+func captureProcfile(appName string) (string, error) {
+	existing := filepath.Join(common.GetAppDataDirectory("ps", appName), "Procfile")
+	processSpecific := fmt.Sprintf("%s.%s", existing, os.Getenv("DOKKU_PID"))
+
+	path := existing
+	if common.FileExists(processSpecific) {
+		path = processSpecific
+	} else if common.FileExists(processSpecific + ".missing") {
+		return "", nil
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(body), nil
+}
+
+func captureDockerOptions(appName string) (map[string][]string, error) {
+	properties, err := common.PropertyGetAll("docker-options", appName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := map[string][]string{}
+	for key := range properties {
+		lines, err := common.PropertyListGet("docker-options", appName, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" {
+				options[key] = append(options[key], line)
+			}
+		}
+	}
+	return options, nil
+}
+
+func parseScale(output string) map[string]string {
+	scale := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		processType, quantity, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok {
+			scale[processType] = quantity
+		}
+	}
+	return scale
+}
+
+// InputsDiff is the difference between the deploy inputs of two builds.
+type InputsDiff struct {
+	From          string        `json:"from"`
+	To            string        `json:"to"`
+	Image         *ValueChange  `json:"image,omitempty"`
+	Env           []KeyChange   `json:"env"`
+	AppJSON       []string      `json:"app_json"`
+	Procfile      []string      `json:"procfile"`
+	Scale         []ValueChange `json:"scale"`
+	DockerOptions []string      `json:"docker_options"`
+}
+
+// KeyChange is the status of a single env key. Values are never included.
+type KeyChange struct {
+	Key    string      `json:"key"`
+	Status InputStatus `json:"status"`
+}
+
+// ValueChange is a non-secret input that differs between two builds.
+type ValueChange struct {
+	Name string `json:"name,omitempty"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Empty reports whether the two builds were deployed with the same inputs.
+func (d InputsDiff) Empty() bool {
+	for _, change := range d.Env {
+		if change.Status != InputStatusUnchanged {
+			return false
+		}
+	}
+	return d.Image == nil && len(d.AppJSON) == 0 && len(d.Procfile) == 0 && len(d.Scale) == 0 && len(d.DockerOptions) == 0
+}
+
+// DiffInputs compares the inputs of two builds.
+func DiffInputs(a, b BuildInputs) InputsDiff {
+	diff := InputsDiff{
+		From:          a.BuildID,
+		To:            b.BuildID,
+		Env:           []KeyChange{},
+		AppJSON:       diffLines(indentJSON(a.AppJSON), indentJSON(b.AppJSON)),
+		Procfile:      diffLines(a.Procfile, b.Procfile),
+		Scale:         []ValueChange{},
+		DockerOptions: diffLines(flattenDockerOptions(a.DockerOptions), flattenDockerOptions(b.DockerOptions)),
+	}
+
+	if a.ImageID != b.ImageID {
+		diff.Image = &ValueChange{From: a.ImageID, To: b.ImageID}
+	}
+
+	for _, key := range unionKeys(a.Env, b.Env) {
+		from, inA := a.Env[key]
+		to, inB := b.Env[key]
+		status := InputStatusUnchanged
+		switch {
+		case !inA:
+			status = InputStatusAdded
+		case !inB:
+			status = InputStatusRemoved
+		case from != to:
+			status = InputStatusChanged
+		}
+		diff.Env = append(diff.Env, KeyChange{Key: key, Status: status})
+	}
+
+	for _, processType := range unionKeys(a.Scale, b.Scale) {
+		if a.Scale[processType] != b.Scale[processType] {
+			diff.Scale = append(diff.Scale, ValueChange{Name: processType, From: a.Scale[processType], To: b.Scale[processType]})
+		}
+	}
+
+	return diff
+}
+
+func unionKeys(a, b map[string]string) []string {
+	seen := map[string]bool{}
+	for key := range a {
+		seen[key] = true
+	}
+	for key := range b {
+		seen[key] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func indentJSON(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(raw), "", "  "); err != nil {
+		return raw
+	}
+	return out.String()
+}
+
+func flattenDockerOptions(options map[string][]string) string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := []string{}
+	for _, key := range keys {
+		for _, option := range options[key] {
+			lines = append(lines, fmt.Sprintf("%s: %s", key, option))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffLines returns the lines removed from a ("- ") and added in b ("+ "),
+// in order, or nil when the two are identical.
+func diffLines(a, b string) []string {
+	if a == b {
+		return nil
+	}
+	as := splitLines(a)
+	bs := splitLines(b)
+
+	// longest common subsequence table, built from the end
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i] == bs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		switch {
+		case i < len(as) && j < len(bs) && as[i] == bs[j]:
+			i++
+			j++
+		case j < len(bs) && (i == len(as) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, "+ "+bs[j])
+			j++
+		default:
+			lines = append(lines, "- "+as[i])
+			i++
+		}
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// CommandDiff shows how the deploy inputs of two builds differ. Env values
+// are only ever reported as added, removed, changed or unchanged.
+func CommandDiff(appName, fromID, toID, format string) error {
+	if format == "" {
+		format = "stdout"
+	}
+	if format != "stdout" && format != "json" {
+		return fmt.Errorf("Invalid format specified, supported formats: json, stdout")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+	if fromID == "" || toID == "" {
+		return errors.New("Please specify two build ids")
+	}
+
+	from, err := readInputsForDiff(appName, fromID)
+	if err != nil {
+		return err
+	}
+	to, err := readInputsForDiff(appName, toID)
+	if err != nil {
+		return err
+	}
+	diff := DiffInputs(from, to)
+
+	if format == "json" {
+		body, err := json.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		common.Log(string(body))
+		return nil
+	}
+
+	common.LogInfo2Quiet(fmt.Sprintf("Image %s..%s", fromID, toID))
+	if diff.Image == nil {
+		common.LogVerbose("unchanged")
+	} else {
+		common.LogVerbose(fmt.Sprintf("- %s", diff.Image.From))
+		common.LogVerbose(fmt.Sprintf("+ %s", diff.Image.To))
+	}
+
+	common.LogInfo2Quiet("Env")
+	if len(diff.Env) == 0 {
+		common.LogVerbose("unchanged")
+	} else {
+		rows := []string{}
+		for _, change := range diff.Env {
+			rows = append(rows, fmt.Sprintf("%s|%s", change.Key, change.Status))
+		}
+		for _, line := range strings.Split(columnize.Format(rows, &columnize.Config{Delim: "|"}), "\n") {
+			common.LogVerbose(line)
+		}
+	}
+
+	printDiffLines("app.json", diff.AppJSON)
+	printDiffLines("Procfile", diff.Procfile)
+
+	common.LogInfo2Quiet("Scale")
+	if len(diff.Scale) == 0 {
+		common.LogVerbose("unchanged")
+	}
+	for _, change := range diff.Scale {
+		common.LogVerbose(fmt.Sprintf("%s: %s -> %s", change.Name, valueOrNone(change.From), valueOrNone(change.To)))
+	}
+
+	printDiffLines("Docker options", diff.DockerOptions)
+	return nil
+}
+
+func readInputsForDiff(appName, buildID string) (BuildInputs, error) {
+	inputs, err := ReadInputs(appName, buildID)
+	if err != nil && os.IsNotExist(err) {
+		if _, recordErr := ReadBuild(appName, buildID); recordErr != nil {
+			return inputs, fmt.Errorf("No build record found for %s/%s", appName, buildID)
+		}
+		return inputs, fmt.Errorf("No deploy inputs were recorded for %s/%s", appName, buildID)
+	}
+	return inputs, err
+}
+
+func printDiffLines(title string, lines []string) {
+	common.LogInfo2Quiet(title)
+	if len(lines) == 0 {
+		common.LogVerbose("unchanged")
+		return
+	}
+	for _, line := range lines {
+		common.LogVerbose(line)
+	}
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
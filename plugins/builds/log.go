@@ -0,0 +1,155 @@
+package builds
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// LogFormatText captures build output to the log file as-is.
+	LogFormatText = "text"
+
+	// LogFormatJSONL captures each line of build output as a LogEntry.
+	LogFormatJSONL = "jsonl"
+)
+
+// LogEntry is a single line of build output in a jsonl-formatted build log.
+type LogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Phase     string    `json:"phase,omitempty"`
+	Stream    string    `json:"stream"`
+	Line      string    `json:"line"`
+}
+
+// ResolveLogFormat returns the log format for an app, cascading from per-app
+// override → global override → LogFormatText.
+func ResolveLogFormat(appName string) string {
+	return resolveProperty(appName, "log-format")
+}
+
+// AnnotateLog reads build output from r and appends each line to the build's
+// log file as a LogEntry tagged with the stream and the phase that was
+// running when the line was written.
+func AnnotateLog(appName, buildID, stream string, r io.Reader) error {
+	f, err := os.OpenFile(LogPathFor(appName, buildID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	phases := phaseTracker{path: RecordPath(appName, buildID)}
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			entry := LogEntry{
+				Timestamp: time.Now().UTC(),
+				Phase:     phases.current(),
+				Stream:    stream,
+				Line:      strings.TrimSuffix(line, "\n"),
+			}
+			body, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(append(body, '\n')); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// phaseTracker caches the running phase of a build, re-reading the record
+// only when it changes on disk.
+type phaseTracker struct {
+	path    string
+	modTime time.Time
+	phase   string
+}
+
+func (t *phaseTracker) current() string {
+	info, err := os.Stat(t.path)
+	if err != nil || info.ModTime().Equal(t.modTime) {
+		return t.phase
+	}
+	t.modTime = info.ModTime()
+
+	body, err := os.ReadFile(t.path)
+	if err != nil {
+		return t.phase
+	}
+	var b Build
+	if err := json.Unmarshal(body, &b); err != nil {
+		return t.phase
+	}
+	t.phase = ""
+	if i := b.openPhase(); i >= 0 {
+		t.phase = b.Phases[i].Name
+	}
+	return t.phase
+}
+
+// copyBuildLogFrom writes everything in the build's log after offset to w and
+// returns the new offset. jsonl logs are rendered back to plain text unless
+// raw is set.
+func copyBuildLogFrom(b Build, offset int64, w io.Writer, raw bool) (int64, error) {
+	if b.LogFormat != LogFormatJSONL {
+		return copyLogFrom(b.LogPath(), offset, w)
+	}
+	return copyJSONLLogFrom(b.LogPath(), offset, w, raw)
+}
+
+// copyJSONLLogFrom is copyLogFrom for jsonl logs. Only complete lines are
+// consumed so that a partially written entry is picked up on the next call.
+func copyJSONLLogFrom(logPath string, offset int64, w io.Writer, raw bool) (int64, error) {
+	f, err := openLogAt(logPath, offset)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return offset, nil
+		}
+		return offset, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		offset += int64(len(line))
+
+		if raw {
+			if _, err := w.Write(line); err != nil {
+				return offset, err
+			}
+			continue
+		}
+
+		var entry LogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// not an entry, most likely output written before capture started
+			if _, err := w.Write(line); err != nil {
+				return offset, err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(w, entry.Line); err != nil {
+			return offset, err
+		}
+	}
+}
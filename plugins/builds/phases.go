@@ -0,0 +1,227 @@
+package builds
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// Phase names recorded on build records, in the order they normally run.
+const (
+	PhaseExtract         = "extract"
+	PhaseBuild           = "build"
+	PhasePredeploy       = "predeploy"
+	PhaseRelease         = "release"
+	PhaseSchedulerDeploy = "scheduler-deploy"
+	PhaseHealthchecks    = "healthchecks"
+	PhaseProxyRebuild    = "proxy-rebuild"
+	PhasePostdeploy      = "postdeploy"
+)
+
+// PhaseOutcome is the result of a single build phase.
+type PhaseOutcome string
+
+const (
+	PhaseOutcomeRunning   PhaseOutcome = "running"
+	PhaseOutcomeSucceeded PhaseOutcome = "succeeded"
+	PhaseOutcomeFailed    PhaseOutcome = "failed"
+	PhaseOutcomeCanceled  PhaseOutcome = "canceled"
+)
+
+// Valid reports whether the outcome may be passed when ending a phase.
+func (o PhaseOutcome) Valid() bool {
+	switch o {
+	case PhaseOutcomeSucceeded, PhaseOutcomeFailed, PhaseOutcomeCanceled:
+		return true
+	}
+	return false
+}
+
+// BuildPhase is the timing of a single step of a build or deploy.
+type BuildPhase struct {
+	Name       string       `json:"name"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Outcome    PhaseOutcome `json:"outcome"`
+}
+
+// Duration returns the elapsed time of the phase (or until now, for the open
+// phase of an in-flight build).
+func (p BuildPhase) Duration() time.Duration {
+	end := time.Now().UTC()
+	if p.FinishedAt != nil {
+		end = *p.FinishedAt
+	}
+	if end.Before(p.StartedAt) {
+		return 0
+	}
+	return end.Sub(p.StartedAt).Round(time.Second)
+}
+
+// openPhase returns the index of the phase that is currently running, or -1.
+func (b *Build) openPhase() int {
+	for i := len(b.Phases) - 1; i >= 0; i-- {
+		if b.Phases[i].FinishedAt == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// startPhase opens a phase, closing the previously running phase as
+// succeeded. Starting the phase that is already running is a no-op so that
+// triggers which fire once per container only open it once.
+func (b *Build) startPhase(name string, now time.Time) {
+	if i := b.openPhase(); i >= 0 {
+		if b.Phases[i].Name == name {
+			return
+		}
+		b.closePhase(i, PhaseOutcomeSucceeded, now)
+	}
+	b.Phases = append(b.Phases, BuildPhase{
+		Name:      name,
+		StartedAt: now,
+		Outcome:   PhaseOutcomeRunning,
+	})
+}
+
+// endPhase closes a running phase. A phase that ends without having been
+// started is assumed to have started when the previous phase finished, or
+// when the build itself started.
+func (b *Build) endPhase(name string, outcome PhaseOutcome, now time.Time) {
+	for i := len(b.Phases) - 1; i >= 0; i-- {
+		if b.Phases[i].Name != name {
+			continue
+		}
+		if b.Phases[i].FinishedAt == nil {
+			b.closePhase(i, outcome, now)
+		}
+		return
+	}
+
+	if i := b.openPhase(); i >= 0 {
+		b.closePhase(i, PhaseOutcomeSucceeded, now)
+	}
+	startedAt := b.StartedAt
+	if len(b.Phases) > 0 {
+		startedAt = *b.Phases[len(b.Phases)-1].FinishedAt
+	}
+	b.Phases = append(b.Phases, BuildPhase{
+		Name:       name,
+		StartedAt:  startedAt,
+		FinishedAt: &now,
+		Outcome:    outcome,
+	})
+}
+
+// finishPhases closes any running phase with the given outcome. It is called
+// when the build record is finalized.
+func (b *Build) finishPhases(outcome PhaseOutcome, now time.Time) {
+	for i := range b.Phases {
+		if b.Phases[i].FinishedAt == nil {
+			b.closePhase(i, outcome, now)
+		}
+	}
+}
+
+func (b *Build) closePhase(i int, outcome PhaseOutcome, now time.Time) {
+	finishedAt := now
+	b.Phases[i].FinishedAt = &finishedAt
+	b.Phases[i].Outcome = outcome
+}
+
+// StartPhase marks a phase of an in-flight build as started.
+func StartPhase(appName, buildID, name string) error {
+	return updateInFlightBuild(appName, buildID, func(b *Build) {
+		b.startPhase(name, time.Now().UTC())
+	})
+}
+
+// EndPhase marks a phase of an in-flight build as finished.
+func EndPhase(appName, buildID, name string, outcome PhaseOutcome) error {
+	return updateInFlightBuild(appName, buildID, func(b *Build) {
+		b.endPhase(name, outcome, time.Now().UTC())
+	})
+}
+
+// recordPhase applies a phase event for the build in DOKKU_BUILD_ID. Failing
+// to record timing must never fail the deploy, so errors are only logged.
+func recordPhase(appName, name string, start bool) {
+	buildID := os.Getenv("DOKKU_BUILD_ID")
+	if appName == "" || buildID == "" {
+		return
+	}
+
+	var err error
+	if start {
+		err = StartPhase(appName, buildID, name)
+	} else {
+		err = EndPhase(appName, buildID, name, PhaseOutcomeSucceeded)
+	}
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to record %s phase for build %s: %s", name, buildID, err))
+	}
+}
+
+// recordBuildOrigin updates the git sha or builder of the build in
+// DOKKU_BUILD_ID. Like phase timing, failures are only logged.
+func recordBuildOrigin(appName string, fn func(b *Build)) {
+	buildID := os.Getenv("DOKKU_BUILD_ID")
+	if appName == "" || buildID == "" {
+		return
+	}
+
+	if err := updateInFlightBuild(appName, buildID, fn); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to record source for build %s: %s", buildID, err))
+	}
+}
+
+// updateInFlightBuild applies fn to a running build record while holding the
+// app's builds lock, as phases may be written by parallel deploy processes.
+// Missing and finalized records are left untouched.
+func updateInFlightBuild(appName, buildID string, fn func(b *Build)) error {
+	unlock, err := lockAppBuilds(appName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	b, err := ReadBuild(appName, buildID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if b.Status.IsTerminal() {
+		return nil
+	}
+
+	fn(&b)
+	return WriteBuild(b)
+}
+
+// lockAppBuilds takes an exclusive flock on the app's builds data directory.
+func lockAppBuilds(appName string) (func(), error) {
+	dir := AppDataDir(appName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create builds data dir: %w", err)
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock builds data dir: %w", err)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,135 @@
+package builds
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// QueuePositionCanceled is reported for builds that were dropped from the
+	// queue before acquiring the deploy lock.
+	QueuePositionCanceled = "canceled"
+
+	// QueuePositionSuperseded is reported for builds that were coalesced into
+	// a newer queued build.
+	QueuePositionSuperseded = "superseded"
+)
+
+// QueueModeEnabled reports whether blocked deploys for the app wait in the
+// deploy queue instead of failing on the deploy lock.
+func QueueModeEnabled(appName string) bool {
+	return common.PropertyGetDefault("builds", appName, "queue-mode", DefaultProperties["queue-mode"]) == "true"
+}
+
+// QueueCoalesceEnabled reports whether queued git-hook builds for the app are
+// coalesced so that only the newest push is deployed.
+func QueueCoalesceEnabled(appName string) bool {
+	return common.PropertyGetDefault("builds", appName, "queue-coalesce", DefaultProperties["queue-coalesce"]) == "true"
+}
+
+// FetchQueuedBuilds returns the live queued builds for an app in FIFO order.
+func FetchQueuedBuilds(appName string) ([]Build, error) {
+	all, err := FetchBuilds(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := make([]Build, 0, len(all))
+	for _, b := range all {
+		if b.Status == BuildStatusQueued && CheckPIDAlive(b.PID) {
+			queued = append(queued, b)
+		}
+	}
+
+	sort.SliceStable(queued, func(i, j int) bool {
+		if queued[i].StartedAt.Equal(queued[j].StartedAt) {
+			return queued[i].ID < queued[j].ID
+		}
+		return queued[i].StartedAt.Before(queued[j].StartedAt)
+	})
+	return queued, nil
+}
+
+// EnqueueBuild marks a build as waiting on the deploy lock. When coalescing is
+// enabled and the build came from a git push, older queued git pushes are
+// canceled in its favor.
+func EnqueueBuild(appName, buildID string) error {
+	b, err := ReadBuild(appName, buildID)
+	if err != nil {
+		return err
+	}
+	if b.Status != BuildStatusRunning && b.Status != BuildStatusQueued {
+		return fmt.Errorf("Build %s cannot be queued (status=%s)", buildID, b.Status)
+	}
+
+	b.Status = BuildStatusQueued
+	if err := WriteBuild(b); err != nil {
+		return err
+	}
+
+	if b.Source != BuildSourceGitHook || !QueueCoalesceEnabled(appName) {
+		return nil
+	}
+
+	queued, err := FetchQueuedBuilds(appName)
+	if err != nil {
+		return err
+	}
+	for _, other := range queued {
+		if other.ID == b.ID || other.Source != BuildSourceGitHook || other.StartedAt.After(b.StartedAt) {
+			continue
+		}
+		if err := finalizeCanceled(other, b.ID); err != nil {
+			common.LogWarn(fmt.Sprintf("Could not coalesce queued build %s/%s: %s", appName, other.ID, err))
+		}
+	}
+	return nil
+}
+
+// QueuePosition returns the number of live queued builds ahead of the given
+// build, or QueuePositionCanceled / QueuePositionSuperseded when the build was
+// dropped from the queue.
+func QueuePosition(appName, buildID string) (string, error) {
+	b, err := ReadBuild(appName, buildID)
+	if err != nil {
+		return "", err
+	}
+	if b.SupersededBy != "" {
+		return QueuePositionSuperseded, nil
+	}
+	if b.Status != BuildStatusQueued {
+		return QueuePositionCanceled, nil
+	}
+
+	queued, err := FetchQueuedBuilds(appName)
+	if err != nil {
+		return "", err
+	}
+	for i, other := range queued {
+		if other.ID == buildID {
+			return strconv.Itoa(i), nil
+		}
+	}
+	return "0", nil
+}
+
+// DequeueBuild marks a queued build as running once it holds the deploy lock.
+// Queued builds only come from git pushes, so the extract phase starts here
+// rather than including the time spent waiting in the queue.
+func DequeueBuild(appName, buildID string) error {
+	b, err := ReadBuild(appName, buildID)
+	if err != nil {
+		return err
+	}
+	if b.Status != BuildStatusQueued {
+		return fmt.Errorf("Build %s is not queued (status=%s)", buildID, b.Status)
+	}
+
+	b.Status = BuildStatusRunning
+	b.startPhase(PhaseExtract, time.Now().UTC())
+	return WriteBuild(b)
+}
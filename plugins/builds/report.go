@@ -18,21 +18,50 @@ func ReportSingleApp(appName, format, infoFlag string) error {
 	var flags map[string]common.ReportFunc
 	if appName == "--global" {
 		flags = map[string]common.ReportFunc{
-			"--builds-global-retention": reportGlobalRetention,
+			"--builds-global-retention":           reportGlobalRetention,
+			"--builds-global-log-format":          reportGlobalLogFormat,
+			"--builds-global-retention-max-age":   reportGlobalRetentionMaxAge,
+			"--builds-global-retention-max-bytes": reportGlobalRetentionMaxBytes,
+			"--builds-global-compress-after":      reportGlobalCompressAfter,
+			"--builds-global-webhook-url":         reportGlobalWebhookURL,
 		}
 	} else {
 		flags = map[string]common.ReportFunc{
-			"--build-id":                  reportBuildID,
-			"--build-kind":                reportBuildKind,
-			"--build-status":              reportBuildStatus,
-			"--build-pid":                 reportBuildPID,
-			"--build-source":              reportBuildSource,
-			"--build-started-at":          reportBuildStartedAt,
-			"--build-finished-at":         reportBuildFinishedAt,
-			"--build-exit-code":           reportBuildExitCode,
-			"--builds-retention":          reportRetention,
-			"--builds-global-retention":   reportGlobalRetention,
-			"--builds-computed-retention": reportComputedRetention,
+			"--build-id":                            reportBuildID,
+			"--build-kind":                          reportBuildKind,
+			"--build-status":                        reportBuildStatus,
+			"--build-pid":                           reportBuildPID,
+			"--build-source":                        reportBuildSource,
+			"--build-started-at":                    reportBuildStartedAt,
+			"--build-finished-at":                   reportBuildFinishedAt,
+			"--build-exit-code":                     reportBuildExitCode,
+			"--build-image":                         reportBuildImage,
+			"--build-image-id":                      reportBuildImageID,
+			"--build-git-sha":                       reportBuildGitSHA,
+			"--build-builder":                       reportBuildBuilder,
+			"--build-user":                          reportBuildUser,
+			"--builds-retention":                    reportRetention,
+			"--builds-global-retention":             reportGlobalRetention,
+			"--builds-computed-retention":           reportComputedRetention,
+			"--builds-queue-mode":                   reportQueueMode,
+			"--builds-queue-coalesce":               reportQueueCoalesce,
+			"--builds-log-format":                   reportLogFormat,
+			"--builds-global-log-format":            reportGlobalLogFormat,
+			"--builds-computed-log-format":          reportComputedLogFormat,
+			"--builds-retention-max-age":            reportRetentionMaxAge,
+			"--builds-global-retention-max-age":     reportGlobalRetentionMaxAge,
+			"--builds-computed-retention-max-age":   reportComputedRetentionMaxAge,
+			"--builds-retention-max-bytes":          reportRetentionMaxBytes,
+			"--builds-global-retention-max-bytes":   reportGlobalRetentionMaxBytes,
+			"--builds-computed-retention-max-bytes": reportComputedRetentionMaxBytes,
+			"--builds-compress-after":               reportCompressAfter,
+			"--builds-global-compress-after":        reportGlobalCompressAfter,
+			"--builds-computed-compress-after":      reportComputedCompressAfter,
+			"--builds-disk-usage":                   reportDiskUsage,
+			"--builds-webhook-url":                  reportWebhookURL,
+			"--builds-global-webhook-url":           reportGlobalWebhookURL,
+			"--builds-computed-webhook-url":         reportComputedWebhookURL,
+			"--builds-webhook-signed":               reportWebhookSigned,
 		}
 	}
 
@@ -127,6 +156,46 @@ func reportBuildExitCode(appName string) string {
 	return strconv.Itoa(*b.ExitCode)
 }
 
+func reportBuildImage(appName string) string {
+	b, ok := mostRecentBuild(appName)
+	if !ok {
+		return ""
+	}
+	return b.Image
+}
+
+func reportBuildImageID(appName string) string {
+	b, ok := mostRecentBuild(appName)
+	if !ok {
+		return ""
+	}
+	return b.ImageID
+}
+
+func reportBuildGitSHA(appName string) string {
+	b, ok := mostRecentBuild(appName)
+	if !ok {
+		return ""
+	}
+	return b.GitSHA
+}
+
+func reportBuildBuilder(appName string) string {
+	b, ok := mostRecentBuild(appName)
+	if !ok {
+		return ""
+	}
+	return b.Builder
+}
+
+func reportBuildUser(appName string) string {
+	b, ok := mostRecentBuild(appName)
+	if !ok {
+		return ""
+	}
+	return b.User
+}
+
 func reportRetention(appName string) string {
 	return common.PropertyGet("builds", appName, "retention")
 }
@@ -138,3 +207,85 @@ func reportGlobalRetention(_ string) string {
 func reportComputedRetention(appName string) string {
 	return strconv.Itoa(ResolveRetention(appName))
 }
+
+func reportQueueMode(appName string) string {
+	return common.PropertyGet("builds", appName, "queue-mode")
+}
+
+func reportQueueCoalesce(appName string) string {
+	return common.PropertyGet("builds", appName, "queue-coalesce")
+}
+
+func reportLogFormat(appName string) string {
+	return common.PropertyGet("builds", appName, "log-format")
+}
+
+func reportGlobalLogFormat(_ string) string {
+	return common.PropertyGet("builds", "--global", "log-format")
+}
+
+func reportComputedLogFormat(appName string) string {
+	return ResolveLogFormat(appName)
+}
+
+func reportRetentionMaxAge(appName string) string {
+	return common.PropertyGet("builds", appName, "retention-max-age")
+}
+
+func reportGlobalRetentionMaxAge(_ string) string {
+	return common.PropertyGet("builds", "--global", "retention-max-age")
+}
+
+func reportComputedRetentionMaxAge(appName string) string {
+	return resolveProperty(appName, "retention-max-age")
+}
+
+func reportRetentionMaxBytes(appName string) string {
+	return common.PropertyGet("builds", appName, "retention-max-bytes")
+}
+
+func reportGlobalRetentionMaxBytes(_ string) string {
+	return common.PropertyGet("builds", "--global", "retention-max-bytes")
+}
+
+func reportComputedRetentionMaxBytes(appName string) string {
+	return resolveProperty(appName, "retention-max-bytes")
+}
+
+func reportCompressAfter(appName string) string {
+	return common.PropertyGet("builds", appName, "compress-after")
+}
+
+func reportGlobalCompressAfter(_ string) string {
+	return common.PropertyGet("builds", "--global", "compress-after")
+}
+
+func reportComputedCompressAfter(appName string) string {
+	return resolveProperty(appName, "compress-after")
+}
+
+func reportDiskUsage(appName string) string {
+	size, err := DiskUsage(appName)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+func reportWebhookURL(appName string) string {
+	return common.PropertyGet("builds", appName, "webhook-url")
+}
+
+func reportGlobalWebhookURL(_ string) string {
+	return common.PropertyGet("builds", "--global", "webhook-url")
+}
+
+func reportComputedWebhookURL(appName string) string {
+	return resolveProperty(appName, "webhook-url")
+}
+
+// reportWebhookSigned reports whether deliveries are signed without exposing
+// the webhook-secret itself.
+func reportWebhookSigned(appName string) string {
+	return strconv.FormatBool(resolveProperty(appName, "webhook-secret") != "")
+}
@@ -0,0 +1,333 @@
+package builds
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// CompressedLogSuffix is appended to the log path of compressed build logs.
+const CompressedLogSuffix = ".gz"
+
+var (
+	ageRegexp  = regexp.MustCompile(`^([0-9]+)([smhdw])$`)
+	sizeRegexp = regexp.MustCompile(`^([0-9]+)([KMGT]?)$`)
+
+	ageUnits = map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	sizeUnits = map[string]int64{
+		"":  1,
+		"K": 1 << 10,
+		"M": 1 << 20,
+		"G": 1 << 30,
+		"T": 1 << 40,
+	}
+)
+
+// CompressPolicy describes which finalized build logs are gzipped. Logs are
+// compressed once more than Count newer finalized builds exist, or once the
+// build finished more than Age ago. A zero policy never compresses.
+type CompressPolicy struct {
+	Count int
+	Age   time.Duration
+	set   bool
+}
+
+// Enabled reports whether the policy compresses any logs.
+func (p CompressPolicy) Enabled() bool {
+	return p.set
+}
+
+// ParseAge parses an age such as 30d, 12h or 2w.
+func ParseAge(value string) (time.Duration, error) {
+	match := ageRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("Invalid age %q: must be a positive number followed by one of s, m, h, d, w (e.g. 30d)", value)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("Invalid age %q: must be a positive number followed by one of s, m, h, d, w (e.g. 30d)", value)
+	}
+	return time.Duration(n) * ageUnits[match[2]], nil
+}
+
+// ParseSize parses a byte count with an optional K, M, G or T suffix.
+func ParseSize(value string) (int64, error) {
+	match := sizeRegexp.FindStringSubmatch(strings.ToUpper(value))
+	if match == nil {
+		return 0, fmt.Errorf("Invalid size %q: must be a positive number of bytes with an optional K, M, G or T suffix (e.g. 500M)", value)
+	}
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("Invalid size %q: must be a positive number of bytes with an optional K, M, G or T suffix (e.g. 500M)", value)
+	}
+	return n * sizeUnits[match[2]], nil
+}
+
+// ParseCompressPolicy parses a compress-after value: either a number of newer
+// builds to keep uncompressed, or an age such as 7d.
+func ParseCompressPolicy(value string) (CompressPolicy, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 {
+			return CompressPolicy{}, fmt.Errorf("Invalid compress-after %q: must be a non-negative number of builds or an age (e.g. 7d)", value)
+		}
+		return CompressPolicy{Count: n, set: true}, nil
+	}
+
+	age, err := ParseAge(value)
+	if err != nil {
+		return CompressPolicy{}, fmt.Errorf("Invalid compress-after %q: must be a non-negative number of builds or an age (e.g. 7d)", value)
+	}
+	return CompressPolicy{Age: age, set: true}, nil
+}
+
+// resolveProperty returns the value of a builds property for an app, falling
+// back to the global value and then the default.
+func resolveProperty(appName, property string) string {
+	if appName != "" && appName != "--global" {
+		if value := common.PropertyGet("builds", appName, property); value != "" {
+			return value
+		}
+	}
+	return common.PropertyGetDefault("builds", "--global", property, DefaultProperties[property])
+}
+
+// ResolveRetentionMaxAge returns the maximum age of finalized build records
+// for an app, or zero when records are not pruned by age.
+func ResolveRetentionMaxAge(appName string) time.Duration {
+	value := resolveProperty(appName, "retention-max-age")
+	if value == "" {
+		return 0
+	}
+	age, err := ParseAge(value)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Ignoring builds retention-max-age for %s: %s", appName, err))
+		return 0
+	}
+	return age
+}
+
+// ResolveRetentionMaxBytes returns the maximum disk usage of an app's build
+// records and logs, or zero when records are not pruned by size.
+func ResolveRetentionMaxBytes(appName string) int64 {
+	value := resolveProperty(appName, "retention-max-bytes")
+	if value == "" {
+		return 0
+	}
+	size, err := ParseSize(value)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Ignoring builds retention-max-bytes for %s: %s", appName, err))
+		return 0
+	}
+	return size
+}
+
+// ResolveCompressPolicy returns the log compression policy for an app.
+func ResolveCompressPolicy(appName string) CompressPolicy {
+	value := resolveProperty(appName, "compress-after")
+	if value == "" {
+		return CompressPolicy{}
+	}
+	policy, err := ParseCompressPolicy(value)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Ignoring builds compress-after for %s: %s", appName, err))
+		return CompressPolicy{}
+	}
+	return policy
+}
+
+// age returns how long ago the build finished (or started, when it was never
+// finalized).
+func (b Build) age(now time.Time) time.Duration {
+	if b.FinishedAt != nil {
+		return now.Sub(*b.FinishedAt)
+	}
+	return now.Sub(b.StartedAt)
+}
+
+// applyRetentionLimits returns how many of the newest-first finalized builds
+// to keep so that none is older than maxAge and the app's builds use at most
+// maxBytes. usedBytes is the disk usage of every build, including live ones.
+// The most recent finalized build is always kept.
+func applyRetentionLimits(finalized []Build, sizes []int64, usedBytes int64, maxAge time.Duration, maxBytes int64, now time.Time) int {
+	keep := len(finalized)
+	if maxAge > 0 {
+		for i := 1; i < keep; i++ {
+			if finalized[i].age(now) > maxAge {
+				keep = i
+				break
+			}
+		}
+	}
+
+	if maxBytes > 0 {
+		for i := len(finalized) - 1; i >= keep; i-- {
+			usedBytes -= sizes[i]
+		}
+		for keep > 1 && usedBytes > maxBytes {
+			keep--
+			usedBytes -= sizes[keep]
+		}
+	}
+	return keep
+}
+
+// shouldCompress reports whether the log of the finalized build at index i of
+// a newest-first list should be compressed under the policy.
+func (p CompressPolicy) shouldCompress(b Build, i int, now time.Time) bool {
+	if !p.set {
+		return false
+	}
+	if p.Age > 0 {
+		return b.age(now) > p.Age
+	}
+	return i >= p.Count
+}
+
+// buildSize returns the disk usage of a build's record, log and inputs
+// snapshot.
+func buildSize(appName, buildID string) int64 {
+	var size int64
+	logPath := LogPathFor(appName, buildID)
+	for _, path := range []string{RecordPath(appName, buildID), logPath, logPath + CompressedLogSuffix, InputsPathFor(appName, buildID)} {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// DiskUsage returns the number of bytes used by an app's build records and
+// logs.
+func DiskUsage(appName string) (int64, error) {
+	entries, err := os.ReadDir(AppDataDir(appName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var size int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// CompressBuildLog gzips a build's log in place, leaving <id>.log.gz behind.
+// Logs that are missing or already compressed are left untouched.
+func CompressBuildLog(appName, buildID string) error {
+	logPath := LogPathFor(appName, buildID)
+	src, err := os.Open(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	tmp := logPath + CompressedLogSuffix + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(logPath)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, logPath+CompressedLogSuffix); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(logPath)
+}
+
+// existingLogPath returns the path of the build log as it is stored on disk,
+// which is the compressed path once the log has been compressed.
+func existingLogPath(logPath string) string {
+	if _, err := os.Stat(logPath); err != nil && os.IsNotExist(err) {
+		if _, err := os.Stat(logPath + CompressedLogSuffix); err == nil {
+			return logPath + CompressedLogSuffix
+		}
+	}
+	return logPath
+}
+
+// openLogAt opens a build log, transparently decompressing compressed logs,
+// and positions it at offset bytes into the uncompressed output.
+func openLogAt(logPath string, offset int64) (io.ReadCloser, error) {
+	f, err := os.Open(logPath)
+	if err == nil {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return f, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err = os.Open(logPath + CompressedLogSuffix)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	rc := &gzipLogReader{Reader: zr, file: f}
+	if _, err := io.CopyN(io.Discard, rc, offset); err != nil && err != io.EOF {
+		rc.Close()
+		return nil, err
+	}
+	return rc, nil
+}
+
+type gzipLogReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipLogReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
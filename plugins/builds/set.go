@@ -2,7 +2,9 @@ package builds
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/dokku/dokku/plugins/common"
 )
@@ -16,7 +18,7 @@ func CommandSet(appName, property, value string) error {
 	}
 
 	if _, ok := DefaultProperties[property]; !ok {
-		return fmt.Errorf("Invalid property %q (allowed: retention)", property)
+		return fmt.Errorf("Invalid property %q (allowed: %s)", property, strings.Join(validPropertyNames(), ", "))
 	}
 
 	if value != "" {
@@ -39,6 +41,39 @@ func validateSetValue(property, value string) error {
 		if n < MinimumRetention {
 			return fmt.Errorf("Invalid retention %d: must be >= %d", n, MinimumRetention)
 		}
+	case "retention-max-age":
+		if _, err := ParseAge(value); err != nil {
+			return err
+		}
+	case "retention-max-bytes":
+		if _, err := ParseSize(value); err != nil {
+			return err
+		}
+	case "compress-after":
+		if _, err := ParseCompressPolicy(value); err != nil {
+			return err
+		}
+	case "log-format":
+		if value != LogFormatText && value != LogFormatJSONL {
+			return fmt.Errorf("Invalid log-format %q: must be %s or %s", value, LogFormatText, LogFormatJSONL)
+		}
+	case "webhook-url":
+		if err := ValidateWebhookURL(value); err != nil {
+			return err
+		}
+	case "queue-mode", "queue-coalesce":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("Invalid %s %q: must be true or false", property, value)
+		}
 	}
 	return nil
 }
+
+func validPropertyNames() []string {
+	names := make([]string, 0, len(DefaultProperties))
+	for name := range DefaultProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
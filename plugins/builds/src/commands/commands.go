@@ -18,13 +18,18 @@ Manage running and historical builds
 Additional commands:`
 
 	helpContent = `
-    builds:cancel <app>, Cancel a running build for an app
+    builds:cancel <app> [--build-id <build-id>], Cancel a running or queued build for an app
+    builds:diff <app> <build-id> <build-id> [--format json|stdout], Show how the deploy inputs of two builds differ
     builds:info <app> <build-id> [--format json|stdout], Show details for a single build
-    builds:list [<app>] [--format json] [--kind build|deploy] [--status <status>], List builds
-    builds:output <app> [<build-id>|current], Show build output
+    builds:list [<app>] [--format json] [--kind build|deploy] [--status <status>] [--sha <sha>] [--builder <builder>] [--user <user>], List builds
+    builds:output <app> [<build-id>|current] [--follow] [--format json|text], Show build output
     builds:prune <app> [--all-apps], Prune build records to retention
     builds:report [<app>] [<flag>], Display a build report for one or more apps
-    builds:set [--global|<app>] <key> [<value>], Set or clear a builds property`
+    builds:rollback <app> [<build-id>], Redeploy the image of a previous successful build
+    builds:set [--global|<app>] <key> [<value>], Set or clear a builds property
+    builds:stats [<app>] [--since <age|timestamp>] [--format json|stdout], Show success rates and durations of finished builds
+    builds:wait <app> [<build-id>|current] [--timeout <seconds>] [--stream], Wait for a build to finish and exit with its exit code
+    builds:webhooks <app> [--format json|stdout], List webhook deliveries for an app`
 )
 
 func main() {
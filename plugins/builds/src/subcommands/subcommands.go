@@ -19,8 +19,14 @@ func main() {
 	switch subcommand {
 	case "cancel":
 		args := flag.NewFlagSet("builds:cancel", flag.ExitOnError)
+		buildID := args.String("build-id", "", "--build-id: cancel a specific (possibly queued) build")
 		args.Parse(os.Args[2:])
-		err = builds.CommandCancel(args.Arg(0))
+		err = builds.CommandCancel(args.Arg(0), *buildID)
+	case "diff":
+		args := flag.NewFlagSet("builds:diff", flag.ExitOnError)
+		format := args.String("format", "stdout", "format: [ stdout | json ]")
+		args.Parse(os.Args[2:])
+		err = builds.CommandDiff(args.Arg(0), args.Arg(1), args.Arg(2), *format)
 	case "info":
 		args := flag.NewFlagSet("builds:info", flag.ExitOnError)
 		format := args.String("format", "stdout", "format: [ stdout | json ]")
@@ -30,13 +36,24 @@ func main() {
 		args := flag.NewFlagSet("builds:list", flag.ExitOnError)
 		format := args.String("format", "stdout", "format: [ stdout | json ]")
 		kind := args.String("kind", "", "filter by kind: [ build | deploy ]")
-		status := args.String("status", "", "filter by status: [ running | succeeded | failed | canceled | abandoned ]")
+		status := args.String("status", "", "filter by status: [ queued | running | succeeded | failed | canceled | abandoned ]")
+		sha := args.String("sha", "", "filter by git sha prefix")
+		builder := args.String("builder", "", "filter by builder")
+		user := args.String("user", "", "filter by the user that triggered the build")
 		args.Parse(os.Args[2:])
-		err = builds.CommandList(args.Arg(0), *format, *kind, *status)
+		err = builds.CommandList(args.Arg(0), *format, builds.ListFilter{
+			Kind:    *kind,
+			Status:  *status,
+			SHA:     *sha,
+			Builder: *builder,
+			User:    *user,
+		})
 	case "output":
 		args := flag.NewFlagSet("builds:output", flag.ExitOnError)
+		follow := args.Bool("follow", false, "--follow: also wait on and stream a queued build until it finishes")
+		format := args.String("format", "text", "format: [ text | json ]")
 		args.Parse(os.Args[2:])
-		err = builds.CommandOutput(args.Arg(0), args.Arg(1))
+		err = builds.CommandOutput(args.Arg(0), args.Arg(1), *follow, *format)
 	case "prune":
 		args := flag.NewFlagSet("builds:prune", flag.ExitOnError)
 		allApps := args.Bool("all-apps", false, "--all-apps: prune every app")
@@ -54,6 +71,10 @@ func main() {
 			}
 			err = builds.CommandReport(appName, *format, reportArgs.InfoFlag)
 		}
+	case "rollback":
+		args := flag.NewFlagSet("builds:rollback", flag.ExitOnError)
+		args.Parse(os.Args[2:])
+		err = builds.CommandRollback(args.Arg(0), args.Arg(1))
 	case "set":
 		args := flag.NewFlagSet("builds:set", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: set a global property")
@@ -67,6 +88,23 @@ func main() {
 			value = args.Arg(1)
 		}
 		err = builds.CommandSet(appName, property, value)
+	case "stats":
+		args := flag.NewFlagSet("builds:stats", flag.ExitOnError)
+		format := args.String("format", "stdout", "format: [ stdout | json ]")
+		since := args.String("since", "", "--since: only count builds started within an age (e.g. 7d) or after an RFC3339 timestamp")
+		args.Parse(os.Args[2:])
+		err = builds.CommandStats(args.Arg(0), *since, *format)
+	case "wait":
+		args := flag.NewFlagSet("builds:wait", flag.ExitOnError)
+		timeout := args.Int("timeout", 0, "--timeout: number of seconds to wait before giving up, 0 to wait forever")
+		stream := args.Bool("stream", false, "--stream: stream the build log while waiting")
+		args.Parse(os.Args[2:])
+		err = builds.CommandWait(args.Arg(0), args.Arg(1), *timeout, *stream)
+	case "webhooks":
+		args := flag.NewFlagSet("builds:webhooks", flag.ExitOnError)
+		format := args.String("format", "stdout", "format: [ stdout | json ]")
+		args.Parse(os.Args[2:])
+		err = builds.CommandWebhooks(args.Arg(0), *format)
 	default:
 		err = fmt.Errorf("Invalid plugin subcommand call: %s", subcommand)
 	}
@@ -19,26 +19,85 @@ func main() {
 	switch trigger {
 	case "builds-generate-id":
 		err = builds.TriggerBuildsGenerateID()
+	case "builds-log-annotate":
+		appName := flag.Arg(0)
+		buildID := flag.Arg(1)
+		stream := flag.Arg(2)
+		err = builds.TriggerBuildsLogAnnotate(appName, buildID, stream)
+	case "builds-queue-enter":
+		appName := flag.Arg(0)
+		buildID := flag.Arg(1)
+		err = builds.TriggerBuildsQueueEnter(appName, buildID)
+	case "builds-queue-leave":
+		appName := flag.Arg(0)
+		buildID := flag.Arg(1)
+		err = builds.TriggerBuildsQueueLeave(appName, buildID)
+	case "builds-queue-position":
+		appName := flag.Arg(0)
+		buildID := flag.Arg(1)
+		err = builds.TriggerBuildsQueuePosition(appName, buildID)
 	case "builds-record-finalize":
 		appName := flag.Arg(0)
 		buildID := flag.Arg(1)
 		exitStr := flag.Arg(2)
 		err = builds.TriggerBuildsRecordFinalize(appName, buildID, exitStr)
+	case "builds-record-phase":
+		appName := flag.Arg(0)
+		buildID := flag.Arg(1)
+		phase := flag.Arg(2)
+		event := flag.Arg(3)
+		outcome := flag.Arg(4)
+		err = builds.TriggerBuildsRecordPhase(appName, buildID, phase, event, outcome)
 	case "builds-record-start":
 		appName := flag.Arg(0)
 		buildID := flag.Arg(1)
 		pid := flag.Arg(2)
 		source := flag.Arg(3)
 		err = builds.TriggerBuildsRecordStart(appName, buildID, pid, source)
+	case "builds-webhook-deliver":
+		appName := flag.Arg(0)
+		deliveryID := flag.Arg(1)
+		err = builds.TriggerBuildsWebhookDeliver(appName, deliveryID)
+	case "check-deploy":
+		appName := flag.Arg(0)
+		err = builds.TriggerCheckDeploy(appName)
+	case "core-post-deploy":
+		appName := flag.Arg(0)
+		err = builds.TriggerCorePostDeploy(appName)
+	case "core-post-extract":
+		appName := flag.Arg(0)
+		rev := flag.Arg(2)
+		err = builds.TriggerCorePostExtract(appName, rev)
 	case "install":
 		err = builds.TriggerInstall()
 	case "post-app-rename-setup":
 		oldAppName := flag.Arg(0)
 		newAppName := flag.Arg(1)
 		err = builds.TriggerPostAppRenameSetup(oldAppName, newAppName)
+	case "post-deploy":
+		appName := flag.Arg(0)
+		imageTag := flag.Arg(3)
+		err = builds.TriggerPostDeploy(appName, imageTag)
+	case "post-build":
+		appName := flag.Arg(1)
+		err = builds.TriggerPostBuild(appName)
 	case "post-delete":
 		appName := flag.Arg(0)
 		err = builds.TriggerPostDelete(appName)
+	case "post-release-builder":
+		appName := flag.Arg(1)
+		err = builds.TriggerPostReleaseBuilder(appName)
+	case "pre-build":
+		builderType := flag.Arg(0)
+		appName := flag.Arg(1)
+		err = builds.TriggerPreBuild(builderType, appName)
+	case "pre-release-builder":
+		appName := flag.Arg(1)
+		err = builds.TriggerPreReleaseBuilder(appName)
+	case "scheduler-deploy":
+		appName := flag.Arg(1)
+		imageTag := flag.Arg(2)
+		err = builds.TriggerSchedulerDeploy(appName, imageTag)
 	default:
 		err = fmt.Errorf("Invalid plugin trigger call: %s", trigger)
 	}
@@ -0,0 +1,220 @@
+package builds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/ryanuber/columnize"
+)
+
+// BuildStats aggregates the outcomes and durations of a set of finalized
+// builds. Rates are fractions of Total and durations are in seconds.
+type BuildStats struct {
+	Total       int     `json:"total"`
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	Canceled    int     `json:"canceled"`
+	SuccessRate float64 `json:"success_rate"`
+	FailureRate float64 `json:"failure_rate"`
+	CancelRate  float64 `json:"cancel_rate"`
+	P50Seconds  float64 `json:"p50_seconds"`
+	P95Seconds  float64 `json:"p95_seconds"`
+	MaxSeconds  float64 `json:"max_seconds"`
+
+	durations []time.Duration
+}
+
+// StatsReport is the output of builds:stats.
+type StatsReport struct {
+	App      string                 `json:"app,omitempty"`
+	Since    *time.Time             `json:"since,omitempty"`
+	All      BuildStats             `json:"all"`
+	BySource map[string]*BuildStats `json:"by_source"`
+	ByKind   map[string]*BuildStats `json:"by_kind"`
+}
+
+func (s *BuildStats) add(b Build) {
+	s.Total++
+	switch b.Status {
+	case BuildStatusSucceeded:
+		s.Succeeded++
+	case BuildStatusFailed:
+		s.Failed++
+	case BuildStatusCanceled:
+		s.Canceled++
+	}
+	s.durations = append(s.durations, b.Duration())
+}
+
+func (s *BuildStats) finish() {
+	if s.Total == 0 {
+		return
+	}
+	total := float64(s.Total)
+	s.SuccessRate = float64(s.Succeeded) / total
+	s.FailureRate = float64(s.Failed) / total
+	s.CancelRate = float64(s.Canceled) / total
+
+	sort.Slice(s.durations, func(i, j int) bool { return s.durations[i] < s.durations[j] })
+	s.P50Seconds = percentile(s.durations, 50).Seconds()
+	s.P95Seconds = percentile(s.durations, 95).Seconds()
+	s.MaxSeconds = s.durations[len(s.durations)-1].Seconds()
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// addToGroup counts a build towards the stats of a single group.
+func addToGroup(groups map[string]*BuildStats, key string, b Build) {
+	if groups[key] == nil {
+		groups[key] = &BuildStats{}
+	}
+	groups[key].add(b)
+}
+
+// ComputeStats aggregates the finalized builds that started at or after since.
+// In-flight and abandoned records are not counted.
+func ComputeStats(builds []Build, since time.Time) StatsReport {
+	report := StatsReport{
+		BySource: map[string]*BuildStats{},
+		ByKind:   map[string]*BuildStats{},
+	}
+	for _, b := range builds {
+		if !b.Status.IsTerminal() || b.StartedAt.Before(since) {
+			continue
+		}
+		report.All.add(b)
+		addToGroup(report.BySource, string(b.Source), b)
+		addToGroup(report.ByKind, string(b.Kind), b)
+	}
+
+	report.All.finish()
+	for _, groups := range []map[string]*BuildStats{report.BySource, report.ByKind} {
+		for _, s := range groups {
+			s.finish()
+		}
+	}
+	return report
+}
+
+// parseSince parses a --since value, either an age such as 7d or an RFC3339
+// timestamp.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if age, err := ParseAge(value); err == nil {
+		return now.Add(-age), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid --since %q: must be an age (e.g. 7d) or an RFC3339 timestamp", value)
+}
+
+// CommandStats reports success rates and durations of an app's builds, or of
+// every app's builds when no app is given.
+func CommandStats(appName, since, format string) error {
+	if format == "" {
+		format = "stdout"
+	}
+	if format != "stdout" && format != "json" {
+		return fmt.Errorf("Invalid format specified, supported formats: json, stdout")
+	}
+	sinceTime, err := parseSince(since, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	apps := []string{appName}
+	if appName == "" {
+		apps, err = common.DokkuApps()
+		if err != nil && !errors.Is(err, common.NoAppsExist) {
+			return err
+		}
+	} else if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	all := []Build{}
+	for _, app := range apps {
+		builds, err := FetchBuilds(app)
+		if err != nil {
+			common.LogWarn(fmt.Sprintf("Could not read builds for %s: %s", app, err))
+			continue
+		}
+		all = append(all, builds...)
+	}
+
+	report := ComputeStats(all, sinceTime)
+	report.App = appName
+	if !sinceTime.IsZero() {
+		report.Since = &sinceTime
+	}
+
+	if format == "json" {
+		body, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		common.Log(string(body))
+		return nil
+	}
+
+	if report.All.Total == 0 {
+		fmt.Println("No finished builds recorded")
+		return nil
+	}
+
+	rows := []string{"Group | Builds | Succeeded | Failed | Canceled | Success Rate | p50 | p95 | Max"}
+	rows = append(rows, statsRow("all", report.All))
+	for _, kind := range sortedStatsKeys(report.ByKind) {
+		rows = append(rows, statsRow("kind:"+kind, *report.ByKind[kind]))
+	}
+	for _, source := range sortedStatsKeys(report.BySource) {
+		rows = append(rows, statsRow("source:"+source, *report.BySource[source]))
+	}
+	fmt.Println(columnize.SimpleFormat(rows))
+	return nil
+}
+
+func statsRow(group string, s BuildStats) string {
+	seconds := func(v float64) time.Duration {
+		return time.Duration(v * float64(time.Second))
+	}
+	return fmt.Sprintf("%s | %d | %d | %d | %d | %.1f%% | %s | %s | %s",
+		group,
+		s.Total,
+		s.Succeeded,
+		s.Failed,
+		s.Canceled,
+		s.SuccessRate*100,
+		seconds(s.P50Seconds),
+		seconds(s.P95Seconds),
+		seconds(s.MaxSeconds),
+	)
+}
+
+func sortedStatsKeys(groups map[string]*BuildStats) []string {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
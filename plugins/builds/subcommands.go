@@ -31,13 +31,61 @@ func toListView(b Build) listView {
 		Build:         b,
 		DisplayStatus: b.DisplayStatus(),
 		Duration:      b.Duration().String(),
-		LogPath:       b.LogPath(),
+		LogPath:       existingLogPath(b.LogPath()),
 	}
 }
 
-// CommandList lists running builds across apps (no app arg) or running + recent
-// records for one app.
-func CommandList(appName string, format string, kindFilter string, statusFilter string) error {
+// ListFilter narrows the records shown by builds:list. Empty fields match
+// every record.
+type ListFilter struct {
+	Kind    string
+	Status  string
+	SHA     string
+	Builder string
+	User    string
+}
+
+func (f ListFilter) empty() bool {
+	return f == ListFilter{}
+}
+
+func (f ListFilter) validate() error {
+	if f.Kind != "" {
+		k := BuildKind(f.Kind)
+		if !k.Valid() {
+			return fmt.Errorf("Invalid --kind value %q (allowed: build, deploy)", f.Kind)
+		}
+	}
+	if f.Status != "" {
+		if !validStatusFilter(BuildStatus(f.Status)) {
+			return fmt.Errorf("Invalid --status value %q (allowed: queued, running, succeeded, failed, canceled, abandoned)", f.Status)
+		}
+	}
+	return nil
+}
+
+func (f ListFilter) matches(v listView) bool {
+	if f.Kind != "" && string(v.Kind) != f.Kind {
+		return false
+	}
+	if f.Status != "" && string(v.DisplayStatus) != f.Status {
+		return false
+	}
+	if f.SHA != "" && (v.GitSHA == "" || !strings.HasPrefix(v.GitSHA, f.SHA)) {
+		return false
+	}
+	if f.Builder != "" && v.Builder != f.Builder {
+		return false
+	}
+	if f.User != "" && v.User != f.User {
+		return false
+	}
+	return true
+}
+
+// CommandList lists in-flight builds across apps (no app arg) or in-flight +
+// recent records for one app.
+func CommandList(appName string, format string, filter ListFilter) error {
 	if format == "" {
 		format = "stdout"
 	}
@@ -45,20 +93,12 @@ func CommandList(appName string, format string, kindFilter string, statusFilter
 		return fmt.Errorf("Invalid format specified, supported formats: json, stdout")
 	}
 
-	if kindFilter != "" {
-		k := BuildKind(kindFilter)
-		if !k.Valid() {
-			return fmt.Errorf("Invalid --kind value %q (allowed: build, deploy)", kindFilter)
-		}
-	}
-	if statusFilter != "" {
-		if !validStatusFilter(BuildStatus(statusFilter)) {
-			return fmt.Errorf("Invalid --status value %q (allowed: running, succeeded, failed, canceled, abandoned)", statusFilter)
-		}
+	if err := filter.validate(); err != nil {
+		return err
 	}
 
 	if appName == "" {
-		return commandListAllRunning(format, kindFilter, statusFilter)
+		return commandListAllRunning(format, filter)
 	}
 
 	if err := common.VerifyAppName(appName); err != nil {
@@ -74,29 +114,26 @@ func CommandList(appName string, format string, kindFilter string, statusFilter
 	views := make([]listView, 0, len(all))
 	for _, b := range all {
 		v := toListView(b)
-		if !matchesKind(b, kindFilter) {
-			continue
-		}
-		if !matchesStatus(v, statusFilter) {
+		if !filter.matches(v) {
 			continue
 		}
 		views = append(views, v)
 	}
 
 	sort.SliceStable(views, func(i, j int) bool {
-		// Live-running first, then by start time descending.
-		iLive := views[i].Build.Status == BuildStatusRunning && views[i].DisplayStatus == BuildStatusRunning
-		jLive := views[j].Build.Status == BuildStatusRunning && views[j].DisplayStatus == BuildStatusRunning
+		// Live in-flight first, then by start time descending.
+		iLive := views[i].Build.Status.IsInFlight() && views[i].DisplayStatus == views[i].Build.Status
+		jLive := views[j].Build.Status.IsInFlight() && views[j].DisplayStatus == views[j].Build.Status
 		if iLive != jLive {
 			return iLive
 		}
 		return views[i].StartedAt.After(views[j].StartedAt)
 	})
 
-	if statusFilter == "" && kindFilter == "" && len(views) > retention {
+	if filter.empty() && len(views) > retention {
 		live := 0
 		for _, v := range views {
-			if v.DisplayStatus == BuildStatusRunning {
+			if v.DisplayStatus.IsInFlight() {
 				live++
 			}
 		}
@@ -120,14 +157,17 @@ func CommandList(appName string, format string, kindFilter string, statusFilter
 		return nil
 	}
 
-	rows := []string{"Build ID | Kind | Status | PID | Source | Started | Duration"}
+	rows := []string{"Build ID | Kind | Status | PID | Source | SHA | Builder | User | Started | Duration"}
 	for _, v := range views {
-		rows = append(rows, fmt.Sprintf("%s | %s | %s | %d | %s | %s | %s",
+		rows = append(rows, fmt.Sprintf("%s | %s | %s | %d | %s | %s | %s | %s | %s | %s",
 			v.ID,
 			v.Kind,
 			v.DisplayStatus,
 			v.PID,
 			v.Source,
+			v.ShortSHA(),
+			v.Builder,
+			v.User,
 			v.StartedAt.Format(time.RFC3339),
 			v.Duration,
 		))
@@ -136,7 +176,7 @@ func CommandList(appName string, format string, kindFilter string, statusFilter
 	return nil
 }
 
-func commandListAllRunning(format, kindFilter, statusFilter string) error {
+func commandListAllRunning(format string, filter ListFilter) error {
 	apps, err := common.DokkuApps()
 	if err != nil && !errors.Is(err, common.NoAppsExist) {
 		return err
@@ -150,14 +190,11 @@ func commandListAllRunning(format, kindFilter, statusFilter string) error {
 			continue
 		}
 		for _, b := range running {
-			if b.Status != BuildStatusRunning {
+			if !b.Status.IsInFlight() {
 				continue
 			}
 			v := toListView(b)
-			if !matchesKind(b, kindFilter) {
-				continue
-			}
-			if !matchesStatus(v, statusFilter) {
+			if !filter.matches(v) {
 				continue
 			}
 			views = append(views, v)
@@ -178,18 +215,20 @@ func commandListAllRunning(format, kindFilter, statusFilter string) error {
 	}
 
 	if len(views) == 0 {
-		fmt.Println("No builds currently running")
+		fmt.Println("No builds currently queued or running")
 		return nil
 	}
 
-	rows := []string{"App | Build ID | Kind | PID | Source | Started"}
+	rows := []string{"App | Build ID | Kind | Status | PID | Source | User | Started"}
 	for _, v := range views {
-		rows = append(rows, fmt.Sprintf("%s | %s | %s | %d | %s | %s",
+		rows = append(rows, fmt.Sprintf("%s | %s | %s | %s | %d | %s | %s | %s",
 			v.App,
 			v.ID,
 			v.Kind,
+			v.DisplayStatus,
 			v.PID,
 			v.Source,
+			v.User,
 			v.StartedAt.Format(time.RFC3339),
 		))
 	}
@@ -197,23 +236,9 @@ func commandListAllRunning(format, kindFilter, statusFilter string) error {
 	return nil
 }
 
-func matchesKind(b Build, filter string) bool {
-	if filter == "" {
-		return true
-	}
-	return string(b.Kind) == filter
-}
-
-func matchesStatus(v listView, filter string) bool {
-	if filter == "" {
-		return true
-	}
-	return string(v.DisplayStatus) == filter
-}
-
 func validStatusFilter(s BuildStatus) bool {
 	switch s {
-	case BuildStatusRunning, BuildStatusSucceeded, BuildStatusFailed, BuildStatusCanceled, BuildStatusAbandoned:
+	case BuildStatusQueued, BuildStatusRunning, BuildStatusSucceeded, BuildStatusFailed, BuildStatusCanceled, BuildStatusAbandoned:
 		return true
 	}
 	return false
@@ -265,45 +290,102 @@ func CommandInfo(appName, buildID, format string) error {
 		rows = append(rows, fmt.Sprintf("Finished:|%s", v.FinishedAt.Format(time.RFC3339)))
 	}
 	rows = append(rows, fmt.Sprintf("Duration:|%s", v.Duration))
+	if v.SupersededBy != "" {
+		rows = append(rows, fmt.Sprintf("Superseded By:|%s", v.SupersededBy))
+	}
 	if v.ExitCode != nil {
 		rows = append(rows, fmt.Sprintf("Exit Code:|%d", *v.ExitCode))
 	}
+	if v.GitSHA != "" {
+		rows = append(rows, fmt.Sprintf("Git SHA:|%s", v.GitSHA))
+	}
+	if v.Builder != "" {
+		rows = append(rows, fmt.Sprintf("Builder:|%s", v.Builder))
+	}
+	if v.User != "" {
+		rows = append(rows, fmt.Sprintf("User:|%s", v.User))
+	}
+	if v.Image != "" {
+		rows = append(rows, fmt.Sprintf("Image:|%s", v.Image))
+	}
+	if v.ImageID != "" {
+		rows = append(rows, fmt.Sprintf("Image ID:|%s", v.ImageID))
+	}
 	rows = append(rows, fmt.Sprintf("Log:|%s", v.LogPath))
 	fmt.Println(columnize.Format(rows, &columnize.Config{Delim: "|"}))
+
+	if len(v.Phases) == 0 {
+		return nil
+	}
+
+	phaseRows := []string{"Phase | Started | Finished | Duration | Outcome"}
+	for _, p := range v.Phases {
+		finished := "-"
+		if p.FinishedAt != nil {
+			finished = p.FinishedAt.Format(time.RFC3339)
+		}
+		outcome := string(p.Outcome)
+		if p.FinishedAt == nil && v.DisplayStatus == BuildStatusAbandoned {
+			outcome = string(BuildStatusAbandoned)
+		}
+		phaseRows = append(phaseRows, fmt.Sprintf("%s | %s | %s | %s | %s", p.Name, p.StartedAt.Format(time.RFC3339), finished, p.Duration(), outcome))
+	}
+	fmt.Println()
+	fmt.Println(columnize.SimpleFormat(phaseRows))
 	return nil
 }
 
-// CommandCancel cancels the in-flight build for an app.
-func CommandCancel(appName string) error {
+// CommandCancel cancels the in-flight build for an app. When a build id is
+// given, that build is canceled instead, which drops it from the deploy queue
+// if it has not yet acquired the deploy lock.
+func CommandCancel(appName, buildID string) error {
 	if err := common.VerifyAppName(appName); err != nil {
 		return err
 	}
 
 	lockPath := deployLockPath(appName)
 	body, err := os.ReadFile(lockPath)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lockedID := strings.TrimSpace(string(body))
+
+	if buildID == "" {
 		if os.IsNotExist(err) {
 			common.LogInfo1("App not currently deploying")
 			return nil
 		}
-		return err
+		if lockedID == "" {
+			common.LogInfo1("No matching app deploy found")
+			return nil
+		}
+		buildID = lockedID
 	}
-	buildID := strings.TrimSpace(string(body))
-	if buildID == "" {
-		common.LogInfo1("No matching app deploy found")
-		return nil
+
+	removeLock := func() {
+		if buildID == lockedID {
+			_ = os.Remove(lockPath)
+		}
 	}
 
 	b, err := ReadBuild(appName, buildID)
 	if err != nil {
 		if os.IsNotExist(err) {
+			if buildID != lockedID {
+				return fmt.Errorf("No build record found for %s/%s", appName, buildID)
+			}
 			common.LogInfo1(fmt.Sprintf("No build record for %s, removing stale lock file", buildID))
-			_ = os.Remove(lockPath)
+			removeLock()
 			return nil
 		}
 		return err
 	}
 
+	if b.Status == BuildStatusQueued {
+		common.LogInfo1(fmt.Sprintf("Removing build %s from the deploy queue", buildID))
+		return finalizeCanceled(b, "")
+	}
+
 	if b.Status != BuildStatusRunning {
 		common.LogInfo1(fmt.Sprintf("Build %s is no longer running (status=%s); leaving record untouched", buildID, b.Status))
 		return nil
@@ -319,7 +401,7 @@ func CommandCancel(appName string) error {
 		if err := WriteBuild(b); err != nil {
 			return err
 		}
-		_ = os.Remove(lockPath)
+		removeLock()
 		return nil
 	}
 
@@ -330,17 +412,30 @@ func CommandCancel(appName string) error {
 
 	current, err := ReadBuild(appName, buildID)
 	if err == nil && current.Status == BuildStatusRunning {
-		now := time.Now().UTC()
-		exitCode := -1
-		current.Status = BuildStatusCanceled
-		current.FinishedAt = &now
-		current.ExitCode = &exitCode
-		if err := WriteBuild(current); err != nil {
+		if err := finalizeCanceled(current, ""); err != nil {
 			return err
 		}
 	}
 
-	_ = os.Remove(lockPath)
+	removeLock()
+	return nil
+}
+
+// finalizeCanceled writes a canceled terminal status onto a build record,
+// noting the newer build it was coalesced into if any.
+func finalizeCanceled(b Build, supersededBy string) error {
+	now := time.Now().UTC()
+	exitCode := -1
+	b.Status = BuildStatusCanceled
+	b.FinishedAt = &now
+	b.ExitCode = &exitCode
+	b.SupersededBy = supersededBy
+	b.finishPhases(PhaseOutcomeCanceled, now)
+	if err := WriteBuild(b); err != nil {
+		return err
+	}
+
+	SendWebhook(b, WebhookEventFinished)
 	return nil
 }
 
@@ -356,8 +451,16 @@ func deployLockPath(appName string) string {
 	return filepath.Join(common.GetAppDataDirectory("apps", appName), ".deploy.lock")
 }
 
-// CommandOutput streams the build log for a given build (or the current one).
-func CommandOutput(appName, buildID string) error {
+// CommandOutput prints the build log for a given build (or the current one).
+// Running builds are streamed until they reach a terminal status; with follow,
+// queued builds are waited on and streamed as well.
+func CommandOutput(appName, buildID string, follow bool, format string) error {
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" {
+		return fmt.Errorf("Invalid format specified, supported formats: json, text")
+	}
 	if err := common.VerifyAppName(appName); err != nil {
 		return err
 	}
@@ -378,7 +481,7 @@ func CommandOutput(appName, buildID string) error {
 		}
 	}
 
-	logPath := LogPathFor(appName, buildID)
+	logPath := existingLogPath(LogPathFor(appName, buildID))
 	if _, err := os.Stat(logPath); err != nil {
 		if os.IsNotExist(err) {
 			return outputViaJournalctl(buildID)
@@ -387,14 +490,40 @@ func CommandOutput(appName, buildID string) error {
 	}
 
 	b, err := ReadBuild(appName, buildID)
-	if err != nil && !os.IsNotExist(err) {
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if format == "json" {
+			return fmt.Errorf("No build record found for %s/%s", appName, buildID)
+		}
+		_, err := copyLogFrom(LogPathFor(appName, buildID), 0, os.Stdout)
 		return err
 	}
+	if format == "json" && b.LogFormat != LogFormatJSONL {
+		return fmt.Errorf("Build %s was not captured with the %s log-format", buildID, LogFormatJSONL)
+	}
+
+	if b.Status == BuildStatusRunning && CheckPIDAlive(b.PID) {
+		follow = true
+	}
+
+	raw := format == "json"
+	var offset int64
+	for {
+		offset, err = copyBuildLogFrom(b, offset, os.Stdout, raw)
+		if err != nil {
+			return err
+		}
+		if !follow || !b.IsLive() {
+			return nil
+		}
 
-	if err == nil && b.Status == BuildStatusRunning && CheckPIDAlive(b.PID) {
-		return execStreaming("tail", "-n", "1000", "-f", logPath)
+		time.Sleep(WaitPollInterval)
+		if b, err = ReadBuild(appName, buildID); err != nil {
+			return err
+		}
 	}
-	return execStreaming("cat", logPath)
 }
 
 func outputViaJournalctl(buildID string) error {
@@ -456,3 +585,63 @@ func CommandReport(appName, format, infoFlag string) error {
 	}
 	return ReportSingleApp(appName, format, infoFlag)
 }
+
+// CommandRollback redeploys the image recorded for a previous successful build.
+func CommandRollback(appName, buildID string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	if scheduler := common.GetAppScheduler(appName); scheduler != "docker-local" {
+		return fmt.Errorf("builds:rollback is only supported by the docker-local scheduler (app uses %s)", scheduler)
+	}
+
+	all, err := FetchBuilds(appName)
+	if err != nil {
+		return err
+	}
+
+	imageTag, err := common.GetRunningImageTag(appName, "")
+	if err != nil {
+		return err
+	}
+
+	image := fmt.Sprintf("%s:%s", common.GetAppImageRepo(appName), imageTag)
+	deployedImageID, _ := common.DockerInspect(image, "{{.Id}}")
+	target, err := FindRollbackTarget(all, buildID, deployedImageID)
+	if err != nil {
+		return err
+	}
+
+	if !common.VerifyImage(target.Image) {
+		return fmt.Errorf("Image %s for build %s is no longer available on this host", target.Image, target.ID)
+	}
+
+	common.LogInfo1(fmt.Sprintf("Rolling back %s to build %s", appName, target.ID))
+	common.LogVerbose(fmt.Sprintf("Tagging %s as %s", target.Image, image))
+	if err := dockerTag(target.Image, image); err != nil {
+		return err
+	}
+
+	_, err = common.CallPlugnTrigger(common.PlugnTriggerInput{
+		Trigger:     "release-and-deploy",
+		Args:        []string{appName, imageTag, string(BuildSourceRollback)},
+		Env:         map[string]string{"DOKKU_ROLLBACK_BUILD_ID": target.ID},
+		StreamStdio: true,
+	})
+	return err
+}
+
+func dockerTag(image string, target string) error {
+	result, err := common.CallExecCommand(common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    []string{"image", "tag", image, target},
+	})
+	if err != nil {
+		return fmt.Errorf("docker tag command failed: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("docker tag command exited with code %d: %s", result.ExitCode, result.StderrContents())
+	}
+	return nil
+}
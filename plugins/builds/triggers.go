@@ -64,7 +64,9 @@ func TriggerBuildsGenerateID() error {
 // Args: <app> <build-id> <pid> <source>
 //
 // kind is derived from source via BuildSource.DefaultKind(); callers do not
-// pass it.
+// pass it. The git sha and builder are captured from the app's current state
+// here; builds that extract new source overwrite them with the revision and
+// builder actually used, and rollbacks copy them from the target build.
 func TriggerBuildsRecordStart(appName, buildID, pidStr, sourceStr string) error {
 	if appName == "" {
 		return errors.New("builds-record-start: missing app name")
@@ -92,8 +94,28 @@ func TriggerBuildsRecordStart(appName, buildID, pidStr, sourceStr string) error
 		StartedAt: time.Now().UTC(),
 		Status:    BuildStatusRunning,
 		Source:    source,
+		User:      common.ResolveUser(),
+		GitSHA:    ResolveGitSHA(appName),
+		Builder:   ResolveBuilder(appName),
 	}
-	return WriteBuild(b)
+	if targetID := os.Getenv("DOKKU_ROLLBACK_BUILD_ID"); source == BuildSourceRollback && targetID != "" {
+		target, err := ReadBuild(appName, targetID)
+		if err != nil {
+			common.LogWarn(fmt.Sprintf("builds-record-start: unable to read rollback target %s/%s: %s", appName, targetID, err))
+		} else {
+			b.GitSHA = target.GitSHA
+			b.Builder = target.Builder
+		}
+	}
+	if ResolveLogFormat(appName) == LogFormatJSONL {
+		b.LogFormat = LogFormatJSONL
+	}
+	if err := WriteBuild(b); err != nil {
+		return err
+	}
+
+	SendWebhook(b, WebhookEventStarted)
+	return nil
 }
 
 // TriggerBuildsRecordFinalize writes the terminal status onto an existing
@@ -134,12 +156,233 @@ func TriggerBuildsRecordFinalize(appName, buildID, exitStr string) error {
 	b.ExitCode = &exitCode
 	if exitCode == 0 {
 		b.Status = BuildStatusSucceeded
+		b.finishPhases(PhaseOutcomeSucceeded, now)
 	} else {
 		b.Status = BuildStatusFailed
+		b.finishPhases(PhaseOutcomeFailed, now)
 	}
 	if err := WriteBuild(b); err != nil {
 		return err
 	}
 
+	SendWebhook(b, WebhookEventFinished)
 	return PruneAppBuilds(appName)
 }
+
+// TriggerPostDeploy closes the proxy-rebuild phase and records the deployed
+// image and its id onto the in-flight build record.
+// The image is additionally tagged with a per-build tag so that it can still be
+// referenced by builds:rollback once later builds have moved the app's tag.
+//
+// Args: <app> <internal-port> <internal-ip-address> <image-tag>
+func TriggerPostDeploy(appName, imageTag string) error {
+	buildID := os.Getenv("DOKKU_BUILD_ID")
+	if buildID == "" {
+		return nil
+	}
+
+	recordPhase(appName, PhaseProxyRebuild, false)
+
+	b, err := ReadBuild(appName, buildID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	image, err := common.GetDeployingAppImageName(appName, imageTag, "")
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to resolve deployed image for build %s: %s", buildID, err))
+		return nil
+	}
+
+	buildImage := fmt.Sprintf("%s:%s", common.GetAppImageRepo(appName), RollbackImageTag(buildID))
+	if err := dockerTag(image, buildImage); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to tag deployed image for build %s: %s", buildID, err))
+		buildImage = image
+	}
+
+	b.Image = buildImage
+	if imageID, err := common.DockerInspect(image, "{{.Id}}"); err == nil {
+		b.ImageID = imageID
+	}
+	return WriteBuild(b)
+}
+
+// TriggerBuildsQueueEnter marks a build as queued behind the app's deploy lock.
+//
+// Args: <app> <build-id>
+func TriggerBuildsQueueEnter(appName, buildID string) error {
+	if appName == "" {
+		return errors.New("builds-queue-enter: missing app name")
+	}
+	if buildID == "" {
+		return errors.New("builds-queue-enter: missing build id")
+	}
+	return EnqueueBuild(appName, buildID)
+}
+
+// TriggerBuildsQueuePosition writes the queue position of a build to stdout:
+// the number of queued builds ahead of it, or "canceled" / "superseded" when
+// it was dropped from the queue. Like builds-generate-id, the output is
+// captured by bash callers and must not include log output.
+//
+// Args: <app> <build-id>
+func TriggerBuildsQueuePosition(appName, buildID string) error {
+	if appName == "" {
+		return errors.New("builds-queue-position: missing app name")
+	}
+	if buildID == "" {
+		return errors.New("builds-queue-position: missing build id")
+	}
+
+	position, err := QueuePosition(appName, buildID)
+	if err != nil {
+		return err
+	}
+	fmt.Println(position)
+	return nil
+}
+
+// TriggerBuildsQueueLeave marks a queued build as running once it has acquired
+// the deploy lock.
+//
+// Args: <app> <build-id>
+func TriggerBuildsQueueLeave(appName, buildID string) error {
+	if appName == "" {
+		return errors.New("builds-queue-leave: missing app name")
+	}
+	if buildID == "" {
+		return errors.New("builds-queue-leave: missing build id")
+	}
+	return DequeueBuild(appName, buildID)
+}
+
+// TriggerBuildsLogAnnotate reads build output on stdin and appends it to the
+// build's log file as jsonl entries. It is used by dokku_setup_build_capture
+// when the app's log-format is jsonl, once per output stream.
+//
+// Args: <app> <build-id> <stream>
+func TriggerBuildsLogAnnotate(appName, buildID, stream string) error {
+	if appName == "" {
+		return errors.New("builds-log-annotate: missing app name")
+	}
+	if buildID == "" {
+		return errors.New("builds-log-annotate: missing build id")
+	}
+	if stream != "stdout" && stream != "stderr" {
+		return fmt.Errorf("builds-log-annotate: invalid stream %q, must be one of: stdout, stderr", stream)
+	}
+	return AnnotateLog(appName, buildID, stream, os.Stdin)
+}
+
+// TriggerBuildsRecordPhase records the start or end of a named phase on an
+// in-flight build record. Plugins that run their own deploy steps may call it
+// to have them show up in builds:info.
+//
+// Args: <app> <build-id> <phase> <start|end> [<outcome>]
+func TriggerBuildsRecordPhase(appName, buildID, phase, event, outcomeStr string) error {
+	if appName == "" {
+		return errors.New("builds-record-phase: missing app name")
+	}
+	if buildID == "" {
+		return errors.New("builds-record-phase: missing build id")
+	}
+	if phase == "" {
+		return errors.New("builds-record-phase: missing phase name")
+	}
+
+	switch event {
+	case "start":
+		return StartPhase(appName, buildID, phase)
+	case "end":
+		outcome := PhaseOutcomeSucceeded
+		if outcomeStr != "" {
+			outcome = PhaseOutcome(outcomeStr)
+		}
+		if !outcome.Valid() {
+			return fmt.Errorf("builds-record-phase: invalid outcome %q, must be one of: failed, succeeded, canceled", outcomeStr)
+		}
+		return EndPhase(appName, buildID, phase, outcome)
+	}
+	return fmt.Errorf("builds-record-phase: invalid event %q, must be one of: start, end", event)
+}
+
+// TriggerCorePostExtract closes the extract phase of the in-flight build.
+func TriggerCorePostExtract(appName, rev string) error {
+	recordPhase(appName, PhaseExtract, false)
+	if rev != "" {
+		recordBuildOrigin(appName, func(b *Build) {
+			b.GitSHA = rev
+		})
+	}
+	return nil
+}
+
+// TriggerPreBuild starts the build phase of the in-flight build and records
+// the builder it is built with.
+func TriggerPreBuild(builderType, appName string) error {
+	recordPhase(appName, PhaseBuild, true)
+	if builderType != "" {
+		recordBuildOrigin(appName, func(b *Build) {
+			b.Builder = builderType
+		})
+	}
+	return nil
+}
+
+// TriggerPostBuild closes the build phase of the in-flight build.
+func TriggerPostBuild(appName string) error {
+	recordPhase(appName, PhaseBuild, false)
+	return nil
+}
+
+// TriggerPreReleaseBuilder starts the release phase of the in-flight build.
+// The app-json plugin runs the predeploy task from this trigger before the
+// builds plugin, so the predeploy phase precedes it.
+func TriggerPreReleaseBuilder(appName string) error {
+	recordPhase(appName, PhaseRelease, true)
+	return nil
+}
+
+// TriggerPostReleaseBuilder closes the release phase of the in-flight build.
+func TriggerPostReleaseBuilder(appName string) error {
+	recordPhase(appName, PhaseRelease, false)
+	return nil
+}
+
+// TriggerSchedulerDeploy starts the scheduler-deploy phase of the in-flight
+// build and snapshots the inputs it is deployed with.
+func TriggerSchedulerDeploy(appName, imageTag string) error {
+	recordPhase(appName, PhaseSchedulerDeploy, true)
+	SnapshotInputs(appName, imageTag)
+	return nil
+}
+
+// TriggerCheckDeploy starts the healthchecks phase of the in-flight build the
+// first time a container is checked.
+func TriggerCheckDeploy(appName string) error {
+	recordPhase(appName, PhaseHealthchecks, true)
+	return nil
+}
+
+// TriggerCorePostDeploy starts the proxy-rebuild phase of the in-flight build.
+func TriggerCorePostDeploy(appName string) error {
+	recordPhase(appName, PhaseProxyRebuild, true)
+	return nil
+}
+
+// TriggerBuildsWebhookDeliver delivers a queued build webhook. It is run in
+// the background by the builds plugin itself and retries failed deliveries.
+//
+// Args: <app> <delivery-id>
+func TriggerBuildsWebhookDeliver(appName, deliveryID string) error {
+	if appName == "" {
+		return errors.New("builds-webhook-deliver: missing app name")
+	}
+	if deliveryID == "" {
+		return errors.New("builds-webhook-deliver: missing delivery id")
+	}
+	return DeliverWebhook(appName, deliveryID)
+}
@@ -0,0 +1,166 @@
+package builds
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// WaitExitCodeTimeout is returned by builds:wait when the timeout elapses
+	// before the build finishes. It matches the exit code used by timeout(1).
+	WaitExitCodeTimeout = 124
+
+	// WaitExitCodeCanceled is returned by builds:wait for canceled builds.
+	WaitExitCodeCanceled = 125
+
+	// WaitExitCodeAbandoned is returned by builds:wait when the build process
+	// exits without finalizing its record.
+	WaitExitCodeAbandoned = 126
+)
+
+// WaitPollInterval is how often builds:wait re-reads the build record.
+var WaitPollInterval = time.Second
+
+// WaitFailed wraps the result of builds:wait so that the build's outcome is
+// propagated as the command's exit code
+type WaitFailed struct {
+	BuildID  string
+	Reason   string
+	exitCode int
+}
+
+// ExitCode returns an exit code to use in case this error bubbles
+// up into an os.Exit() call
+func (err *WaitFailed) ExitCode() int {
+	return err.exitCode
+}
+
+// Error returns a description of why the wait did not succeed
+func (err *WaitFailed) Error() string {
+	return fmt.Sprintf("Build %s %s", err.BuildID, err.Reason)
+}
+
+// CommandWait blocks until a build reaches a terminal status and returns an
+// error carrying the build's exit code when it did not succeed.
+func CommandWait(appName, buildID string, timeoutSeconds int, streamOutput bool) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+	if timeoutSeconds < 0 {
+		return errors.New("Invalid --timeout value, must be a non-negative number of seconds")
+	}
+
+	if buildID == "" || buildID == "current" {
+		id, err := resolveWaitBuildID(appName)
+		if err != nil {
+			return err
+		}
+		buildID = id
+	}
+
+	var deadline time.Time
+	if timeoutSeconds > 0 {
+		deadline = time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+	}
+
+	var offset int64
+	for {
+		b, err := ReadBuild(appName, buildID)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("No build record found for %s/%s", appName, buildID)
+			}
+			return err
+		}
+
+		if streamOutput {
+			offset, err = copyBuildLogFrom(b, offset, os.Stdout, false)
+			if err != nil {
+				common.LogWarn(fmt.Sprintf("Unable to read build log: %s", err))
+				streamOutput = false
+			}
+		}
+
+		if done, err := waitResult(b); done {
+			return err
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return &WaitFailed{
+				BuildID:  buildID,
+				Reason:   fmt.Sprintf("did not finish within %ds", timeoutSeconds),
+				exitCode: WaitExitCodeTimeout,
+			}
+		}
+
+		time.Sleep(WaitPollInterval)
+	}
+}
+
+// waitResult reports whether the build is finished and, if so, the error
+// builds:wait should exit with.
+func waitResult(b Build) (bool, error) {
+	switch b.DisplayStatus() {
+	case BuildStatusSucceeded:
+		return true, nil
+	case BuildStatusCanceled:
+		if b.SupersededBy != "" {
+			return true, &WaitFailed{BuildID: b.ID, Reason: fmt.Sprintf("was superseded by %s", b.SupersededBy), exitCode: WaitExitCodeCanceled}
+		}
+		return true, &WaitFailed{BuildID: b.ID, Reason: "was canceled", exitCode: WaitExitCodeCanceled}
+	case BuildStatusAbandoned:
+		return true, &WaitFailed{BuildID: b.ID, Reason: "was abandoned", exitCode: WaitExitCodeAbandoned}
+	case BuildStatusFailed:
+		if b.ExitCode != nil && *b.ExitCode < 0 {
+			// reaped records are finalized as failed with exit code -1
+			return true, &WaitFailed{BuildID: b.ID, Reason: "was abandoned", exitCode: WaitExitCodeAbandoned}
+		}
+		exitCode := 1
+		if b.ExitCode != nil && *b.ExitCode > 0 && *b.ExitCode < 256 {
+			exitCode = *b.ExitCode
+		}
+		return true, &WaitFailed{BuildID: b.ID, Reason: fmt.Sprintf("failed with exit code %d", exitCode), exitCode: exitCode}
+	}
+	return false, nil
+}
+
+// resolveWaitBuildID returns the in-flight build id from the deploy lock,
+// falling back to the most recent build record.
+func resolveWaitBuildID(appName string) (string, error) {
+	body, err := os.ReadFile(deployLockPath(appName))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if buildID := strings.TrimSpace(string(body)); buildID != "" {
+		return buildID, nil
+	}
+
+	b, ok := mostRecentBuild(appName)
+	if !ok {
+		return "", fmt.Errorf("No builds recorded for %s", appName)
+	}
+	return b.ID, nil
+}
+
+// copyLogFrom writes everything in the log file after offset to w and returns
+// the new offset. Compressed logs are read transparently and a missing log
+// file is not an error.
+func copyLogFrom(logPath string, offset int64, w io.Writer) (int64, error) {
+	f, err := openLogAt(logPath, offset)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return offset, nil
+		}
+		return offset, err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(w, f)
+	return offset + n, err
+}
@@ -0,0 +1,399 @@
+package builds
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/ryanuber/columnize"
+)
+
+const (
+	// WebhookEventStarted is sent when a build record is created.
+	WebhookEventStarted = "build.started"
+
+	// WebhookEventFinished is sent when a build record is finalized.
+	WebhookEventFinished = "build.finished"
+
+	// WebhookSignatureHeader carries the HMAC-SHA256 of the request body when
+	// a webhook-secret is configured.
+	WebhookSignatureHeader = "X-Dokku-Signature-256"
+
+	// WebhookMaxAttempts is the number of times a delivery is attempted
+	// before it is given up on.
+	WebhookMaxAttempts = 5
+
+	// webhookLogMaxEntries bounds the per-app delivery log. Once exceeded,
+	// the oldest entries are dropped.
+	webhookLogMaxEntries = 500
+)
+
+const (
+	WebhookOutcomeDelivered = "delivered"
+	WebhookOutcomeRetrying  = "retrying"
+	WebhookOutcomeFailed    = "failed"
+)
+
+var (
+	// WebhookRetryInterval is the delay before the first retry of a failed
+	// delivery. Each subsequent retry waits twice as long.
+	WebhookRetryInterval = 5 * time.Second
+
+	// WebhookTimeout bounds a single delivery attempt.
+	WebhookTimeout = 10 * time.Second
+)
+
+// WebhookPayload is the JSON body POSTed to the webhook-url.
+type WebhookPayload struct {
+	Event      string    `json:"event"`
+	DeliveryID string    `json:"delivery_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	App        string    `json:"app"`
+	GitSHA     string    `json:"git_sha,omitempty"`
+	URLs       []string  `json:"urls"`
+	Build      Build     `json:"build"`
+}
+
+// WebhookAttempt is a single entry of an app's webhook delivery log.
+type WebhookAttempt struct {
+	DeliveryID string    `json:"delivery_id"`
+	BuildID    string    `json:"build_id"`
+	Event      string    `json:"event"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	Timestamp  time.Time `json:"timestamp"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Outcome    string    `json:"outcome"`
+}
+
+// pendingWebhook is a delivery handed off to the background delivery process.
+type pendingWebhook struct {
+	URL     string         `json:"url"`
+	Payload WebhookPayload `json:"payload"`
+}
+
+// ValidateWebhookURL checks that a webhook-url is an absolute http(s) url.
+func ValidateWebhookURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Invalid webhook-url %q: must be an http or https url", value)
+	}
+	return nil
+}
+
+func webhookPendingDir(appName string) string {
+	return filepath.Join(AppDataDir(appName), "webhooks")
+}
+
+func webhookPendingPath(appName, deliveryID string) string {
+	return filepath.Join(webhookPendingDir(appName), deliveryID+".json")
+}
+
+// WebhookLogPath returns the path of an app's webhook delivery log.
+func WebhookLogPath(appName string) string {
+	return filepath.Join(AppDataDir(appName), "webhooks.log")
+}
+
+// SendWebhook queues a webhook for a build event and hands it off to a
+// detached delivery process, so that slow or failing endpoints never hold up
+// the deploy. Apps without a webhook-url are skipped.
+func SendWebhook(b Build, event string) {
+	deliveryID, err := queueWebhook(b, event)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to queue %s webhook for build %s: %s", event, b.ID, err))
+		return
+	}
+	if deliveryID == "" {
+		return
+	}
+
+	cmd := exec.Command("plugn", "trigger", "builds-webhook-deliver", b.App, deliveryID)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to deliver %s webhook for build %s: %s", event, b.ID, err))
+		return
+	}
+	cmd.Process.Release()
+}
+
+// queueWebhook writes a pending delivery for a build event and returns its
+// id, or an empty id when no webhook-url is configured.
+func queueWebhook(b Build, event string) (string, error) {
+	webhookURL := resolveProperty(b.App, "webhook-url")
+	if webhookURL == "" {
+		return "", nil
+	}
+
+	pending := pendingWebhook{
+		URL: webhookURL,
+		Payload: WebhookPayload{
+			Event:      event,
+			DeliveryID: GenerateBuildID(),
+			Timestamp:  time.Now().UTC(),
+			App:        b.App,
+			GitSHA:     b.GitSHA,
+			URLs:       []string{},
+			Build:      b,
+		},
+	}
+
+	if err := os.MkdirAll(webhookPendingDir(b.App), 0755); err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(pending)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(webhookPendingPath(b.App, pending.Payload.DeliveryID), body, 0600); err != nil {
+		return "", err
+	}
+	return pending.Payload.DeliveryID, nil
+}
+
+// DeliverWebhook POSTs a pending delivery, retrying with exponential backoff
+// until it is accepted or WebhookMaxAttempts is reached. Every attempt is
+// written to the app's delivery log.
+func DeliverWebhook(appName, deliveryID string) error {
+	path := webhookPendingPath(appName, deliveryID)
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	var pending pendingWebhook
+	if err := json.Unmarshal(body, &pending); err != nil {
+		return fmt.Errorf("parse pending webhook %s: %w", deliveryID, err)
+	}
+	if urls, err := triggerOutput("domains-urls", appName, "urls"); err == nil && urls != "" {
+		pending.Payload.URLs = strings.Fields(urls)
+	}
+
+	payload, err := json.Marshal(pending.Payload)
+	if err != nil {
+		return err
+	}
+	secret := resolveProperty(appName, "webhook-secret")
+
+	delay := WebhookRetryInterval
+	for attempt := 1; attempt <= WebhookMaxAttempts; attempt++ {
+		entry := WebhookAttempt{
+			DeliveryID: deliveryID,
+			BuildID:    pending.Payload.Build.ID,
+			Event:      pending.Payload.Event,
+			URL:        pending.URL,
+			Attempt:    attempt,
+			Timestamp:  time.Now().UTC(),
+		}
+		entry.StatusCode, err = postWebhook(pending.URL, deliveryID, pending.Payload.Event, secret, payload)
+		switch {
+		case err == nil:
+			entry.Outcome = WebhookOutcomeDelivered
+		case attempt == WebhookMaxAttempts:
+			entry.Outcome = WebhookOutcomeFailed
+			entry.Error = err.Error()
+		default:
+			entry.Outcome = WebhookOutcomeRetrying
+			entry.Error = err.Error()
+		}
+		if logErr := appendWebhookLog(appName, entry); logErr != nil {
+			common.LogWarn(fmt.Sprintf("Unable to write webhook delivery log: %s", logErr))
+		}
+
+		if entry.Outcome != WebhookOutcomeRetrying {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return nil
+}
+
+// postWebhook sends a single delivery attempt. Any non-2xx response is
+// treated as a failure.
+func postWebhook(webhookURL, deliveryID, event, secret string, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Dokku-Webhook")
+	req.Header.Set("X-Dokku-Event", event)
+	req.Header.Set("X-Dokku-Delivery", deliveryID)
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, payload))
+	}
+
+	client := &http.Client{Timeout: WebhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the value of the signature header for a payload.
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// appendWebhookLog adds an entry to the delivery log, dropping the oldest
+// entries once it grows past webhookLogMaxEntries.
+func appendWebhookLog(appName string, entry WebhookAttempt) error {
+	unlock, err := lockAppBuilds(appName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := ReadWebhookLog(appName)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, entry)
+	if len(entries) > webhookLogMaxEntries {
+		entries = entries[len(entries)-webhookLogMaxEntries:]
+	}
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+
+	path := WebhookLogPath(appName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadWebhookLog returns every entry of an app's delivery log, oldest first.
+func ReadWebhookLog(appName string) ([]WebhookAttempt, error) {
+	f, err := os.Open(WebhookLogPath(appName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []WebhookAttempt{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []WebhookAttempt{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry WebhookAttempt
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// webhookDelivery is the latest state of a delivery, as shown by
+// builds:webhooks.
+type webhookDelivery struct {
+	WebhookAttempt
+	Attempts int `json:"attempts"`
+}
+
+// summarizeWebhookLog collapses the delivery log to the latest attempt of
+// each delivery, newest first.
+func summarizeWebhookLog(entries []WebhookAttempt) []webhookDelivery {
+	byID := map[string]*webhookDelivery{}
+	for _, entry := range entries {
+		d, ok := byID[entry.DeliveryID]
+		if !ok {
+			d = &webhookDelivery{}
+			byID[entry.DeliveryID] = d
+		}
+		d.WebhookAttempt = entry
+		d.Attempts++
+	}
+
+	deliveries := make([]webhookDelivery, 0, len(byID))
+	for _, d := range byID {
+		deliveries = append(deliveries, *d)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].DeliveryID > deliveries[j].DeliveryID
+	})
+	return deliveries
+}
+
+// CommandWebhooks lists the webhook deliveries made for an app.
+func CommandWebhooks(appName, format string) error {
+	if format == "" {
+		format = "stdout"
+	}
+	if format != "stdout" && format != "json" {
+		return fmt.Errorf("Invalid format specified, supported formats: json, stdout")
+	}
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	entries, err := ReadWebhookLog(appName)
+	if err != nil {
+		return err
+	}
+	deliveries := summarizeWebhookLog(entries)
+
+	if format == "json" {
+		body, err := json.Marshal(deliveries)
+		if err != nil {
+			return err
+		}
+		common.Log(string(body))
+		return nil
+	}
+
+	if len(deliveries) == 0 {
+		common.LogInfo1(fmt.Sprintf("No webhook deliveries recorded for %s", appName))
+		return nil
+	}
+
+	rows := []string{"Delivery | Build | Event | Status | Attempts | Last Attempt | Response"}
+	for _, d := range deliveries {
+		response := "-"
+		if d.StatusCode != 0 {
+			response = fmt.Sprintf("%d", d.StatusCode)
+		}
+		if d.Outcome != WebhookOutcomeDelivered && d.Error != "" {
+			response = d.Error
+		}
+		rows = append(rows, fmt.Sprintf("%s | %s | %s | %s | %d | %s | %s", d.DeliveryID, d.BuildID, d.Event, d.Outcome, d.Attempts, d.Timestamp.Format(time.RFC3339), response))
+	}
+	fmt.Println(columnize.SimpleFormat(rows))
+	return nil
+}
@@ -11,6 +11,10 @@ import (
 	"time"
 )
 
+// RetainedImageTagPrefix is the tag prefix of app images retained for
+// rollbacks, which are skipped when pruning unused images
+const RetainedImageTagPrefix = "build-"
+
 // ComposeUpInput is the input for the ComposeUp function
 type ComposeUpInput struct {
 	ProjectName string
@@ -654,20 +658,64 @@ func listContainers(status string, appName string) ([]string, error) {
 	return DockerFilterContainers(filters)
 }
 
+// pruneUnusedImages removes the app's images that no container uses, much like
+// `docker image prune --all`, but keeps images tagged for rollbacks
 func pruneUnusedImages(appName string) {
-	args := []string{
-		"image",
-		"prune",
-		"--all",
-		"--force",
-		"--filter",
-		fmt.Sprintf("label=com.dokku.app-name=%v", appName),
+	result, err := CallExecCommand(ExecCommandInput{
+		Command: DockerBin(),
+		Args: []string{
+			"image",
+			"ls",
+			"--filter",
+			fmt.Sprintf("label=com.dokku.app-name=%v", appName),
+			"--format",
+			"{{.ID}} {{.Repository}}:{{.Tag}}",
+		},
+	})
+	if err != nil || result.ExitCode != 0 {
+		return
 	}
 
-	CallExecCommand(ExecCommandInput{
-		Command: DockerBin(),
-		Args:    args,
-	}) // nolint: errcheck
+	imageIDs := []string{}
+	references := map[string][]string{}
+	retained := map[string]bool{}
+	for _, line := range strings.Split(result.StdoutContents(), "\n") {
+		imageID, reference, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+
+		if _, seen := references[imageID]; !seen {
+			imageIDs = append(imageIDs, imageID)
+			references[imageID] = []string{}
+		}
+
+		tag := reference[strings.LastIndex(reference, ":")+1:]
+		if tag == "<none>" {
+			continue
+		}
+		if strings.HasPrefix(tag, RetainedImageTagPrefix) {
+			retained[imageID] = true
+		}
+		references[imageID] = append(references[imageID], reference)
+	}
+
+	for _, imageID := range imageIDs {
+		if retained[imageID] {
+			continue
+		}
+
+		containerIDs, err := DockerFilterContainers([]string{fmt.Sprintf("ancestor=%v", imageID)})
+		if err != nil || (len(containerIDs) > 0 && containerIDs[0] != "") {
+			continue
+		}
+
+		toRemove := references[imageID]
+		if len(toRemove) == 0 {
+			toRemove = []string{imageID}
+		}
+		RemoveImages(toRemove) // nolint: errcheck
+	}
 }
 
 // DockerRemoveContainers will call `docker container rm` on the specified containers
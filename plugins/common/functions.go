@@ -15,15 +15,7 @@ func filterApps(apps []string) ([]string, error) {
 		sshUser = os.Getenv("USER")
 	}
 
-	sshName := os.Getenv("SSH_NAME")
-	if sshName == "" {
-		sshName = os.Getenv("NAME")
-	}
-	if sshName == "" {
-		sshName = "default"
-	}
-
-	args := append([]string{sshUser, sshName}, apps...)
+	args := append([]string{sshUser, ResolveUser()}, apps...)
 	results, _ := CallPlugnTrigger(PlugnTriggerInput{
 		Trigger: "user-auth-app",
 		Args:    args,
@@ -38,6 +30,18 @@ func filterApps(apps []string) ([]string, error) {
 	return filteredApps, nil
 }
 
+// ResolveUser returns the ssh key name of the user that triggered the current
+// command, as exported by dokku_auth
+func ResolveUser() string {
+	if name := os.Getenv("SSH_NAME"); name != "" {
+		return name
+	}
+	if name := os.Getenv("NAME"); name != "" {
+		return name
+	}
+	return "default"
+}
+
 func removeEmptyEntries(s []string) []string {
 	var r []string
 	for _, str := range s {
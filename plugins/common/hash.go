@@ -0,0 +1,54 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValueHashKey returns the key stored in filename for use with HashValue,
+// generating it on first use.
+//
+// Value hashes let a record show whether a value changed without storing the
+// value itself. Keying them keeps low-entropy values from being guessed from a
+// record that has left the host, for example one pasted into an issue. They do
+// not protect values from anyone who can read the dokku data directories, as
+// the key is stored there alongside the values it hashes.
+func ValueHashKey(filename string) ([]byte, error) {
+	body, err := os.ReadFile(filename)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(body)))
+		if err != nil || len(key) != sha256.Size {
+			return nil, fmt.Errorf("Invalid value hash key in %s", filename)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("Unable to generate value hash key: %s", err.Error())
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filename, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// HashValue returns the hex-encoded HMAC of the given parts with a key from
+// ValueHashKey. The parts are separated by a NUL byte.
+func HashValue(key []byte, parts ...string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -34,6 +34,9 @@ type PlugnTriggerInput struct {
 	// StreamStderr prints stderr directly to os.Stderr as the command runs.
 	StreamStderr bool
 
+	// StdoutWriter is the writer to write stdout to
+	StdoutWriter io.Writer
+
 	// Trigger is the trigger to execute
 	Trigger string
 }
@@ -56,6 +59,7 @@ func CallPlugnTriggerWithContext(ctx context.Context, input PlugnTriggerInput) (
 		StreamStdio:        input.StreamStdio,
 		StreamStdout:       input.StreamStdout,
 		StreamStderr:       input.StreamStderr,
+		StdoutWriter:       input.StdoutWriter,
 	})
 
 	if input.PrintCommand || os.Getenv("DOKKU_TRACE") == "1" {
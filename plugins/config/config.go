@@ -3,11 +3,25 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"syscall"
 
+	appjson "github.com/dokku/dokku/plugins/app-json"
 	"github.com/dokku/dokku/plugins/common"
 )
 
+var (
+	// DefaultProperties is a map of all valid config properties with corresponding default property values
+	DefaultProperties = map[string]string{}
+
+	// GlobalProperties is a map of all valid global config properties
+	GlobalProperties = map[string]bool{
+		secretRefEnvPrefixProperty: true,
+		secretRefFileRootProperty:  true,
+	}
+)
+
 // Get retrieves a value from a config. If appName is empty the global config is used.
 func Get(appName string, key string) (value string, ok bool) {
 	env, err := loadAppOrGlobalEnv(appName)
@@ -31,18 +45,57 @@ func GetWithDefault(appName string, key string, defaultValue string) (value stri
 
 // SetMany variables in the environment. If appName is empty the global config is used. If restart is true the app is restarted.
 func SetMany(appName string, entries map[string]string, replace bool, restart bool) (err error) {
+	return SetManyInScope(appName, Scope{}, entries, replace, restart)
+}
+
+// SetManyInScope sets variables in a single scope of the environment. If restart is true the app is restarted.
+func SetManyInScope(appName string, scope Scope, entries map[string]string, replace bool, restart bool) (err error) {
 	global := appName == "" || appName == "--global"
-	env, err := loadAppOrGlobalEnv(appName)
+	keys, err := setManyInScope(appName, scope, entries, replace)
 	if err != nil {
 		return
 	}
+	if len(keys) != 0 {
+		triggerUpdate(appName, "set", keys)
+	}
+	if !global && restart && shouldRestartForScope(appName, scope) {
+		triggerRestart(appName)
+	}
+	return
+}
+
+// setManyInScope writes entries to a scope of the environment under the app's
+// config lock, returning the keys that were set
+func setManyInScope(appName string, scope Scope, entries map[string]string, replace bool) ([]string, error) {
+	unlock, err := lockConfig(appName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	global := appName == "" || appName == "--global"
+	env, err := loadEnvForUpdate(appName, scope)
+	if err != nil {
+		return nil, err
+	}
 	keys := make([]string, 0, len(entries))
-	for k := range entries {
-		if err = validateKey(k); err != nil {
-			return
+	for k, v := range entries {
+		if err := validateKey(k); err != nil {
+			return nil, err
+		}
+		if IsSecretRef(v) {
+			if _, err := parseSecretRef(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !global {
+		if err := validateAppJSONEnv(appName, entries); err != nil {
+			return nil, err
 		}
 	}
 
+	before := copyEnvMap(env.Map())
 	if replace {
 		env.Clear()
 	}
@@ -51,7 +104,11 @@ func SetMany(appName string, entries map[string]string, replace bool, restart bo
 		keys = append(keys, k)
 	}
 	if len(entries) != 0 {
-		common.LogInfo1Quiet("Setting config vars")
+		if scope.IsZero() {
+			common.LogInfo1Quiet("Setting config vars")
+		} else {
+			common.LogInfo1Quiet(fmt.Sprintf("Setting config vars for the %s", scope))
+		}
 		if os.Getenv("DOKKU_QUIET_OUTPUT") == "" {
 			fmt.Println(prettyPrintEnvEntries("       ", entries))
 		}
@@ -60,27 +117,62 @@ func SetMany(appName string, entries map[string]string, replace bool, restart bo
 			Filename: env.Filename(),
 			Mode:     os.FileMode(0600),
 		})
-		triggerUpdate(appName, "set", keys)
-	}
-	if !global && restart && shouldRestart(appName) {
-		triggerRestart(appName)
+		operation := "set"
+		if replace {
+			operation = "replace"
+		}
+		recordConfigHistoryOrWarn(recordConfigHistoryInput{
+			AppName:   appName,
+			Scope:     scope,
+			Operation: operation,
+			Before:    before,
+			After:     env.Map(),
+		})
 	}
-	return
+	return keys, nil
 }
 
 // UnsetMany a value in a config. If appName is empty the global config is used. If restart is true the app is restarted.
 func UnsetMany(appName string, keys []string, restart bool) (err error) {
+	return UnsetManyInScope(appName, Scope{}, keys, restart)
+}
+
+// UnsetManyInScope unsets values in a single scope of the environment. If restart is true the app is restarted.
+func UnsetManyInScope(appName string, scope Scope, keys []string, restart bool) (err error) {
 	global := appName == "" || appName == "--global"
-	env, err := loadAppOrGlobalEnv(appName)
+	changed, err := unsetManyInScope(appName, scope, keys)
 	if err != nil {
 		return
 	}
+	if changed {
+		triggerUpdate(appName, "unset", keys)
+	}
+	if !global && restart && shouldRestartForScope(appName, scope) {
+		triggerRestart(appName)
+	}
+	return
+}
+
+// unsetManyInScope removes keys from a scope of the environment under the
+// app's config lock, returning whether any key was removed
+func unsetManyInScope(appName string, scope Scope, keys []string) (bool, error) {
+	unlock, err := lockConfig(appName)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	env, err := loadEnvForUpdate(appName, scope)
+	if err != nil {
+		return false, err
+	}
 	var changed = false
 	for _, k := range keys {
-		if err = validateKey(k); err != nil {
-			return
+		if err := validateKey(k); err != nil {
+			return false, err
 		}
 	}
+	before := copyEnvMap(env.Map())
 	for _, k := range keys {
 		if _, hasKey := env.Map()[k]; hasKey {
 			common.LogInfo1Quiet(fmt.Sprintf("Unsetting %s", k))
@@ -96,22 +188,53 @@ func UnsetMany(appName string, keys []string, restart bool) (err error) {
 			Filename: env.Filename(),
 			Mode:     os.FileMode(0600),
 		})
-		triggerUpdate(appName, "unset", keys)
-	}
-	if !global && restart && shouldRestart(appName) {
-		triggerRestart(appName)
+		recordConfigHistoryOrWarn(recordConfigHistoryInput{
+			AppName:   appName,
+			Scope:     scope,
+			Operation: "unset",
+			Before:    before,
+			After:     env.Map(),
+		})
 	}
-	return
+	return changed, nil
 }
 
 // UnsetAll removes all config keys
 func UnsetAll(appName string, restart bool) (err error) {
+	return UnsetAllInScope(appName, Scope{}, restart)
+}
+
+// UnsetAllInScope removes all config keys from a single scope of the environment
+func UnsetAllInScope(appName string, scope Scope, restart bool) (err error) {
 	global := appName == "" || appName == "--global"
-	env, err := loadAppOrGlobalEnv(appName)
+	changed, err := unsetAllInScope(appName, scope)
 	if err != nil {
 		return
 	}
+	if changed {
+		triggerUpdate(appName, "clear", []string{})
+	}
+	if !global && restart && shouldRestartForScope(appName, scope) {
+		triggerRestart(appName)
+	}
+	return
+}
+
+// unsetAllInScope removes every key from a scope of the environment under the
+// app's config lock, returning whether any key was removed
+func unsetAllInScope(appName string, scope Scope) (bool, error) {
+	unlock, err := lockConfig(appName)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	env, err := loadEnvForUpdate(appName, scope)
+	if err != nil {
+		return false, err
+	}
 	var changed = false
+	before := copyEnvMap(env.Map())
 	for k := range env.Map() {
 		common.LogInfo1Quiet(fmt.Sprintf("Unsetting %s", k))
 		env.Unset(k)
@@ -123,12 +246,26 @@ func UnsetAll(appName string, restart bool) (err error) {
 			Filename: env.Filename(),
 			Mode:     os.FileMode(0600),
 		})
-		triggerUpdate(appName, "clear", []string{})
+		recordConfigHistoryOrWarn(recordConfigHistoryInput{
+			AppName:   appName,
+			Scope:     scope,
+			Operation: "clear",
+			Before:    before,
+			After:     env.Map(),
+		})
 	}
-	if !global && restart && shouldRestart(appName) {
-		triggerRestart(appName)
+	return changed, nil
+}
+
+// shouldRestartForScope returns whether a change to the given scope should
+// restart the app. Build phase values only take effect on the next build, so
+// restarting would not pick them up.
+func shouldRestartForScope(appName string, scope Scope) bool {
+	if scope.Phase == "build" {
+		common.LogInfo1Quiet("Build phase config vars take effect on the next build")
+		return false
 	}
-	return
+	return shouldRestart(appName)
 }
 
 func shouldRestart(appName string) bool {
@@ -167,13 +304,13 @@ func triggerUpdate(appName string, operation string, args []string) {
 	}
 }
 
-// getEnvironment for the given app (global config if appName is empty). Merge with global environment if merged is true.
-func getEnvironment(appName string, merged bool) (env *Env) {
+// getEnvironment for the given app (global config if appName is empty) and scope. Merge with global environment and the layers of the scope if merged is true.
+func getEnvironment(appName string, scope Scope, merged bool) (env *Env) {
 	var err error
 	if appName != "" && merged {
-		env, err = LoadMergedAppEnv(appName)
+		env, err = LoadMergedScopedAppEnv(appName, scope)
 	} else {
-		env, err = loadAppOrGlobalEnv(appName)
+		env, err = LoadScopedAppEnv(appName, scope)
 	}
 
 	if err != nil {
@@ -195,6 +332,80 @@ func getAppNameOrGlobal(appName string, global bool) (string, error) {
 	return appName, nil
 }
 
+// loadEnvForUpdate loads a single scope of an environment, ensuring the
+// directory it is written to exists
+func loadEnvForUpdate(appName string, scope Scope) (*Env, error) {
+	env, err := LoadScopedAppEnv(appName, scope)
+	if err != nil || scope.IsZero() {
+		return env, err
+	}
+
+	if err := setupScopeDir(env.Filename()); err != nil {
+		return nil, fmt.Errorf("Unable to create config directory for the %s: %s", scope, err.Error())
+	}
+	return env, nil
+}
+
+// lockConfig serializes config mutations of an app - or of the global
+// environment - by taking an exclusive flock on its config directory. A shared
+// flock on the config root is held as well, so mutations of different apps
+// run concurrently but never alongside a key rotation.
+func lockConfig(appName string) (func(), error) {
+	unlockRoot, err := flockConfigDirectory(getConfigRoot(), syscall.LOCK_SH)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(getGlobalFile())
+	if appName != "" && appName != "--global" {
+		appFile, _ := getAppFile(appName)
+		dir = filepath.Dir(appFile)
+	}
+	unlockApp, err := flockConfigDirectory(dir, syscall.LOCK_EX)
+	if err != nil {
+		unlockRoot()
+		return nil, err
+	}
+
+	return func() {
+		unlockApp()
+		unlockRoot()
+	}, nil
+}
+
+// lockAllConfig takes an exclusive flock on the config root, waiting for every
+// in-flight config mutation to finish and blocking new ones. It is held while
+// every environment on the host is rewritten.
+func lockAllConfig() (func(), error) {
+	return flockConfigDirectory(getConfigRoot(), syscall.LOCK_EX)
+}
+
+func getConfigRoot() string {
+	return filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "config")
+}
+
+// flockConfigDirectory takes a flock of the given type on a directory,
+// creating it if needed, and returns a function that releases it
+func flockConfigDirectory(dir string, how int) (func(), error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create config directory: %s", err.Error())
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open config directory: %s", err.Error())
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Unable to lock config directory: %s", err.Error())
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
 func loadAppOrGlobalEnv(appName string) (env *Env, err error) {
 	if appName == "" || appName == "--global" {
 		return LoadGlobalEnv()
@@ -202,6 +413,19 @@ func loadAppOrGlobalEnv(appName string) (env *Env, err error) {
 	return LoadAppEnv(appName)
 }
 
+// validateAppJSONEnv checks values against the env definitions of the app's
+// app.json. An unreadable app.json only warns, so that config can still be
+// changed to fix a broken deploy.
+func validateAppJSONEnv(appName string, entries map[string]string) error {
+	appJSON, err := appjson.GetAppJSON(appName)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to validate config vars against app.json: %s", err.Error()))
+		return nil
+	}
+
+	return appjson.ValidateEnv(appJSON.Env, entries, false)
+}
+
 func validateKey(key string) error {
 	r, _ := regexp.Compile("^[a-zA-Z_][a-zA-Z0-9_]*$")
 	if !r.MatchString(key) {
@@ -85,19 +85,19 @@ func TestConfigSetMany(t *testing.T) {
 	expectValue(testAppName, "testKey", "TESTING")
 
 	vals := []string{"testKey=updated", "testKey2=new"}
-	Expect(CommandSet(testAppName, vals, false, true, false)).To(Succeed())
+	Expect(CommandSet(testAppName, Scope{}, vals, false, true, false)).To(Succeed())
 	expectValue(testAppName, "testKey", "updated")
 	expectValue(testAppName, "testKey2", "new")
 
 	vals = []string{"testKey=updated_global", "testKey2=new_global"}
-	Expect(CommandSet("", vals, true, true, false)).To(Succeed())
+	Expect(CommandSet("", Scope{}, vals, true, true, false)).To(Succeed())
 	expectValue("", "testKey", "updated_global")
 	expectValue("", "testKey2", "new_global")
 	expectValue("", "globalKey", "GLOBAL_VALUE")
 	expectValue(testAppName, "testKey", "updated")
 	expectValue(testAppName, "testKey2", "new")
 
-	Expect(CommandSet(testAppName+"does_not_exist", vals, false, true, false)).ToNot(Succeed())
+	Expect(CommandSet(testAppName+"does_not_exist", Scope{}, vals, false, true, false)).ToNot(Succeed())
 }
 
 func TestConfigUnsetAll(t *testing.T) {
@@ -109,12 +109,12 @@ func TestConfigUnsetAll(t *testing.T) {
 	expectValue(testAppName, "testKey", "TESTING")
 	expectValue("", "testKey", "GLOBAL_TESTING")
 
-	Expect(CommandClear(testAppName, false, true)).To(Succeed())
+	Expect(CommandClear(testAppName, Scope{}, false, true)).To(Succeed())
 	expectNoValue(testAppName, "testKey")
 	expectNoValue(testAppName, "noKey")
 	expectNoValue(testAppName, "globalKey")
 
-	Expect(CommandClear(testAppName+"does-not-exist", false, true)).ToNot(Succeed())
+	Expect(CommandClear(testAppName+"does-not-exist", Scope{}, false, true)).ToNot(Succeed())
 }
 
 func TestConfigUnsetMany(t *testing.T) {
@@ -127,15 +127,15 @@ func TestConfigUnsetMany(t *testing.T) {
 	expectValue("", "testKey", "GLOBAL_TESTING")
 
 	keys := []string{"testKey", "noKey"}
-	Expect(CommandUnset(testAppName, keys, false, true)).To(Succeed())
+	Expect(CommandUnset(testAppName, Scope{}, keys, false, true)).To(Succeed())
 	expectNoValue(testAppName, "testKey")
 	expectValue("", "testKey", "GLOBAL_TESTING")
 
-	Expect(CommandUnset(testAppName, keys, false, true)).To(Succeed())
+	Expect(CommandUnset(testAppName, Scope{}, keys, false, true)).To(Succeed())
 	expectNoValue(testAppName, "testKey")
 	expectNoValue(testAppName, "globalKey")
 
-	Expect(CommandUnset(testAppName+"does-not-exist", keys, false, true)).ToNot(Succeed())
+	Expect(CommandUnset(testAppName+"does-not-exist", Scope{}, keys, false, true)).ToNot(Succeed())
 }
 
 func TestConfigImport(t *testing.T) {
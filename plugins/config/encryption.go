@@ -0,0 +1,468 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// encryptedValuePrefix marks a config value that is encrypted at rest
+const encryptedValuePrefix = "enc:v1:"
+
+// encryptionKey is a NaCl secretbox key
+type encryptionKey = [32]byte
+
+// getEncryptionKeyFile returns the path to the host key used to encrypt config values
+func getEncryptionKeyFile() string {
+	return filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "config", ".encryption-key")
+}
+
+// getPendingEncryptionKeyFile returns the path to the key a rotation is moving to.
+// It only exists while config:rotate-key is running, or if a rotation was interrupted.
+func getPendingEncryptionKeyFile() string {
+	return getEncryptionKeyFile() + ".pending"
+}
+
+// getPreviousEncryptionKeyFile returns the path to the key a rotation moved
+// away from. It is kept until every environment is confirmed to be readable
+// with the new key.
+func getPreviousEncryptionKeyFile() string {
+	return getEncryptionKeyFile() + ".old"
+}
+
+// EncryptionEnabled returns whether config values are encrypted at rest
+func EncryptionEnabled() bool {
+	return common.FileExists(getEncryptionKeyFile())
+}
+
+func generateEncryptionKey() (*encryptionKey, error) {
+	key := new(encryptionKey)
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, fmt.Errorf("Unable to generate encryption key: %s", err.Error())
+	}
+	return key, nil
+}
+
+func readEncryptionKey(filename string) (*encryptionKey, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read encryption key: %s", err.Error())
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(decoded) != len(encryptionKey{}) {
+		return nil, fmt.Errorf("Invalid encryption key in %s", filename)
+	}
+
+	key := new(encryptionKey)
+	copy(key[:], decoded)
+	return key, nil
+}
+
+func writeEncryptionKey(filename string, key *encryptionKey) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("Unable to create config directory: %s", err.Error())
+	}
+
+	contents := base64.StdEncoding.EncodeToString(key[:]) + "\n"
+	if err := os.WriteFile(filename, []byte(contents), 0600); err != nil {
+		return fmt.Errorf("Unable to write encryption key: %s", err.Error())
+	}
+
+	return common.SetPermissions(common.SetPermissionInput{
+		Filename: filename,
+		Mode:     os.FileMode(0600),
+	})
+}
+
+// currentEncryptionKey returns the key new values are encrypted with, or nil
+// if encryption is not enabled
+func currentEncryptionKey() (*encryptionKey, error) {
+	if !EncryptionEnabled() {
+		return nil, nil
+	}
+	return readEncryptionKey(getEncryptionKeyFile())
+}
+
+// decryptionKeys returns every key a stored value may be encrypted with. The
+// pending and previous keys are included so that values remain readable if a
+// rotation is interrupted.
+func decryptionKeys() ([]*encryptionKey, error) {
+	keys := []*encryptionKey{}
+	for _, filename := range []string{getEncryptionKeyFile(), getPendingEncryptionKeyFile(), getPreviousEncryptionKeyFile()} {
+		if !common.FileExists(filename) {
+			continue
+		}
+
+		key, err := readEncryptionKey(filename)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func isEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+func encryptValue(value string, key *encryptionKey) (string, error) {
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", fmt.Errorf("Unable to generate nonce: %s", err.Error())
+	}
+
+	sealed := secretbox.Seal(nonce[:], []byte(value), &nonce, key)
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptValue(value string, keys []*encryptionKey) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil || len(sealed) < 24+secretbox.Overhead {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], sealed[:24])
+	for _, key := range keys {
+		if opened, ok := secretbox.Open(nil, sealed[24:], &nonce, key); ok {
+			return string(opened), nil
+		}
+	}
+
+	if len(keys) == 0 {
+		return "", errors.New("value is encrypted but no encryption key exists")
+	}
+	return "", errors.New("value cannot be decrypted with the current encryption key")
+}
+
+// decryptEnvMap decrypts every encrypted value in envMap in place. Plaintext
+// values are left as-is, so files written before encryption was enabled load
+// unchanged.
+func decryptEnvMap(envMap map[string]string) error {
+	var keys []*encryptionKey
+	for k, v := range envMap {
+		if !isEncryptedValue(v) {
+			continue
+		}
+
+		if keys == nil {
+			var err error
+			if keys, err = decryptionKeys(); err != nil {
+				return err
+			}
+		}
+
+		decrypted, err := decryptValue(v, keys)
+		if err != nil {
+			return fmt.Errorf("Unable to decrypt config value %s: %s", k, err.Error())
+		}
+		envMap[k] = decrypted
+	}
+	return nil
+}
+
+// writeEnvFile writes envMap to filename as a dotenv file, encrypting every
+// value when encryption is enabled
+func writeEnvFile(envMap map[string]string, filename string) error {
+	key, err := currentEncryptionKey()
+	if err != nil {
+		return err
+	}
+	return writeEnvFileWithKey(envMap, filename, key)
+}
+
+// writeEnvFileWithKey writes envMap to filename, encrypting every value with
+// key. A nil key writes the values as plaintext.
+func writeEnvFileWithKey(envMap map[string]string, filename string, key *encryptionKey) error {
+	if key == nil {
+		return writeDotenvFile(envMap, filename)
+	}
+
+	encrypted := make(map[string]string, len(envMap))
+	for k, v := range envMap {
+		value, err := encryptValue(v, key)
+		if err != nil {
+			return err
+		}
+		encrypted[k] = value
+	}
+	return writeDotenvFile(encrypted, filename)
+}
+
+// writeDotenvFile replaces filename with envMap in dotenv format. The file is
+// written to a temporary file in the same directory and synced before being
+// renamed into place, so a failed write never leaves a truncated file behind.
+func writeDotenvFile(envMap map[string]string, filename string) error {
+	content, err := godotenv.Marshal(envMap)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// hasPlaintextValues returns whether the dotenv file at filename holds any
+// value that is not encrypted
+func hasPlaintextValues(filename string) (bool, error) {
+	if !common.FileExists(filename) {
+		return false, nil
+	}
+
+	envMap, err := godotenv.Read(filename)
+	if err != nil {
+		return false, err
+	}
+
+	for _, v := range envMap {
+		if !isEncryptedValue(v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// allEnvs loads the global environment and the environment of every app,
+// along with every scope of each app and every history snapshot of each
+func allEnvs() ([]*Env, error) {
+	global, err := LoadGlobalEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load global environment: %s", err.Error())
+	}
+
+	snapshots, err := historyEnvs("--global", Scope{})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load global config history: %s", err.Error())
+	}
+
+	envs := append([]*Env{global}, snapshots...)
+	apps, err := common.UnfilteredDokkuApps()
+	if err != nil {
+		// NoAppsExist is also returned when the apps cannot be listed at all
+		if !errors.Is(err, common.NoAppsExist) {
+			return nil, fmt.Errorf("Unable to list apps: %s", err.Error())
+		}
+		if _, err := os.ReadDir(common.MustGetEnv("DOKKU_ROOT")); err != nil {
+			return nil, fmt.Errorf("Unable to list apps: %s", err.Error())
+		}
+	}
+	for _, appName := range apps {
+		scopes, err := ListAppScopes(appName)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to list config scopes for %s: %s", appName, err.Error())
+		}
+
+		for _, scope := range append([]Scope{{}}, scopes...) {
+			env, err := LoadScopedAppEnv(appName, scope)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to load environment for %s: %s", appName, err.Error())
+			}
+
+			snapshots, err := historyEnvs(appName, scope)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to load config history for %s: %s", appName, err.Error())
+			}
+			envs = append(append(envs, env), snapshots...)
+		}
+	}
+
+	return envs, nil
+}
+
+// rewriteEnvs writes every environment back to disk with the given key
+func rewriteEnvs(envs []*Env, key *encryptionKey) error {
+	for _, env := range envs {
+		if env.Len() == 0 && !common.FileExists(env.Filename()) {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(env.Filename()), 0755); err != nil {
+			return fmt.Errorf("Unable to create config directory for %s: %s", env.name, err.Error())
+		}
+
+		if err := writeEnvFileWithKey(env.Map(), env.Filename(), key); err != nil {
+			return fmt.Errorf("Unable to write environment for %s: %s", env.name, err.Error())
+		}
+
+		if err := common.SetPermissions(common.SetPermissionInput{
+			Filename: env.Filename(),
+			Mode:     os.FileMode(0600),
+		}); err != nil {
+			return fmt.Errorf("Unable to set permissions on environment for %s: %s", env.name, err.Error())
+		}
+	}
+	return nil
+}
+
+// RotateEncryptionKey re-encrypts every environment with a new host key,
+// enabling encryption if it is not yet enabled. The new key is staged as a
+// pending key until every environment has been rewritten, so an interrupted
+// rotation leaves every value readable and is resumed by the next call. The
+// replaced key is kept until every environment is confirmed to be readable
+// with the new key. The config lock is held throughout, so no value is
+// written with the old key after it has been replaced.
+func RotateEncryptionKey() error {
+	unlock, err := lockAllConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	envs, err := allEnvs()
+	if err != nil {
+		return err
+	}
+
+	pendingKeyFile := getPendingEncryptionKeyFile()
+	var key *encryptionKey
+	if common.FileExists(pendingKeyFile) {
+		common.LogWarn("Resuming an interrupted key rotation")
+		key, err = readEncryptionKey(pendingKeyFile)
+	} else {
+		if key, err = generateEncryptionKey(); err == nil {
+			err = writeEncryptionKey(pendingKeyFile, key)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := rewriteEnvs(envs, key); err != nil {
+		return err
+	}
+
+	previousKeyFile := getPreviousEncryptionKeyFile()
+	if EncryptionEnabled() && !common.FileExists(previousKeyFile) {
+		previous, err := currentEncryptionKey()
+		if err != nil {
+			return err
+		}
+		if err := writeEncryptionKey(previousKeyFile, previous); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(pendingKeyFile, getEncryptionKeyFile()); err != nil {
+		return fmt.Errorf("Unable to replace encryption key: %s", err.Error())
+	}
+
+	if err := verifyEnvsKey(envs, key); err != nil {
+		return fmt.Errorf("%s, the previous key is kept in %s", err.Error(), previousKeyFile)
+	}
+
+	if err := os.Remove(previousKeyFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to remove previous encryption key: %s", err.Error())
+	}
+
+	return nil
+}
+
+// verifyEnvsKey checks that every value of every environment on disk is
+// encrypted with key
+func verifyEnvsKey(envs []*Env, key *encryptionKey) error {
+	for _, env := range envs {
+		if !common.FileExists(env.Filename()) {
+			continue
+		}
+
+		envMap, err := godotenv.Read(env.Filename())
+		if err != nil {
+			return fmt.Errorf("Unable to read environment for %s: %s", env.name, err.Error())
+		}
+		for k, v := range envMap {
+			if !isEncryptedValue(v) {
+				return fmt.Errorf("Config value %s for %s was not encrypted", k, env.name)
+			}
+			if _, err := decryptValue(v, []*encryptionKey{key}); err != nil {
+				return fmt.Errorf("Config value %s for %s is not readable with the new key", k, env.name)
+			}
+		}
+	}
+	return nil
+}
+
+// DisableEncryption writes every environment back as plaintext and removes
+// the host key
+func DisableEncryption() error {
+	unlock, err := lockAllConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	envs, err := allEnvs()
+	if err != nil {
+		return err
+	}
+
+	if err := rewriteEnvs(envs, nil); err != nil {
+		return err
+	}
+
+	for _, filename := range []string{getPendingEncryptionKeyFile(), getPreviousEncryptionKeyFile(), getEncryptionKeyFile()} {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Unable to remove encryption key: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// encryptPlaintextEnvs encrypts any environment that still holds plaintext
+// values, such as one written before the host key was put in place
+func encryptPlaintextEnvs() error {
+	unlock, err := lockAllConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	key, err := currentEncryptionKey()
+	if err != nil || key == nil {
+		return err
+	}
+
+	envs, err := allEnvs()
+	if err != nil {
+		return err
+	}
+
+	plaintext := []*Env{}
+	for _, env := range envs {
+		ok, err := hasPlaintextValues(env.Filename())
+		if err != nil {
+			return fmt.Errorf("Unable to read environment for %s: %s", env.name, err.Error())
+		}
+		if ok {
+			plaintext = append(plaintext, env)
+		}
+	}
+
+	return rewriteEnvs(plaintext, key)
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/joho/godotenv"
+)
+
+func readRawEnv(t *testing.T, filename string) map[string]string {
+	t.Helper()
+	envMap, err := godotenv.Read(filename)
+	if err != nil {
+		t.Fatalf("godotenv.Read(%s): %v", filename, err)
+	}
+	return envMap
+}
+
+func TestEncryptValueRoundtrip(t *testing.T) {
+	key, err := generateEncryptionKey()
+	if err != nil {
+		t.Fatalf("generateEncryptionKey: %v", err)
+	}
+	other, err := generateEncryptionKey()
+	if err != nil {
+		t.Fatalf("generateEncryptionKey: %v", err)
+	}
+
+	encrypted, err := encryptValue("s3cr'et\nvalue", key)
+	if err != nil {
+		t.Fatalf("encryptValue: %v", err)
+	}
+	if !isEncryptedValue(encrypted) || strings.Contains(encrypted, "s3cr") {
+		t.Fatalf("encryptValue returned %q", encrypted)
+	}
+
+	decrypted, err := decryptValue(encrypted, []*encryptionKey{other, key})
+	if err != nil {
+		t.Fatalf("decryptValue: %v", err)
+	}
+	if decrypted != "s3cr'et\nvalue" {
+		t.Errorf("decryptValue = %q", decrypted)
+	}
+
+	if _, err := decryptValue(encrypted, []*encryptionKey{other}); err == nil {
+		t.Error("expected decryptValue to fail with the wrong key")
+	}
+	if _, err := decryptValue(encryptedValuePrefix+"bm90LWEtYm94", []*encryptionKey{key}); err == nil {
+		t.Error("expected decryptValue to fail on a malformed value")
+	}
+}
+
+func TestEncryptionWriteAndLoad(t *testing.T) {
+	dokkuRoot, libRoot := setupMigrateEnv(t)
+	if err := os.MkdirAll(filepath.Join(dokkuRoot, "alpha"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := setupAppConfigDir("alpha"); err != nil {
+		t.Fatalf("setupAppConfigDir: %v", err)
+	}
+
+	if err := SetMany("alpha", map[string]string{"PLAIN": "before"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+
+	appFile, _ := getAppFile("alpha")
+	if got := readRawEnv(t, appFile)["PLAIN"]; got != "before" {
+		t.Fatalf("expected a plaintext value before encryption, got %q", got)
+	}
+
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	if !EncryptionEnabled() {
+		t.Fatal("expected encryption to be enabled")
+	}
+	if _, err := os.Stat(filepath.Join(libRoot, "config", ".encryption-key.pending")); !os.IsNotExist(err) {
+		t.Errorf("expected the pending key to be removed, got err=%v", err)
+	}
+
+	if err := SetMany("alpha", map[string]string{"SECRET": "hunter2"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	for key, value := range readRawEnv(t, appFile) {
+		if !isEncryptedValue(value) {
+			t.Errorf("expected %s to be encrypted on disk, got %q", key, value)
+		}
+	}
+	expectEnvValue(t, "alpha", "PLAIN", "before")
+	expectEnvValue(t, "alpha", "SECRET", "hunter2")
+
+	env, err := LoadMergedAppEnv("alpha")
+	if err != nil {
+		t.Fatalf("LoadMergedAppEnv: %v", err)
+	}
+	if got, err := env.Export(ExportFormatDockerArgs); err != nil || got != "--env=PLAIN='before' --env=SECRET='hunter2'" {
+		t.Errorf("docker-args export = %q, %v", got, err)
+	}
+
+	before := readRawEnv(t, appFile)["SECRET"]
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	if after := readRawEnv(t, appFile)["SECRET"]; after == before {
+		t.Error("expected rotation to re-encrypt the value")
+	}
+	expectEnvValue(t, "alpha", "SECRET", "hunter2")
+
+	if err := DisableEncryption(); err != nil {
+		t.Fatalf("DisableEncryption: %v", err)
+	}
+	if EncryptionEnabled() {
+		t.Error("expected encryption to be disabled")
+	}
+	if got := readRawEnv(t, appFile)["SECRET"]; got != "hunter2" {
+		t.Errorf("expected a plaintext value after decryption, got %q", got)
+	}
+}
+
+// TestRotateEncryptionKey_ResumesInterruptedRotation covers a rotation that
+// rewrote some environments with the pending key before it was interrupted
+func TestRotateEncryptionKey_ResumesInterruptedRotation(t *testing.T) {
+	setupMigrateEnv(t)
+	if err := MigrateEnvFiles(); err != nil {
+		t.Fatalf("MigrateEnvFiles: %v", err)
+	}
+	if err := SetMany("--global", map[string]string{"GLOBAL": "value"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+
+	pending, err := generateEncryptionKey()
+	if err != nil {
+		t.Fatalf("generateEncryptionKey: %v", err)
+	}
+	if err := writeEncryptionKey(getPendingEncryptionKeyFile(), pending); err != nil {
+		t.Fatalf("writeEncryptionKey: %v", err)
+	}
+	if err := writeEnvFileWithKey(map[string]string{"GLOBAL": "value"}, getGlobalFile(), pending); err != nil {
+		t.Fatalf("writeEnvFileWithKey: %v", err)
+	}
+	expectEnvValue(t, "--global", "GLOBAL", "value")
+
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	current, err := readEncryptionKey(getEncryptionKeyFile())
+	if err != nil {
+		t.Fatalf("readEncryptionKey: %v", err)
+	}
+	if *current != *pending {
+		t.Error("expected the resumed rotation to finish with the pending key")
+	}
+	if common.FileExists(getPreviousEncryptionKeyFile()) {
+		t.Error("expected the previous key to be removed once every environment was rewritten")
+	}
+	expectEnvValue(t, "--global", "GLOBAL", "value")
+}
+
+func TestRotateEncryptionKey_WaitsForConfigLock(t *testing.T) {
+	setupMigrateEnv(t)
+	if err := MigrateEnvFiles(); err != nil {
+		t.Fatalf("MigrateEnvFiles: %v", err)
+	}
+	if err := SetMany("--global", map[string]string{"GLOBAL": "value"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	before, err := readEncryptionKey(getEncryptionKeyFile())
+	if err != nil {
+		t.Fatalf("readEncryptionKey: %v", err)
+	}
+
+	unlock, err := lockConfig("--global")
+	if err != nil {
+		t.Fatalf("lockConfig: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RotateEncryptionKey()
+	}()
+
+	select {
+	case err := <-done:
+		unlock()
+		t.Fatalf("expected the rotation to wait for the config lock, got %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	current, err := readEncryptionKey(getEncryptionKeyFile())
+	if err != nil {
+		t.Fatalf("readEncryptionKey: %v", err)
+	}
+	if *current != *before {
+		t.Error("expected the key to be unchanged while the config lock is held")
+	}
+
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	expectEnvValue(t, "--global", "GLOBAL", "value")
+}
+
+func TestLockConfig_AppsDoNotBlockEachOther(t *testing.T) {
+	setupMigrateEnv(t)
+	unlock, err := lockConfig("app-one")
+	if err != nil {
+		t.Fatalf("lockConfig: %v", err)
+	}
+	defer unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		unlockOther, err := lockConfig("app-two")
+		if err == nil {
+			unlockOther()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("lockConfig: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the lock for one app not to block another app")
+	}
+}
+
+func TestMigrateEnvFiles_EncryptsPlaintextEnv(t *testing.T) {
+	setupMigrateEnv(t)
+	if err := MigrateEnvFiles(); err != nil {
+		t.Fatalf("first MigrateEnvFiles: %v", err)
+	}
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+
+	if err := os.WriteFile(getGlobalFile(), []byte("RESTORED=plaintext\n"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := MigrateEnvFiles(); err != nil {
+		t.Fatalf("MigrateEnvFiles: %v", err)
+	}
+
+	if got := readRawEnv(t, getGlobalFile())["RESTORED"]; !isEncryptedValue(got) {
+		t.Errorf("expected RESTORED to be encrypted on disk, got %q", got)
+	}
+	expectEnvValue(t, "--global", "RESTORED", "plaintext")
+}
@@ -169,33 +169,32 @@ func (e *Env) Write() error {
 	if e.filename == "" {
 		return errors.New("this Env was created unbound to a file")
 	}
-	return godotenv.Write(e.Map(), e.filename)
+	return writeEnvFile(e.Map(), e.filename)
 }
 
 // Export the Env in the given format
-func (e *Env) Export(format ExportFormat) string {
+func (e *Env) Export(format ExportFormat) (string, error) {
 	switch format {
 	case ExportFormatExports:
-		return e.ExportfileString()
+		return e.ExportfileString(), nil
 	case ExportFormatEnvfile:
-		return e.EnvfileString()
+		return e.EnvfileString(), nil
 	case ExportFormatDockerArgs:
 		return e.DockerArgsString()
 	case ExportFormatDockerArgsKeys:
-		return e.DockerArgsKeysString()
+		return e.DockerArgsKeysString(), nil
 	case ExportFormatShell:
-		return e.ShellString()
+		return e.ShellString(), nil
 	case ExportFormatPretty:
-		return prettyPrintEnvEntries("", e.Map())
+		return prettyPrintEnvEntries("", e.Map()), nil
 	case ExportFormatJSON:
-		return e.JSONString()
+		return e.JSONString(), nil
 	case ExportFormatJSONList:
-		return e.JSONListString()
+		return e.JSONListString(), nil
 	case ExportFormatPackArgKeys:
-		return e.PackArgKeysAsString()
+		return e.PackArgKeysAsString(), nil
 	default:
-		common.LogFail(fmt.Sprintf("Unknown export format: %v", format))
-		return ""
+		return "", fmt.Errorf("Unknown export format: %v", format)
 	}
 }
 
@@ -211,8 +210,13 @@ func (e *Env) ExportfileString() string {
 }
 
 // DockerArgsString gets the contents of this Env in the form -env=KEY=VALUE --env...
-func (e *Env) DockerArgsString() string {
-	return e.stringWithPrefixAndSeparator("--env=", " ")
+// with any secret references resolved
+func (e *Env) DockerArgsString() (string, error) {
+	resolved, err := e.resolveSecretRefs()
+	if err != nil {
+		return "", err
+	}
+	return resolved.stringWithPrefixAndSeparator("--env=", " "), nil
 }
 
 // DockerArgsKeysString gets the contents of this Env in the form -env=KEY --env...
@@ -272,13 +276,19 @@ func (e *Env) ShellString() string {
 
 // ExportBundle writes a tarfile of the environment to the given io.Writer.
 // for every environment variable there is a file with the variable's key
-// with its content set to the variable's value
+// with its content set to the variable's value, with any secret references
+// resolved
 func (e *Env) ExportBundle(dest io.Writer) error {
+	resolved, err := e.resolveSecretRefs()
+	if err != nil {
+		return err
+	}
+
 	tarfile := tar.NewWriter(dest)
 	defer tarfile.Close()
 
-	for _, k := range e.Keys() {
-		val, _ := e.Get(k)
+	for _, k := range resolved.Keys() {
+		val, _ := resolved.Get(k)
 		valbin := []byte(val)
 
 		header := &tar.Header{
@@ -344,6 +354,10 @@ func loadFromFile(name string, filename string) (env *Env, err error) {
 		}
 	}
 
+	if err := decryptEnvMap(envMap); err != nil {
+		return nil, err
+	}
+
 	dirty := false
 	for k := range envMap {
 		if err := validateKey(k); err != nil {
@@ -353,7 +367,7 @@ func loadFromFile(name string, filename string) (env *Env, err error) {
 		}
 	}
 	if dirty {
-		if err := godotenv.Write(envMap, filename); err != nil {
+		if err := writeEnvFile(envMap, filename); err != nil {
 			common.LogFail(fmt.Sprintf("Error writing back config for %s after removing invalid keys", name))
 		}
 	}
@@ -2,16 +2,29 @@ package config
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	appjson "github.com/dokku/dokku/plugins/app-json"
 	"github.com/dokku/dokku/plugins/common"
+	"github.com/hashicorp/go-multierror"
+	"github.com/ryanuber/columnize"
 )
 
-func export(appName string, merged bool, format string) error {
-	env := getEnvironment(appName, merged)
+func export(appName string, scope Scope, merged bool, format string, resolveSecretRefs bool) error {
+	env := getEnvironment(appName, scope, merged)
+	if resolveSecretRefs {
+		resolved, err := env.resolveSecretRefs()
+		if err != nil {
+			return err
+		}
+		env = resolved
+	}
 	exportType := ExportFormatExports
 	suffix := "\n"
 
@@ -36,29 +49,32 @@ func export(appName string, merged bool, format string) error {
 		suffix = " "
 	}
 
-	exported := env.Export(exportType)
+	exported, err := env.Export(exportType)
+	if err != nil {
+		return err
+	}
 	fmt.Print(exported + suffix)
 	return nil
 }
 
 // SubBundle implements the logic for config:bundle without app name validation
-func SubBundle(appName string, merged bool) error {
-	env := getEnvironment(appName, merged)
+func SubBundle(appName string, scope Scope, merged bool) error {
+	env := getEnvironment(appName, scope, merged)
 	return env.ExportBundle(os.Stdout)
 }
 
 // SubClear implements the logic for config:clear without app name validation
-func SubClear(appName string, noRestart bool) error {
-	return UnsetAll(appName, !noRestart)
+func SubClear(appName string, scope Scope, noRestart bool) error {
+	return UnsetAllInScope(appName, scope, !noRestart)
 }
 
 // SubExport implements the logic for config:export without app name validation
-func SubExport(appName string, merged bool, format string) error {
-	return export(appName, merged, format)
+func SubExport(appName string, scope Scope, merged bool, format string, resolveSecretRefs bool) error {
+	return export(appName, scope, merged, format, resolveSecretRefs)
 }
 
 // SubGet implements the logic for config:get without app name validation
-func SubGet(appName string, keys []string, quoted bool) error {
+func SubGet(appName string, scope Scope, keys []string, quoted bool) error {
 	if len(keys) == 0 {
 		return errors.New("Expected: key")
 	}
@@ -67,7 +83,12 @@ func SubGet(appName string, keys []string, quoted bool) error {
 		return fmt.Errorf("Unexpected argument(s): %v", keys[1:])
 	}
 
-	value, ok := Get(appName, keys[0])
+	env, err := LoadScopedAppEnv(appName, scope)
+	if err != nil {
+		return err
+	}
+
+	value, ok := env.Get(keys[0])
 	if !ok {
 		os.Exit(1)
 		return nil
@@ -82,6 +103,64 @@ func SubGet(appName string, keys []string, quoted bool) error {
 	return nil
 }
 
+// SubHistory implements the logic for config:history without app name validation
+func SubHistory(appName string, scope Scope, format string) error {
+	if format == "" {
+		format = "stdout"
+	}
+	if format != "stdout" && format != "json" {
+		return fmt.Errorf("Invalid format specified, supported formats: json, stdout")
+	}
+
+	snapshots, err := FetchConfigHistory(appName, scope)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		body, err := json.Marshal(snapshots)
+		if err != nil {
+			return err
+		}
+		common.Log(string(body))
+		return nil
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No config changes recorded")
+		return nil
+	}
+
+	rows := []string{"Version | Operation | Changes | User | Created"}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snapshot := snapshots[i]
+		operation := snapshot.Operation
+		if snapshot.RolledBackTo != 0 {
+			operation = fmt.Sprintf("%s to %d", operation, snapshot.RolledBackTo)
+		}
+
+		changes := []string{}
+		for _, change := range snapshot.Changes {
+			changes = append(changes, changeSymbols[change.Action]+change.Key)
+		}
+
+		user := snapshot.User
+		if user == "" {
+			user = "-"
+		}
+
+		rows = append(rows, fmt.Sprintf("%d | %s | %s | %s | %s",
+			snapshot.Version,
+			operation,
+			strings.Join(changes, " "),
+			user,
+			snapshot.CreatedAt.Format(time.RFC3339),
+		))
+	}
+	fmt.Println(columnize.SimpleFormat(rows))
+	return nil
+}
+
 // SubImport imports environment variables from a file
 func SubImport(appName string, replace bool, noRestart bool, format string, filename string) error {
 	if filename == "-" {
@@ -121,16 +200,30 @@ func SubImport(appName string, replace bool, noRestart bool, format string, file
 }
 
 // SubKeys implements the logic for config:keys without app name validation
-func SubKeys(appName string, merged bool) error {
-	env := getEnvironment(appName, merged)
+func SubKeys(appName string, scope Scope, merged bool) error {
+	env := getEnvironment(appName, scope, merged)
 	for _, k := range env.Keys() {
 		fmt.Println(k)
 	}
 	return nil
 }
 
+// SubRollback implements the logic for config:rollback without app name validation
+func SubRollback(appName string, scope Scope, version string, noRestart bool) error {
+	if version == "" {
+		return errors.New("Expected: version")
+	}
+
+	v, err := strconv.Atoi(version)
+	if err != nil || v < 1 {
+		return fmt.Errorf("Invalid version: %s", version)
+	}
+
+	return Rollback(appName, scope, v, !noRestart)
+}
+
 // SubSet implements the logic for config:set without app name validation
-func SubSet(appName string, pairs []string, noRestart bool, encoded bool) error {
+func SubSet(appName string, scope Scope, pairs []string, noRestart bool, encoded bool) error {
 	if len(pairs) == 0 {
 		return errors.New("At least one env pair must be given")
 	}
@@ -153,38 +246,90 @@ func SubSet(appName string, pairs []string, noRestart bool, encoded bool) error
 		updated[key] = value
 	}
 
-	return SetMany(appName, updated, false, !noRestart)
+	return SetManyInScope(appName, scope, updated, false, !noRestart)
 }
 
 // SubShow implements the logic for config:show without app name validation
-func SubShow(appName string, merged bool, shell bool, export bool) error {
-	env := getEnvironment(appName, merged)
+func SubShow(appName string, scope Scope, merged bool, shell bool, export bool) error {
+	env := getEnvironment(appName, scope, merged)
 	if shell && export {
 		return errors.New("Only one of --shell and --export can be given")
 	}
 	if shell {
 		common.LogWarn("Deprecated: Use 'config:export --format shell' instead")
-		fmt.Print(env.Export(ExportFormatShell))
+		fmt.Print(env.ShellString())
 	} else if export {
 		common.LogWarn("Deprecated: Use 'config:export --format exports' instead")
-		fmt.Println(env.Export(ExportFormatExports))
+		fmt.Println(env.ExportfileString())
 	} else {
 		contextName := "global"
 		if appName != "" {
 			contextName = appName
 		}
+		if !scope.IsZero() {
+			contextName = fmt.Sprintf("%s %s", contextName, scope)
+		}
 		common.LogInfo2Quiet(contextName + " env vars")
-		fmt.Println(env.Export(ExportFormatPretty))
+		fmt.Println(prettyPrintEnvEntries("", env.Map()))
 	}
 
 	return nil
 }
 
 // SubUnset implements the logic for config:unset without app name validation
-func SubUnset(appName string, keys []string, noRestart bool) error {
+func SubUnset(appName string, scope Scope, keys []string, noRestart bool) error {
 	if len(keys) == 0 {
 		return fmt.Errorf("At least one key must be given")
 	}
 
-	return UnsetMany(appName, keys, !noRestart)
+	return UnsetManyInScope(appName, scope, keys, !noRestart)
+}
+
+// SubValidate implements the logic for config:validate without app name validation
+func SubValidate(appName string) error {
+	appJSON, err := appjson.GetAppJSON(appName)
+	if err != nil {
+		return err
+	}
+
+	if len(appJSON.Env) == 0 {
+		common.LogInfo1Quiet(fmt.Sprintf("No env definitions found in app.json for %s", appName))
+		return nil
+	}
+
+	common.LogInfo1Quiet(fmt.Sprintf("Validating config vars of %s against app.json", appName))
+	env, err := LoadMergedAppEnv(appName)
+	if err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	if err := appjson.ValidateEnv(appJSON.Env, env.Map(), true); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	scopes, err := ListAppScopes(appName)
+	if err != nil {
+		return err
+	}
+	for _, scope := range scopes {
+		scoped, err := LoadScopedAppEnv(appName, scope)
+		if err != nil {
+			return err
+		}
+
+		var merr *multierror.Error
+		if errors.As(appjson.ValidateEnv(appJSON.Env, scoped.Map(), false), &merr) {
+			for _, err := range merr.Errors {
+				result = multierror.Append(result, fmt.Errorf("%w (%s)", err, scope))
+			}
+		}
+	}
+
+	if err := result.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	common.LogVerbose("All config vars are valid")
+	return nil
 }
@@ -0,0 +1,449 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// configHistoryRetention is the number of snapshots kept for each environment
+const configHistoryRetention = 50
+
+// ConfigChange is a single key changed by a config mutation
+type ConfigChange struct {
+	// Key is the config key that changed
+	Key string `json:"key"`
+
+	// Action is one of added, changed or removed
+	Action string `json:"action"`
+
+	// Hash is the HMAC-SHA256 of the new value keyed with the host's history
+	// key, and is empty for removed keys
+	Hash string `json:"hash,omitempty"`
+}
+
+// ConfigSnapshot is the persisted record of a single config mutation. The
+// environment as it stood after the mutation is stored alongside it.
+type ConfigSnapshot struct {
+	// Version is the sequential version of the environment
+	Version int `json:"version"`
+
+	// App is the app the environment belongs to, or --global
+	App string `json:"app"`
+
+	// Phase is the phase the environment is scoped to, if any
+	Phase string `json:"phase,omitempty"`
+
+	// ProcessType is the process type the environment is scoped to, if any
+	ProcessType string `json:"process_type,omitempty"`
+
+	// Operation is the kind of mutation, one of initial, set, replace, unset,
+	// clear or rollback
+	Operation string `json:"operation"`
+
+	// RolledBackTo is the version a rollback restored
+	RolledBackTo int `json:"rolled_back_to,omitempty"`
+
+	// Changes are the keys the mutation changed
+	Changes []ConfigChange `json:"changes"`
+
+	// User is the ssh key name of the user that made the change
+	User string `json:"user"`
+
+	// CreatedAt is when the change was made
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// changeSymbols prefixes each changed key in the config:history output
+var changeSymbols = map[string]string{
+	"added":   "+",
+	"changed": "~",
+	"removed": "-",
+}
+
+// recordConfigHistoryInput contains the inputs to recordConfigHistory
+type recordConfigHistoryInput struct {
+	AppName      string
+	Scope        Scope
+	Operation    string
+	RolledBackTo int
+	Before       map[string]string
+	After        map[string]string
+}
+
+func historyScope(appName string) string {
+	if appName == "" || appName == "--global" {
+		return "--global"
+	}
+	return appName
+}
+
+// scope returns the scope of the environment the snapshot belongs to
+func (s ConfigSnapshot) scope() Scope {
+	return Scope{Phase: s.Phase, ProcessType: s.ProcessType}
+}
+
+// getHistoryDir returns the directory holding the config snapshots of an app or the global environment
+func getHistoryDir(appName string, scope Scope) string {
+	if historyScope(appName) == "--global" {
+		return filepath.Join(filepath.Dir(getGlobalFile()), "history")
+	}
+
+	scopeFile, _ := getScopeFile(appName, scope)
+	return filepath.Join(filepath.Dir(scopeFile), "history")
+}
+
+func snapshotRecordPath(appName string, scope Scope, version int) string {
+	return filepath.Join(getHistoryDir(appName, scope), fmt.Sprintf("%d.json", version))
+}
+
+func snapshotEnvPath(appName string, scope Scope, version int) string {
+	return filepath.Join(getHistoryDir(appName, scope), fmt.Sprintf("%d.env", version))
+}
+
+// getHistoryKeyFile returns the path to the host key config history hashes are keyed with
+func getHistoryKeyFile() string {
+	return filepath.Join(common.MustGetEnv("DOKKU_LIB_ROOT"), "config", ".history-key")
+}
+
+// historyKey returns the host key config history hashes are keyed with
+func historyKey() ([]byte, error) {
+	return common.ValueHashKey(getHistoryKeyFile())
+}
+
+func copyEnvMap(envMap map[string]string) map[string]string {
+	copied := make(map[string]string, len(envMap))
+	for k, v := range envMap {
+		copied[k] = v
+	}
+	return copied
+}
+
+// diffEnv returns the changes that turn before into after, sorted by key
+func diffEnv(before map[string]string, after map[string]string, key []byte) []ConfigChange {
+	changes := []ConfigChange{}
+	for k, v := range after {
+		previous, ok := before[k]
+		if !ok {
+			changes = append(changes, ConfigChange{Key: k, Action: "added", Hash: common.HashValue(key, v)})
+		} else if previous != v {
+			changes = append(changes, ConfigChange{Key: k, Action: "changed", Hash: common.HashValue(key, v)})
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, ConfigChange{Key: k, Action: "removed"})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// FetchConfigHistory returns every recorded snapshot for a scope of an app or
+// the global environment, sorted oldest-first
+func FetchConfigHistory(appName string, scope Scope) ([]ConfigSnapshot, error) {
+	entries, err := os.ReadDir(getHistoryDir(appName, scope))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ConfigSnapshot{}, nil
+		}
+		return nil, fmt.Errorf("Unable to list config history: %s", err.Error())
+	}
+
+	snapshots := []ConfigSnapshot{}
+	for _, entry := range entries {
+		version, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		snapshot, err := readConfigSnapshot(appName, scope, version)
+		if err != nil {
+			common.LogWarn(fmt.Sprintf("Skipping unreadable config snapshot %s: %s", entry.Name(), err.Error()))
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Version < snapshots[j].Version
+	})
+	return snapshots, nil
+}
+
+func readConfigSnapshot(appName string, scope Scope, version int) (ConfigSnapshot, error) {
+	var snapshot ConfigSnapshot
+	b, err := os.ReadFile(snapshotRecordPath(appName, scope, version))
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = json.Unmarshal(b, &snapshot)
+	return snapshot, err
+}
+
+// loadConfigSnapshotEnv loads the environment as it stood at the given version
+func loadConfigSnapshotEnv(appName string, scope Scope, version int) (*Env, error) {
+	filename := snapshotEnvPath(appName, scope, version)
+	if !common.FileExists(filename) {
+		if scope.IsZero() {
+			return nil, fmt.Errorf("Config version %d does not exist", version)
+		}
+		return nil, fmt.Errorf("Config version %d does not exist for the %s", version, scope)
+	}
+	return loadFromFile(fmt.Sprintf("%s version %d", historyScope(appName), version), filename)
+}
+
+// historyEnvs loads every snapshot environment of an app or the global
+// environment, so that they can be re-encrypted alongside the current one
+func historyEnvs(appName string, scope Scope) ([]*Env, error) {
+	snapshots, err := FetchConfigHistory(appName, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	envs := []*Env{}
+	for _, snapshot := range snapshots {
+		env, err := loadConfigSnapshotEnv(appName, scope, snapshot.Version)
+		if err != nil {
+			return nil, err
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func writeConfigSnapshot(snapshot ConfigSnapshot, envMap map[string]string) error {
+	historyDir := getHistoryDir(snapshot.App, snapshot.scope())
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		return err
+	}
+	if err := common.SetPermissions(common.SetPermissionInput{
+		Filename: historyDir,
+		Mode:     os.FileMode(0755),
+	}); err != nil {
+		return err
+	}
+
+	envFile := snapshotEnvPath(snapshot.App, snapshot.scope(), snapshot.Version)
+	if err := writeEnvFile(envMap, envFile); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	recordFile := snapshotRecordPath(snapshot.App, snapshot.scope(), snapshot.Version)
+	if err := os.WriteFile(recordFile, b, 0600); err != nil {
+		return err
+	}
+
+	for _, filename := range []string{envFile, recordFile} {
+		if err := common.SetPermissions(common.SetPermissionInput{
+			Filename: filename,
+			Mode:     os.FileMode(0600),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordConfigHistory stores a snapshot of a config mutation. The first
+// mutation of an environment that was set before history was recorded also
+// stores the prior environment as an initial version, so that it can be
+// restored.
+func recordConfigHistory(input recordConfigHistoryInput) error {
+	key, err := historyKey()
+	if err != nil {
+		return err
+	}
+
+	changes := diffEnv(input.Before, input.After, key)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	appName := historyScope(input.AppName)
+	snapshots, err := FetchConfigHistory(appName, input.Scope)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	version := 1
+	if len(snapshots) > 0 {
+		version = snapshots[len(snapshots)-1].Version + 1
+	} else if len(input.Before) > 0 {
+		initial := ConfigSnapshot{
+			Version:     version,
+			App:         appName,
+			Phase:       input.Scope.Phase,
+			ProcessType: input.Scope.ProcessType,
+			Operation:   "initial",
+			Changes:     diffEnv(map[string]string{}, input.Before, key),
+			User:        "",
+			CreatedAt:   now,
+		}
+		if err := writeConfigSnapshot(initial, input.Before); err != nil {
+			return err
+		}
+		snapshots = append(snapshots, initial)
+		version++
+	}
+
+	snapshot := ConfigSnapshot{
+		Version:      version,
+		App:          appName,
+		Phase:        input.Scope.Phase,
+		ProcessType:  input.Scope.ProcessType,
+		Operation:    input.Operation,
+		RolledBackTo: input.RolledBackTo,
+		Changes:      changes,
+		User:         common.ResolveUser(),
+		CreatedAt:    now,
+	}
+	if err := writeConfigSnapshot(snapshot, input.After); err != nil {
+		return err
+	}
+	snapshots = append(snapshots, snapshot)
+
+	if len(snapshots) > configHistoryRetention {
+		for _, pruned := range snapshots[:len(snapshots)-configHistoryRetention] {
+			os.Remove(snapshotEnvPath(appName, input.Scope, pruned.Version))
+			os.Remove(snapshotRecordPath(appName, input.Scope, pruned.Version))
+		}
+	}
+	return nil
+}
+
+// recordConfigHistoryOrWarn records a snapshot, warning rather than failing
+// as the mutation itself has already been written
+func recordConfigHistoryOrWarn(input recordConfigHistoryInput) {
+	if err := recordConfigHistory(input); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to record config history: %s", err.Error()))
+	}
+}
+
+// Rollback restores a scope of the environment of an app or the global
+// environment to the given version. If restart is true the app is restarted.
+func Rollback(appName string, scope Scope, version int, restart bool) error {
+	global := appName == "" || appName == "--global"
+	setKeys, unsetKeys, err := rollback(appName, scope, version)
+	if err != nil {
+		return err
+	}
+	if len(setKeys) == 0 && len(unsetKeys) == 0 {
+		return nil
+	}
+
+	if len(setKeys) > 0 {
+		triggerUpdate(appName, "set", setKeys)
+	}
+	if len(unsetKeys) > 0 {
+		triggerUpdate(appName, "unset", unsetKeys)
+	}
+	if !global && restart && shouldRestartForScope(appName, scope) {
+		triggerRestart(appName)
+	}
+	return nil
+}
+
+// rollback writes a snapshot back to a scope of the environment under the
+// app's config lock, returning the keys that were set and unset
+func rollback(appName string, scope Scope, version int) ([]string, []string, error) {
+	unlock, err := lockConfig(appName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	env, err := loadEnvForUpdate(appName, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := loadConfigSnapshotEnv(appName, scope, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := historyKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := copyEnvMap(env.Map())
+	changes := diffEnv(before, target.Map(), key)
+	if len(changes) == 0 {
+		common.LogInfo1Quiet(fmt.Sprintf("Config already matches version %d", version))
+		return nil, nil, nil
+	}
+
+	common.LogInfo1Quiet(fmt.Sprintf("Rolling back config to version %d", version))
+	setKeys := []string{}
+	unsetKeys := []string{}
+	for _, change := range changes {
+		common.LogVerboseQuiet(fmt.Sprintf("%s %s", strings.ToUpper(change.Action[:1])+change.Action[1:], change.Key))
+		if change.Action == "removed" {
+			unsetKeys = append(unsetKeys, change.Key)
+		} else {
+			setKeys = append(setKeys, change.Key)
+		}
+	}
+
+	env.Clear()
+	for k, v := range target.Map() {
+		env.Set(k, v)
+	}
+	if err := env.Write(); err != nil {
+		return nil, nil, fmt.Errorf("Unable to write environment: %s", err.Error())
+	}
+	common.SetPermissions(common.SetPermissionInput{
+		Filename: env.Filename(),
+		Mode:     os.FileMode(0600),
+	})
+
+	recordConfigHistoryOrWarn(recordConfigHistoryInput{
+		AppName:      appName,
+		Scope:        scope,
+		Operation:    "rollback",
+		RolledBackTo: version,
+		Before:       before,
+		After:        env.Map(),
+	})
+	return setKeys, unsetKeys, nil
+}
+
+// rewriteConfigHistoryApp updates the app name recorded in every snapshot of
+// a scope, such as after the snapshots were moved to a renamed app
+func rewriteConfigHistoryApp(appName string, scope Scope) error {
+	snapshots, err := FetchConfigHistory(appName, scope)
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		snapshot.App = appName
+		b, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(snapshotRecordPath(appName, scope, snapshot.Version), b, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
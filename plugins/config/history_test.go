@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/joho/godotenv"
+)
+
+func setupHistoryApp(t *testing.T, appName string) {
+	t.Helper()
+	dokkuRoot, _ := setupMigrateEnv(t)
+	t.Setenv("SSH_NAME", "admin")
+	if err := os.MkdirAll(filepath.Join(dokkuRoot, appName), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := setupAppConfigDir(appName); err != nil {
+		t.Fatalf("setupAppConfigDir: %v", err)
+	}
+}
+
+func TestDiffEnv(t *testing.T) {
+	key := []byte("history-key")
+	changes := diffEnv(
+		map[string]string{"KEEP": "same", "CHANGE": "old", "REMOVE": "gone"},
+		map[string]string{"KEEP": "same", "CHANGE": "new", "ADD": "fresh"},
+		key,
+	)
+
+	want := []ConfigChange{
+		{Key: "ADD", Action: "added", Hash: common.HashValue(key, "fresh")},
+		{Key: "CHANGE", Action: "changed", Hash: common.HashValue(key, "new")},
+		{Key: "REMOVE", Action: "removed"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("diffEnv = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("diffEnv[%d] = %v, want %v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestHashValueIsKeyed(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	key, err := historyKey()
+	if err != nil {
+		t.Fatalf("historyKey: %v", err)
+	}
+	again, err := historyKey()
+	if err != nil {
+		t.Fatalf("historyKey: %v", err)
+	}
+	if !bytes.Equal(key, again) {
+		t.Error("expected the history key to be generated once and reused")
+	}
+
+	other := []byte("another-history-key")
+	if common.HashValue(key, "secret") == common.HashValue(other, "secret") {
+		t.Error("expected hashes to depend on the history key")
+	}
+
+	unkeyed := sha256.Sum256([]byte("secret"))
+	if common.HashValue(key, "secret") == hex.EncodeToString(unkeyed[:]) {
+		t.Error("expected the hash to not be a plain sha256 of the value")
+	}
+
+	info, err := os.Stat(getHistoryKeyFile())
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("history key mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestConfigHistoryRecordsMutations(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	if err := SetMany("alpha", map[string]string{"A": "1", "B": "2"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := SetMany("alpha", map[string]string{"A": "1"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := UnsetMany("alpha", []string{"B"}, false); err != nil {
+		t.Fatalf("UnsetMany: %v", err)
+	}
+	if err := UnsetAll("alpha", false); err != nil {
+		t.Fatalf("UnsetAll: %v", err)
+	}
+
+	snapshots, err := FetchConfigHistory("alpha", Scope{})
+	if err != nil {
+		t.Fatalf("FetchConfigHistory: %v", err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("expected 3 snapshots as a no-op set is not recorded, got %d", len(snapshots))
+	}
+
+	for i, operation := range []string{"set", "unset", "clear"} {
+		if snapshots[i].Version != i+1 || snapshots[i].Operation != operation {
+			t.Errorf("snapshot %d = version %d %s, want version %d %s", i, snapshots[i].Version, snapshots[i].Operation, i+1, operation)
+		}
+		if snapshots[i].User != "admin" {
+			t.Errorf("snapshot %d user = %q, want admin", i, snapshots[i].User)
+		}
+	}
+	if got := snapshots[1].Changes; len(got) != 1 || got[0].Key != "B" || got[0].Action != "removed" {
+		t.Errorf("unset changes = %v", got)
+	}
+
+	env, err := loadConfigSnapshotEnv("alpha", Scope{}, 2)
+	if err != nil {
+		t.Fatalf("loadConfigSnapshotEnv: %v", err)
+	}
+	if got := env.Map(); len(got) != 1 || got["A"] != "1" {
+		t.Errorf("version 2 env = %v", got)
+	}
+}
+
+func TestConfigHistoryRecordsInitialVersion(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	appFile, _ := getAppFile("alpha")
+	if err := godotenv.Write(map[string]string{"EXISTING": "value"}, appFile); err != nil {
+		t.Fatalf("godotenv.Write: %v", err)
+	}
+	if err := SetMany("alpha", map[string]string{"NEW": "value"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+
+	snapshots, err := FetchConfigHistory("alpha", Scope{})
+	if err != nil {
+		t.Fatalf("FetchConfigHistory: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Operation != "initial" || snapshots[1].Operation != "set" {
+		t.Fatalf("expected an initial and a set snapshot, got %v", snapshots)
+	}
+
+	if err := Rollback("alpha", Scope{}, 1, false); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	expectEnvValue(t, "alpha", "EXISTING", "value")
+	if _, ok := Get("alpha", "NEW"); ok {
+		t.Error("expected NEW to be removed by the rollback")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	if err := SetMany("alpha", map[string]string{"A": "1", "B": "2"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := SetMany("alpha", map[string]string{"A": "changed", "C": "3"}, true, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+
+	if err := Rollback("alpha", Scope{}, 1, false); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	expectEnvValue(t, "alpha", "A", "1")
+	expectEnvValue(t, "alpha", "B", "2")
+	if _, ok := Get("alpha", "C"); ok {
+		t.Error("expected C to be removed by the rollback")
+	}
+
+	snapshots, err := FetchConfigHistory("alpha", Scope{})
+	if err != nil {
+		t.Fatalf("FetchConfigHistory: %v", err)
+	}
+	last := snapshots[len(snapshots)-1]
+	if last.Version != 3 || last.Operation != "rollback" || last.RolledBackTo != 1 {
+		t.Errorf("expected a rollback snapshot at version 3, got %v", last)
+	}
+
+	if err := Rollback("alpha", Scope{}, 99, false); err == nil {
+		t.Error("expected rolling back to a missing version to fail")
+	}
+}
+
+func TestConfigHistoryRetention(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	for i := 0; i < configHistoryRetention+5; i++ {
+		if err := SetMany("alpha", map[string]string{"COUNTER": string(rune('a'+i%26)) + string(rune('0'+i/26))}, false, false); err != nil {
+			t.Fatalf("SetMany: %v", err)
+		}
+	}
+
+	snapshots, err := FetchConfigHistory("alpha", Scope{})
+	if err != nil {
+		t.Fatalf("FetchConfigHistory: %v", err)
+	}
+	if len(snapshots) != configHistoryRetention {
+		t.Fatalf("expected %d snapshots, got %d", configHistoryRetention, len(snapshots))
+	}
+	if snapshots[0].Version != 6 {
+		t.Errorf("expected the oldest kept snapshot to be version 6, got %d", snapshots[0].Version)
+	}
+	if _, err := os.Stat(snapshotEnvPath("alpha", Scope{}, 5)); !os.IsNotExist(err) {
+		t.Errorf("expected the env of a pruned snapshot to be removed, got err=%v", err)
+	}
+}
+
+func TestConfigHistoryIsEncrypted(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	if err := SetMany("alpha", map[string]string{"SECRET": "hunter2"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	if got := readRawEnv(t, snapshotEnvPath("alpha", Scope{}, 1))["SECRET"]; !isEncryptedValue(got) {
+		t.Errorf("expected the snapshot to be encrypted by the rotation, got %q", got)
+	}
+
+	if err := RotateEncryptionKey(); err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	env, err := loadConfigSnapshotEnv("alpha", Scope{}, 1)
+	if err != nil {
+		t.Fatalf("loadConfigSnapshotEnv: %v", err)
+	}
+	if got := env.GetDefault("SECRET", ""); got != "hunter2" {
+		t.Errorf("snapshot SECRET = %q after rotation", got)
+	}
+}
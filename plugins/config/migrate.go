@@ -24,6 +24,9 @@ const envMigratedProperty = "env-migrated"
 // install trigger would have relocated these files, and would otherwise read an
 // empty environment and silently migrate nothing. Nothing here may assume the
 // config install trigger has already run.
+//
+// When encryption is enabled, any environment still holding plaintext values -
+// such as one written before the host key was put in place - is encrypted last.
 func MigrateEnvFiles() error {
 	if err := common.PropertySetup("config"); err != nil {
 		return fmt.Errorf("Unable to setup config properties: %s", err.Error())
@@ -33,17 +36,18 @@ func MigrateEnvFiles() error {
 		return fmt.Errorf("Unable to migrate global environment: %s", err.Error())
 	}
 
-	apps, err := common.UnfilteredDokkuApps()
-	if err != nil {
-		return nil
-	}
-
+	// an error here only means no apps exist yet, which leaves nothing to drain
+	apps, _ := common.UnfilteredDokkuApps()
 	for _, appName := range apps {
 		if err := migrateAppEnv(appName); err != nil {
 			return fmt.Errorf("Unable to migrate environment for %s: %s", appName, err.Error())
 		}
 	}
 
+	if err := encryptPlaintextEnvs(); err != nil {
+		return fmt.Errorf("Unable to encrypt environment: %s", err.Error())
+	}
+
 	return nil
 }
 
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// Phases are the phases an app environment can be scoped to
+var Phases = []string{"build", "deploy", "run"}
+
+// Scope narrows an app environment to a single phase or process type. The
+// zero Scope is the app environment itself.
+type Scope struct {
+	// Phase is one of build, deploy or run
+	Phase string
+
+	// ProcessType is a process type from the app's Procfile
+	ProcessType string
+}
+
+// IsZero returns whether the scope is the app environment itself
+func (s Scope) IsZero() bool {
+	return s.Phase == "" && s.ProcessType == ""
+}
+
+// String returns a human readable name for the scope
+func (s Scope) String() string {
+	parts := []string{}
+	if s.Phase != "" {
+		parts = append(parts, fmt.Sprintf("%s phase", s.Phase))
+	}
+	if s.ProcessType != "" {
+		parts = append(parts, fmt.Sprintf("%s process", s.ProcessType))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validate checks that the scope selects a known phase and a valid process type
+func (s Scope) validate() error {
+	if s.Phase != "" {
+		valid := false
+		for _, phase := range Phases {
+			if s.Phase == phase {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("Invalid phase '%s', expected one of: %s", s.Phase, strings.Join(Phases, ", "))
+		}
+	}
+
+	if s.ProcessType != "" {
+		r := regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_-]*$")
+		if !r.MatchString(s.ProcessType) {
+			return fmt.Errorf("Invalid process type '%s'", s.ProcessType)
+		}
+	}
+	return nil
+}
+
+// validateStorable checks that the scope selects a single layer that values
+// can be stored in
+func (s Scope) validateStorable(appName string) error {
+	if s.IsZero() {
+		return nil
+	}
+	if appName == "" || appName == "--global" {
+		return errors.New("The global environment cannot be scoped to a phase or process type")
+	}
+	if s.Phase != "" && s.ProcessType != "" {
+		return errors.New("Only one of --phase and --process can be given")
+	}
+	return s.validate()
+}
+
+// layers returns the stored scopes that make up the scope, least specific first
+func (s Scope) layers() []Scope {
+	layers := []Scope{}
+	if s.Phase != "" {
+		layers = append(layers, Scope{Phase: s.Phase})
+	}
+	if s.ProcessType != "" {
+		layers = append(layers, Scope{ProcessType: s.ProcessType})
+	}
+	return layers
+}
+
+// getScopeFile returns the path to the file holding the env vars of a single scope
+func getScopeFile(appName string, scope Scope) (string, error) {
+	appFile, err := getAppFile(appName)
+	if err != nil || scope.IsZero() {
+		return appFile, err
+	}
+
+	if scope.Phase != "" {
+		return filepath.Join(filepath.Dir(appFile), "phase", scope.Phase, "ENV"), nil
+	}
+	return filepath.Join(filepath.Dir(appFile), "process", scope.ProcessType, "ENV"), nil
+}
+
+// setupScopeDir creates the directories holding the env vars of a scope
+func setupScopeDir(filename string) error {
+	for _, dir := range []string{filepath.Dir(filepath.Dir(filename)), filepath.Dir(filename)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		if err := common.SetPermissions(common.SetPermissionInput{
+			Filename: dir,
+			Mode:     os.FileMode(0755),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadScopedAppEnv loads the values stored in a single scope of an app environment
+func LoadScopedAppEnv(appName string, scope Scope) (*Env, error) {
+	if err := scope.validateStorable(appName); err != nil {
+		return nil, err
+	}
+	if scope.IsZero() {
+		return loadAppOrGlobalEnv(appName)
+	}
+
+	filename, err := getScopeFile(appName, scope)
+	if err != nil {
+		return nil, err
+	}
+	return loadFromFile(fmt.Sprintf("%s (%s)", appName, scope), filename)
+}
+
+// LoadMergedScopedAppEnv loads an app environment merged with the global
+// environment, with the values of the scope's phase and then its process type
+// layered on top
+func LoadMergedScopedAppEnv(appName string, scope Scope) (*Env, error) {
+	if err := scope.validate(); err != nil {
+		return nil, err
+	}
+
+	env, err := LoadMergedAppEnv(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range scope.layers() {
+		scoped, err := LoadScopedAppEnv(appName, layer)
+		if err != nil {
+			return nil, err
+		}
+		env.Merge(scoped)
+	}
+	return env, nil
+}
+
+// ListAppScopes returns every scope with a stored environment for an app
+func ListAppScopes(appName string) ([]Scope, error) {
+	appFile, err := getAppFile(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []Scope{}
+	for _, kind := range []string{"phase", "process"} {
+		entries, err := os.ReadDir(filepath.Join(filepath.Dir(appFile), kind))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			scope := Scope{ProcessType: entry.Name()}
+			if kind == "phase" {
+				scope = Scope{Phase: entry.Name()}
+			}
+			if scope.validate() == nil {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return scopes, nil
+}
+
+// copyAppScopes copies the scoped environments of an app to another app
+func copyAppScopes(oldAppName string, newAppName string) error {
+	scopes, err := ListAppScopes(oldAppName)
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range scopes {
+		oldEnv, err := LoadScopedAppEnv(oldAppName, scope)
+		if err != nil {
+			return err
+		}
+
+		newEnv, err := loadEnvForUpdate(newAppName, scope)
+		if err != nil {
+			return err
+		}
+
+		newEnv.Merge(oldEnv)
+		if err := newEnv.Write(); err != nil {
+			return err
+		}
+		if err := common.SetPermissions(common.SetPermissionInput{
+			Filename: newEnv.Filename(),
+			Mode:     os.FileMode(0600),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveAppScopes moves the scoped environments and config history of an app to
+// a renamed app
+func moveAppScopes(oldAppName string, newAppName string) error {
+	oldAppFile, err := getAppFile(oldAppName)
+	if err != nil {
+		return err
+	}
+	newAppFile, err := getAppFile(newAppName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"history", "phase", "process"} {
+		oldDir := filepath.Join(filepath.Dir(oldAppFile), name)
+		if !common.DirectoryExists(oldDir) {
+			continue
+		}
+
+		newDir := filepath.Join(filepath.Dir(newAppFile), name)
+		if err := os.RemoveAll(newDir); err != nil {
+			return err
+		}
+		if err := os.Rename(oldDir, newDir); err != nil {
+			return err
+		}
+	}
+
+	scopes, err := ListAppScopes(newAppName)
+	if err != nil {
+		return err
+	}
+	for _, scope := range append([]Scope{{}}, scopes...) {
+		if err := rewriteConfigHistoryApp(newAppName, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScopeValidateStorable(t *testing.T) {
+	tests := []struct {
+		appName string
+		scope   Scope
+		valid   bool
+	}{
+		{"alpha", Scope{}, true},
+		{"alpha", Scope{Phase: "build"}, true},
+		{"alpha", Scope{ProcessType: "web"}, true},
+		{"alpha", Scope{ProcessType: "worker_2"}, true},
+		{"alpha", Scope{Phase: "release"}, false},
+		{"alpha", Scope{ProcessType: "../web"}, false},
+		{"alpha", Scope{Phase: "deploy", ProcessType: "web"}, false},
+		{"--global", Scope{}, true},
+		{"--global", Scope{Phase: "build"}, false},
+		{"", Scope{ProcessType: "web"}, false},
+	}
+
+	for _, test := range tests {
+		err := test.scope.validateStorable(test.appName)
+		if test.valid && err != nil {
+			t.Errorf("validateStorable(%q, %v) = %v, want nil", test.appName, test.scope, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("validateStorable(%q, %v) = nil, want an error", test.appName, test.scope)
+		}
+	}
+}
+
+func TestLoadMergedScopedAppEnv(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+	if err := os.MkdirAll(filepath.Dir(getGlobalFile()), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	if err := SetMany("--global", map[string]string{"GLOBAL": "global", "QUEUE": "global"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := SetMany("alpha", map[string]string{"QUEUE": "app", "PHASE_ONLY": "app"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := SetManyInScope("alpha", Scope{Phase: "deploy"}, map[string]string{"QUEUE": "deploy", "PHASE_ONLY": "deploy"}, false, false); err != nil {
+		t.Fatalf("SetManyInScope: %v", err)
+	}
+	if err := SetManyInScope("alpha", Scope{ProcessType: "worker"}, map[string]string{"QUEUE": "worker"}, false, false); err != nil {
+		t.Fatalf("SetManyInScope: %v", err)
+	}
+	if err := SetManyInScope("alpha", Scope{Phase: "build"}, map[string]string{"NPM_TOKEN": "secret"}, false, false); err != nil {
+		t.Fatalf("SetManyInScope: %v", err)
+	}
+
+	tests := []struct {
+		scope Scope
+		want  map[string]string
+	}{
+		{Scope{}, map[string]string{"GLOBAL": "global", "QUEUE": "app", "PHASE_ONLY": "app"}},
+		{Scope{Phase: "deploy"}, map[string]string{"GLOBAL": "global", "QUEUE": "deploy", "PHASE_ONLY": "deploy"}},
+		{Scope{ProcessType: "web"}, map[string]string{"GLOBAL": "global", "QUEUE": "app", "PHASE_ONLY": "app"}},
+		{Scope{Phase: "deploy", ProcessType: "worker"}, map[string]string{"GLOBAL": "global", "QUEUE": "worker", "PHASE_ONLY": "deploy"}},
+		{Scope{Phase: "build"}, map[string]string{"GLOBAL": "global", "QUEUE": "app", "PHASE_ONLY": "app", "NPM_TOKEN": "secret"}},
+	}
+
+	for _, test := range tests {
+		env, err := LoadMergedScopedAppEnv("alpha", test.scope)
+		if err != nil {
+			t.Fatalf("LoadMergedScopedAppEnv(%v): %v", test.scope, err)
+		}
+		got := env.Map()
+		if len(got) != len(test.want) {
+			t.Errorf("LoadMergedScopedAppEnv(%v) = %v, want %v", test.scope, got, test.want)
+			continue
+		}
+		for k, v := range test.want {
+			if got[k] != v {
+				t.Errorf("LoadMergedScopedAppEnv(%v)[%s] = %q, want %q", test.scope, k, got[k], v)
+			}
+		}
+	}
+
+	scoped, err := LoadScopedAppEnv("alpha", Scope{ProcessType: "worker"})
+	if err != nil {
+		t.Fatalf("LoadScopedAppEnv: %v", err)
+	}
+	if got := scoped.Map(); len(got) != 1 || got["QUEUE"] != "worker" {
+		t.Errorf("worker process env = %v", got)
+	}
+}
+
+func TestScopedMutationsAreIsolated(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	if err := SetMany("alpha", map[string]string{"QUEUE": "app"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := SetManyInScope("alpha", Scope{ProcessType: "worker"}, map[string]string{"QUEUE": "worker"}, false, false); err != nil {
+		t.Fatalf("SetManyInScope: %v", err)
+	}
+	if err := UnsetAllInScope("alpha", Scope{ProcessType: "worker"}, false); err != nil {
+		t.Fatalf("UnsetAllInScope: %v", err)
+	}
+	expectEnvValue(t, "alpha", "QUEUE", "app")
+
+	if err := SetManyInScope("--global", Scope{Phase: "build"}, map[string]string{"KEY": "value"}, false, false); err == nil {
+		t.Error("expected scoping the global environment to fail")
+	}
+
+	snapshots, err := FetchConfigHistory("alpha", Scope{ProcessType: "worker"})
+	if err != nil {
+		t.Fatalf("FetchConfigHistory: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ProcessType != "worker" || snapshots[1].Operation != "clear" {
+		t.Fatalf("expected a set and a clear snapshot for the worker process, got %v", snapshots)
+	}
+
+	if err := Rollback("alpha", Scope{ProcessType: "worker"}, 1, false); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	scoped, err := LoadScopedAppEnv("alpha", Scope{ProcessType: "worker"})
+	if err != nil {
+		t.Fatalf("LoadScopedAppEnv: %v", err)
+	}
+	if got := scoped.GetDefault("QUEUE", ""); got != "worker" {
+		t.Errorf("worker QUEUE = %q after rollback, want worker", got)
+	}
+
+	appSnapshots, err := FetchConfigHistory("alpha", Scope{})
+	if err != nil {
+		t.Fatalf("FetchConfigHistory: %v", err)
+	}
+	if len(appSnapshots) != 1 {
+		t.Errorf("expected scoped changes to be kept out of the app history, got %v", appSnapshots)
+	}
+}
+
+func TestListAppScopes(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	if err := SetManyInScope("alpha", Scope{Phase: "build"}, map[string]string{"A": "1"}, false, false); err != nil {
+		t.Fatalf("SetManyInScope: %v", err)
+	}
+	if err := SetManyInScope("alpha", Scope{ProcessType: "web"}, map[string]string{"B": "2"}, false, false); err != nil {
+		t.Fatalf("SetManyInScope: %v", err)
+	}
+
+	appFile, _ := getAppFile("alpha")
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(appFile), "phase", "bogus"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	scopes, err := ListAppScopes("alpha")
+	if err != nil {
+		t.Fatalf("ListAppScopes: %v", err)
+	}
+	if len(scopes) != 2 || scopes[0] != (Scope{Phase: "build"}) || scopes[1] != (Scope{ProcessType: "web"}) {
+		t.Errorf("ListAppScopes = %v", scopes)
+	}
+}
+
+func TestCopyAndMoveAppScopes(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+	dokkuRoot := os.Getenv("DOKKU_ROOT")
+	for _, appName := range []string{"beta", "gamma"} {
+		if err := os.MkdirAll(filepath.Join(dokkuRoot, appName), 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := setupAppConfigDir(appName); err != nil {
+			t.Fatalf("setupAppConfigDir: %v", err)
+		}
+	}
+
+	if err := SetManyInScope("alpha", Scope{ProcessType: "worker"}, map[string]string{"QUEUE": "jobs"}, false, false); err != nil {
+		t.Fatalf("SetManyInScope: %v", err)
+	}
+
+	if err := copyAppScopes("alpha", "beta"); err != nil {
+		t.Fatalf("copyAppScopes: %v", err)
+	}
+	if err := moveAppScopes("alpha", "gamma"); err != nil {
+		t.Fatalf("moveAppScopes: %v", err)
+	}
+
+	for _, appName := range []string{"beta", "gamma"} {
+		scoped, err := LoadScopedAppEnv(appName, Scope{ProcessType: "worker"})
+		if err != nil {
+			t.Fatalf("LoadScopedAppEnv(%s): %v", appName, err)
+		}
+		if got := scoped.GetDefault("QUEUE", ""); got != "jobs" {
+			t.Errorf("%s worker QUEUE = %q, want jobs", appName, got)
+		}
+	}
+
+	scopes, err := ListAppScopes("alpha")
+	if err != nil {
+		t.Fatalf("ListAppScopes: %v", err)
+	}
+	if len(scopes) != 0 {
+		t.Errorf("expected the renamed app to have no scopes left, got %v", scopes)
+	}
+
+	snapshots, err := FetchConfigHistory("gamma", Scope{ProcessType: "worker"})
+	if err != nil {
+		t.Fatalf("FetchConfigHistory: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].App != "gamma" {
+		t.Errorf("expected the worker history to move with the app, got %v", snapshots)
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// secretRefEnvPrefixProperty limits the env resolver to environment
+	// variables starting with a prefix
+	secretRefEnvPrefixProperty = "secretref-env-prefix"
+
+	// secretRefFileRootProperty is the directory the file resolver may read
+	// secrets from. The file resolver is disabled while it is unset.
+	secretRefFileRootProperty = "secretref-file-root"
+
+	// defaultSecretRefEnvPrefix is the prefix the env resolver is limited to
+	// when no prefix has been set
+	defaultSecretRefEnvPrefix = "DOKKU_SECRET_"
+)
+
+// secretRefPrefix marks a config value that references a secret held outside of dokku
+const secretRefPrefix = "secretref://"
+
+// secretRef is a parsed secret reference of the form secretref://<resolver>/<path>
+type secretRef struct {
+	// Resolver is the name of the resolver the secret is fetched with
+	Resolver string
+
+	// Path identifies the secret to the resolver
+	Path string
+}
+
+// IsSecretRef returns whether a config value is a secret reference
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, secretRefPrefix)
+}
+
+func parseSecretRef(value string) (secretRef, error) {
+	resolver, path, _ := strings.Cut(strings.TrimPrefix(value, secretRefPrefix), "/")
+	ref := secretRef{Resolver: resolver, Path: path}
+
+	r := regexp.MustCompile("^[a-z][a-z0-9-]*$")
+	if !r.MatchString(ref.Resolver) {
+		return ref, fmt.Errorf("Invalid secret reference '%s': expected %s<resolver>/<path>", value, secretRefPrefix)
+	}
+	if ref.Path == "" {
+		return ref, fmt.Errorf("Invalid secret reference '%s': no path specified", value)
+	}
+	return ref, nil
+}
+
+// resolveSecretRef fetches the secret a reference points to. The file and env
+// resolvers are built in, while any other resolver is handed to the
+// config-resolve-secretref trigger.
+func resolveSecretRef(appName string, ref secretRef) (string, error) {
+	switch ref.Resolver {
+	case "file":
+		filename, err := getSecretRefFilename(ref.Path)
+		if err != nil {
+			return "", err
+		}
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case "env":
+		prefix := common.PropertyGetDefault("config", "--global", secretRefEnvPrefixProperty, defaultSecretRefEnvPrefix)
+		if !strings.HasPrefix(ref.Path, prefix) {
+			return "", fmt.Errorf("only environment variables starting with %s can be referenced", prefix)
+		}
+		value, ok := os.LookupEnv(ref.Path)
+		if !ok {
+			return "", fmt.Errorf("%s is not set in the environment", ref.Path)
+		}
+		return value, nil
+	}
+
+	results, err := common.CallPlugnTrigger(common.PlugnTriggerInput{
+		Trigger: "config-resolve-secretref",
+		Args:    []string{appName, ref.Resolver, ref.Path},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	value := results.StdoutContents()
+	if value == "" {
+		return "", fmt.Errorf("no plugin resolved the %s secret reference", ref.Resolver)
+	}
+	return value, nil
+}
+
+// getSecretRefFilename returns the file a file secret reference points to. The
+// file must be within the secretref-file-root directory, and may never be
+// within dokku's own directories, where config and keys are stored.
+func getSecretRefFilename(path string) (string, error) {
+	root := common.PropertyGet("config", "--global", secretRefFileRootProperty)
+	if root == "" {
+		return "", fmt.Errorf("the file resolver is disabled, set the %s property via config:set-property --global to enable it", secretRefFileRootProperty)
+	}
+
+	for _, part := range strings.Split(path, "/") {
+		if part == ".." {
+			return "", errors.New("file references may not contain '..'")
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(filepath.Clean(root))
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %s", secretRefFileRootProperty, err.Error())
+	}
+
+	filename := filepath.Join("/", path)
+	resolved, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		return "", err
+	}
+
+	forbidden := []string{}
+	for _, env := range []string{"DOKKU_LIB_ROOT", "DOKKU_ROOT"} {
+		dir := os.Getenv(env)
+		if dir == "" {
+			continue
+		}
+		forbidden = append(forbidden, filepath.Clean(dir))
+		if resolvedDir, err := filepath.EvalSymlinks(dir); err == nil {
+			forbidden = append(forbidden, resolvedDir)
+		}
+	}
+
+	for _, f := range []string{filename, resolved} {
+		if !isWithinDirectory(f, root) {
+			return "", fmt.Errorf("file references must be within %s", root)
+		}
+		for _, dir := range forbidden {
+			if isWithinDirectory(f, dir) {
+				return "", fmt.Errorf("file references may not be within %s", dir)
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// isWithinDirectory returns whether a cleaned path is a directory or within it
+func isWithinDirectory(path string, dir string) bool {
+	if dir == "/" {
+		return true
+	}
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// ResolveSecretRefs returns a copy of envMap with every secret reference
+// replaced by the secret it points to. It should only be called where the
+// environment is handed to a container, so that secrets are never written to
+// dokku's own config files.
+func ResolveSecretRefs(appName string, envMap map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(envMap))
+	for k, v := range envMap {
+		if !IsSecretRef(v) {
+			resolved[k] = v
+			continue
+		}
+
+		ref, err := parseSecretRef(v)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := resolveSecretRef(appName, ref)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to resolve secret reference for %s: %s", k, err.Error())
+		}
+		resolved[k] = value
+	}
+	return resolved, nil
+}
+
+// resolveSecretRefs returns a copy of the Env with every secret reference resolved
+func (e *Env) resolveSecretRefs() (*Env, error) {
+	appName := e.name
+	if appName == "<global>" {
+		appName = "--global"
+	}
+
+	envMap, err := ResolveSecretRefs(appName, e.env)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Env{
+		name:     e.name,
+		filename: "",
+		env:      envMap,
+	}, nil
+}
@@ -19,16 +19,21 @@ Manage global and app-specific config vars
 Additional commands:`
 
 	helpContent = `
-    config (<app>|--global), Pretty-print an app or global environment
-    config:bundle [--merged] (<app>|--global), Bundle environment into tarfile
-    config:clear [--no-restart] (<app>|--global), Clears environment variables
-    config:export [--format=FORMAT] [--merged] (<app>|--global), Export a global or app environment
-    config:get [--quoted] (<app>|--global) KEY, Display a global or app-specific config value
+    config [--phase PHASE|--process PROCESS] (<app>|--global), Pretty-print an app or global environment
+    config:bundle [--merged] [--phase PHASE|--process PROCESS] (<app>|--global), Bundle environment into tarfile
+    config:clear [--no-restart] [--phase PHASE|--process PROCESS] (<app>|--global), Clears environment variables
+    config:export [--format=FORMAT] [--merged] [--phase PHASE|--process PROCESS] [--resolve-secretrefs] (<app>|--global), Export a global or app environment
+    config:get [--quoted] [--phase PHASE|--process PROCESS] (<app>|--global) KEY, Display a global or app-specific config value
+    config:history [--format=FORMAT] [--phase PHASE|--process PROCESS] (<app>|--global), List recorded changes to an environment
     config:import [--no-restart] [--replace] (<app>|--global) [FILE|-], Import environment from file
-    config:keys [--merged] (<app>|--global), Show keys set in environment
-    config:show [--merged] (<app>|--global), Show keys set in environment
-    config:set [--encoded] [--no-restart] (<app>|--global) KEY1=VALUE1 [KEY2=VALUE2 ...], Set one or more config vars
-    config:unset [--no-restart] (<app>|--global) KEY1 [KEY2 ...], Unset one or more config vars`
+    config:keys [--merged] [--phase PHASE|--process PROCESS] (<app>|--global), Show keys set in environment
+    config:rollback [--no-restart] [--phase PHASE|--process PROCESS] (<app>|--global) VERSION, Restore an environment to a recorded version
+    config:rotate-key [--decrypt], Encrypt config values at rest with a new key
+    config:show [--merged] [--phase PHASE|--process PROCESS] (<app>|--global), Show keys set in environment
+    config:set [--encoded] [--no-restart] [--phase PHASE|--process PROCESS] (<app>|--global) KEY1=VALUE1 [KEY2=VALUE2 ...], Set one or more config vars
+    config:set-property --global <key> <value>, Set or clear a global config property
+    config:unset [--no-restart] [--phase PHASE|--process PROCESS] (<app>|--global) KEY1 [KEY2 ...], Unset one or more config vars
+    config:validate <app>, Validate config vars against the env definitions of app.json`
 )
 
 func main() {
@@ -41,13 +46,15 @@ func main() {
 		args := flag.NewFlagSet("config", flag.ExitOnError)
 		args.Usage = usage
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		shell := args.Bool("shell", false, "--shell: in a single-line for usage in command-line utilities [deprecated]")
 		export := args.Bool("export", false, "--export: print the env as eval-compatible exports [deprecated]")
 		merged := args.Bool("merged", false, "--merged: display the app's environment merged with the global environment")
 		args.Parse(os.Args[2:])
 		appName := args.Arg(0)
 
-		if err := config.CommandShow(appName, *global, *merged, *shell, *export); err != nil {
+		if err := config.CommandShow(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *global, *merged, *shell, *export); err != nil {
 			common.LogFailWithError(err)
 		}
 	case "config:help":
@@ -28,62 +28,77 @@ func main() {
 	case "bundle":
 		args := flag.NewFlagSet("bundle", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: merge app environment and global environment")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.SubBundle(appName, *merged)
+		err = config.SubBundle(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *merged)
 	case "clear":
 		args := flag.NewFlagSet("clear", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		noRestart := args.Bool("no-restart", false, "--no-restart: no restart")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.SubClear(appName, *noRestart)
+		err = config.SubClear(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *noRestart)
 	case "export":
 		args := flag.NewFlagSet("export", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: merge app environment and global environment")
 		format := args.String("format", "exports", "--format: [ docker-args | docker-args-keys | exports | envfile | json | json-list | pack-keys | pretty | shell ] which format to export as)")
+		resolveSecretRefs := args.Bool("resolve-secretrefs", false, "--resolve-secretrefs: replace secret references with the secrets they point to")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.SubExport(appName, *merged, *format)
+		err = config.SubExport(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *merged, *format, *resolveSecretRefs)
 	case "get":
 		args := flag.NewFlagSet("get", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		quoted := args.Bool("quoted", false, "--quoted: get the value quoted")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
 		keys := getKeys(args.Args(), *global)
-		err = config.SubGet(appName, keys, *quoted)
+		err = config.SubGet(appName, config.Scope{Phase: *phase, ProcessType: *processType}, keys, *quoted)
 	case "keys":
 		args := flag.NewFlagSet("keys", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: merge app environment and global environment")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.SubKeys(appName, *merged)
+		err = config.SubKeys(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *merged)
 	case "show":
 		args := flag.NewFlagSet("show", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: display the app's environment merged with the global environment")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.SubShow(appName, *merged, false, false)
+		err = config.SubShow(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *merged, false, false)
 	case "set":
 		args := flag.NewFlagSet("set", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		encoded := args.Bool("encoded", false, "--encoded: interpret VALUEs as base64")
 		noRestart := args.Bool("no-restart", false, "--no-restart: no restart")
 		args.Parse(os.Args[2:])
@@ -91,17 +106,19 @@ func main() {
 			appName = args.Arg(0)
 		}
 		pairs := getKeys(args.Args(), *global)
-		err = config.SubSet(appName, pairs, *noRestart, *encoded)
+		err = config.SubSet(appName, config.Scope{Phase: *phase, ProcessType: *processType}, pairs, *noRestart, *encoded)
 	case "unset":
 		args := flag.NewFlagSet("unset", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		noRestart := args.Bool("no-restart", false, "--no-restart: no restart")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
 		keys := getKeys(args.Args(), *global)
-		err = config.SubUnset(appName, keys, *noRestart)
+		err = config.SubUnset(appName, config.Scope{Phase: *phase, ProcessType: *processType}, keys, *noRestart)
 	default:
 		err = fmt.Errorf("Invalid plugin config_sub call: %s", action)
 	}
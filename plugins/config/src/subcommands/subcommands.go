@@ -30,41 +30,61 @@ func main() {
 	case "bundle":
 		args := flag.NewFlagSet("config:bundle", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: merge app environment and global environment")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.CommandBundle(appName, *global, *merged)
+		err = config.CommandBundle(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *global, *merged)
 	case "clear":
 		args := flag.NewFlagSet("config:clear", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		noRestart := args.Bool("no-restart", false, "--no-restart: no restart")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.CommandClear(appName, *global, *noRestart)
+		err = config.CommandClear(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *global, *noRestart)
 	case "export":
 		args := flag.NewFlagSet("config:export", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: merge app environment and global environment")
 		format := args.String("format", "exports", "--format: [ docker-args | docker-args-keys | exports | envfile | json | json-list | pack-keys | pretty | shell ] which format to export as)")
+		resolveSecretRefs := args.Bool("resolve-secretrefs", false, "--resolve-secretrefs: replace secret references with the secrets they point to")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.CommandExport(appName, *global, *merged, *format)
+		err = config.CommandExport(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *global, *merged, *format, *resolveSecretRefs)
 	case "get":
 		args := flag.NewFlagSet("config:get", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		quoted := args.Bool("quoted", false, "--quoted: get the value quoted")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
 		keys := getKeys(args.Args(), *global)
-		err = config.CommandGet(appName, keys, *global, *quoted)
+		err = config.CommandGet(appName, config.Scope{Phase: *phase, ProcessType: *processType}, keys, *global, *quoted)
+	case "history":
+		args := flag.NewFlagSet("config:history", flag.ExitOnError)
+		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
+		format := args.String("format", "stdout", "--format: [ stdout | json ] the format to output the history in")
+		args.Parse(os.Args[2:])
+		if !*global {
+			appName = args.Arg(0)
+		}
+		err = config.CommandHistory(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *global, *format)
 	case "import":
 		args := flag.NewFlagSet("config:import", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
@@ -84,24 +104,50 @@ func main() {
 	case "keys":
 		args := flag.NewFlagSet("config:keys", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: merge app environment and global environment")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.CommandKeys(appName, *global, *merged)
+		err = config.CommandKeys(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *global, *merged)
 	case "show":
 		args := flag.NewFlagSet("config:show", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		merged := args.Bool("merged", false, "--merged: display the app's environment merged with the global environment")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
-		err = config.CommandShow(appName, *global, *merged, false, false)
+		err = config.CommandShow(appName, config.Scope{Phase: *phase, ProcessType: *processType}, *global, *merged, false, false)
+	case "rollback":
+		args := flag.NewFlagSet("config:rollback", flag.ExitOnError)
+		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
+		noRestart := args.Bool("no-restart", false, "--no-restart: no restart")
+		args.Parse(os.Args[2:])
+		var version string
+		if !*global {
+			appName = args.Arg(0)
+			version = args.Arg(1)
+		} else {
+			version = args.Arg(0)
+		}
+		err = config.CommandRollback(appName, config.Scope{Phase: *phase, ProcessType: *processType}, version, *global, *noRestart)
+	case "rotate-key":
+		args := flag.NewFlagSet("config:rotate-key", flag.ExitOnError)
+		decrypt := args.Bool("decrypt", false, "--decrypt: decrypt all config values and remove the key")
+		args.Parse(os.Args[2:])
+		err = config.CommandRotateKey(*decrypt)
 	case "set":
 		args := flag.NewFlagSet("config:set", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		encoded := args.Bool("encoded", false, "--encoded: interpret VALUEs as base64")
 		noRestart := args.Bool("no-restart", false, "--no-restart: no restart")
 		args.Parse(os.Args[2:])
@@ -109,17 +155,37 @@ func main() {
 			appName = args.Arg(0)
 		}
 		pairs := getKeys(args.Args(), *global)
-		err = config.CommandSet(appName, pairs, *global, *noRestart, *encoded)
+		err = config.CommandSet(appName, config.Scope{Phase: *phase, ProcessType: *processType}, pairs, *global, *noRestart, *encoded)
+	case "set-property":
+		args := flag.NewFlagSet("config:set-property", flag.ExitOnError)
+		global := args.Bool("global", false, "--global: set a global property")
+		args.Parse(os.Args[2:])
+		appName = args.Arg(0)
+		property := args.Arg(1)
+		value := args.Arg(2)
+		if *global {
+			appName = "--global"
+			property = args.Arg(0)
+			value = args.Arg(1)
+		}
+		err = config.CommandSetProperty(appName, property, value)
 	case "unset":
 		args := flag.NewFlagSet("config:unset", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: use the global environment")
+		phase := args.String("phase", "", "--phase: [ build | deploy | run ] the phase the config vars are scoped to")
+		processType := args.String("process", "", "--process: the process type the config vars are scoped to")
 		noRestart := args.Bool("no-restart", false, "--no-restart: no restart")
 		args.Parse(os.Args[2:])
 		if !*global {
 			appName = args.Arg(0)
 		}
 		keys := getKeys(args.Args(), *global)
-		err = config.CommandUnset(appName, keys, *global, *noRestart)
+		err = config.CommandUnset(appName, config.Scope{Phase: *phase, ProcessType: *processType}, keys, *global, *noRestart)
+	case "validate":
+		args := flag.NewFlagSet("config:validate", flag.ExitOnError)
+		args.Parse(os.Args[2:])
+		appName = args.Arg(0)
+		err = config.CommandValidate(appName)
 	default:
 		err = fmt.Errorf("Invalid plugin subcommand call: %s", subcommand)
 	}
@@ -1,45 +1,61 @@
 package config
 
+import (
+	"errors"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
 // CommandBundle creates a tarball of a .env.d directory
 // containing env vars for the app
-func CommandBundle(appName string, global bool, merged bool) error {
+func CommandBundle(appName string, scope Scope, global bool, merged bool) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubBundle(appName, merged)
+	return SubBundle(appName, scope, merged)
 }
 
 // CommandClear unsets all environment variables in use
-func CommandClear(appName string, global bool, noRestart bool) error {
+func CommandClear(appName string, scope Scope, global bool, noRestart bool) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubClear(appName, noRestart)
+	return SubClear(appName, scope, noRestart)
 }
 
 // CommandExport outputs all env vars (merged or not, global or not)
 // in the specified format for consumption by other tools
-func CommandExport(appName string, global bool, merged bool, format string) error {
+func CommandExport(appName string, scope Scope, global bool, merged bool, format string, resolveSecretRefs bool) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubExport(appName, merged, format)
+	return SubExport(appName, scope, merged, format, resolveSecretRefs)
 }
 
 // CommandGet gets the value for the specified environment variable
-func CommandGet(appName string, keys []string, global bool, quoted bool) error {
+func CommandGet(appName string, scope Scope, keys []string, global bool, quoted bool) error {
+	appName, err := getAppNameOrGlobal(appName, global)
+	if err != nil {
+		return err
+	}
+
+	return SubGet(appName, scope, keys, quoted)
+}
+
+// CommandHistory lists the recorded config changes of the specified environment
+func CommandHistory(appName string, scope Scope, global bool, format string) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubGet(appName, keys, quoted)
+	return SubHistory(appName, scope, format)
 }
 
 // CommandImport imports environment variables from a file
@@ -53,41 +69,99 @@ func CommandImport(appName string, global bool, replace bool, noRestart bool, fo
 }
 
 // CommandKeys shows the keys set for the specified environment
-func CommandKeys(appName string, global bool, merged bool) error {
+func CommandKeys(appName string, scope Scope, global bool, merged bool) error {
+	appName, err := getAppNameOrGlobal(appName, global)
+	if err != nil {
+		return err
+	}
+
+	return SubKeys(appName, scope, merged)
+}
+
+// CommandRollback restores the specified environment to a recorded version
+func CommandRollback(appName string, scope Scope, version string, global bool, noRestart bool) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubKeys(appName, merged)
+	return SubRollback(appName, scope, version, noRestart)
+}
+
+// CommandRotateKey re-encrypts all environments with a new host key, or
+// decrypts them and removes the host key
+func CommandRotateKey(decrypt bool) error {
+	if decrypt {
+		if !EncryptionEnabled() {
+			return errors.New("Config encryption is not enabled")
+		}
+
+		common.LogInfo1("Decrypting config values")
+		if err := DisableEncryption(); err != nil {
+			return err
+		}
+		common.LogVerbose("Config encryption disabled")
+		return nil
+	}
+
+	if EncryptionEnabled() {
+		common.LogInfo1("Re-encrypting config values with a new key")
+	} else {
+		common.LogInfo1("Encrypting config values")
+	}
+	if err := RotateEncryptionKey(); err != nil {
+		return err
+	}
+	common.LogVerbose("Config encryption key rotated")
+	return nil
 }
 
 // CommandSet sets one or more environment variable pairs
-func CommandSet(appName string, pairs []string, global bool, noRestart bool, encoded bool) error {
+func CommandSet(appName string, scope Scope, pairs []string, global bool, noRestart bool, encoded bool) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubSet(appName, pairs, noRestart, encoded)
+	return SubSet(appName, scope, pairs, noRestart, encoded)
+}
+
+// CommandSetProperty sets or clears a config property. Properties limit what
+// secret references may read, so they may only be set globally.
+func CommandSetProperty(appName string, property string, value string) error {
+	if appName != "--global" {
+		return errors.New("Config properties may only be set globally")
+	}
+
+	common.CommandPropertySet("config", appName, property, value, DefaultProperties, GlobalProperties)
+	return nil
 }
 
 // CommandShow pretty-prints the specified environment vaiables
-func CommandShow(appName string, global bool, merged bool, shell bool, export bool) error {
+func CommandShow(appName string, scope Scope, global bool, merged bool, shell bool, export bool) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubShow(appName, merged, shell, export)
+	return SubShow(appName, scope, merged, shell, export)
 }
 
 // CommandUnset unsets one or more keys in a specified environment
-func CommandUnset(appName string, keys []string, global bool, noRestart bool) error {
+func CommandUnset(appName string, scope Scope, keys []string, global bool, noRestart bool) error {
 	appName, err := getAppNameOrGlobal(appName, global)
 	if err != nil {
 		return err
 	}
 
-	return SubUnset(appName, keys, noRestart)
+	return SubUnset(appName, scope, keys, noRestart)
+}
+
+// CommandValidate checks the environment of an app against the env definitions of its app.json
+func CommandValidate(appName string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	return SubValidate(appName)
 }
@@ -26,7 +26,7 @@ func TriggerConfigExport(appName string, global string, merged string, format st
 		return err
 	}
 
-	return export(appName, m, format)
+	return export(appName, Scope{}, m, format, false)
 }
 
 // TriggerConfigGet returns an app config value by key
@@ -51,7 +51,7 @@ func TriggerConfigGetGlobal(key string) error {
 
 // TriggerConfigSet sets config values for an app
 func TriggerConfigSet(appName string, noRestart bool, pairs ...string) error {
-	return SubSet(appName, pairs, noRestart, false)
+	return SubSet(appName, Scope{}, pairs, noRestart, false)
 }
 
 // TriggerConfigUnset unsets an app config value by key
@@ -95,6 +95,12 @@ func TriggerInstall() error {
 
 // TriggerPostAppCloneSetup creates new buildpacks files
 func TriggerPostAppCloneSetup(oldAppName string, newAppName string) error {
+	unlock, err := lockAllConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	oldEnv, err := LoadAppEnv(oldAppName)
 	if err != nil {
 		return fmt.Errorf("Unable to load old environment: %s", err.Error())
@@ -110,11 +116,21 @@ func TriggerPostAppCloneSetup(oldAppName string, newAppName string) error {
 		return fmt.Errorf("Unable to write new environment: %s", err.Error())
 	}
 
+	if err := copyAppScopes(oldAppName, newAppName); err != nil {
+		return fmt.Errorf("Unable to copy scoped environments: %s", err.Error())
+	}
+
 	return nil
 }
 
 // TriggerPostAppRenameSetup renames buildpacks files
 func TriggerPostAppRenameSetup(oldAppName string, newAppName string) error {
+	unlock, err := lockAllConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	oldEnv, err := LoadAppEnv(oldAppName)
 	if err != nil {
 		return fmt.Errorf("Unable to load old environment: %s", err.Error())
@@ -130,6 +146,10 @@ func TriggerPostAppRenameSetup(oldAppName string, newAppName string) error {
 		return fmt.Errorf("Unable to write new environment: %s", err.Error())
 	}
 
+	if err := moveAppScopes(oldAppName, newAppName); err != nil {
+		return fmt.Errorf("Unable to move scoped environments and config history: %s", err.Error())
+	}
+
 	return nil
 }
 
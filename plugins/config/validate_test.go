@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupAppJSONEnv(t *testing.T, appName string, content string) {
+	t.Helper()
+	directory := filepath.Join(os.Getenv("DOKKU_LIB_ROOT"), "data", "app-json", appName)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "app.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+const validateAppJSON = `{
+  "env": {
+    "PORT": {"type": "int", "value": "5000"},
+    "LOG_LEVEL": {"type": "enum", "enum": ["debug", "info"], "required": false},
+    "DATABASE_URL": {"type": "url"},
+    "API_KEY": {"pattern": "sk_[a-z0-9]+", "required": false}
+  }
+}`
+
+func TestSetManyValidatesAppJSONEnv(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+	setupAppJSONEnv(t, "alpha", validateAppJSON)
+
+	invalid := []map[string]string{
+		{"PORT": "abc"},
+		{"LOG_LEVEL": "trace"},
+		{"DATABASE_URL": "not a url"},
+		{"API_KEY": "pk_123"},
+	}
+	for _, entries := range invalid {
+		if err := SetMany("alpha", entries, false, false); err == nil {
+			t.Errorf("expected SetMany(%v) to fail validation", entries)
+		}
+	}
+	if _, ok := Get("alpha", "PORT"); ok {
+		t.Error("expected an invalid value to not be written")
+	}
+
+	err := SetMany("alpha", map[string]string{"PORT": "abc"}, false, false)
+	if err == nil || !strings.Contains(err.Error(), "Invalid value for PORT: expected an integer") {
+		t.Errorf("expected a clear error for PORT, got %v", err)
+	}
+	if strings.Contains(err.Error(), "abc") {
+		t.Errorf("expected the error to not include the value, got %v", err)
+	}
+
+	valid := map[string]string{
+		"PORT":         "5000",
+		"LOG_LEVEL":    "debug",
+		"DATABASE_URL": "postgres://user:pass@db:5432/app",
+		"API_KEY":      "sk_abc123",
+		"UNDECLARED":   "anything",
+	}
+	if err := SetMany("alpha", valid, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := SetMany("alpha", map[string]string{"DATABASE_URL": "secretref://env/DATABASE_URL"}, false, false); err != nil {
+		t.Errorf("expected secret references to skip validation, got %v", err)
+	}
+}
+
+func TestSubValidate(t *testing.T) {
+	setupHistoryApp(t, "alpha")
+
+	if err := SubValidate("alpha"); err != nil {
+		t.Errorf("expected an app without app.json to be valid, got %v", err)
+	}
+
+	if err := SetMany("alpha", map[string]string{"PORT": "abc"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	setupAppJSONEnv(t, "alpha", validateAppJSON)
+
+	err := SubValidate("alpha")
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, want := range []string{"Invalid value for PORT", "Missing value for required env var DATABASE_URL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	if err := SetMany("alpha", map[string]string{"PORT": "5000", "DATABASE_URL": "postgres://db/app"}, false, false); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+	if err := SubValidate("alpha"); err != nil {
+		t.Errorf("expected the app to be valid, got %v", err)
+	}
+
+	processFile, _ := getScopeFile("alpha", Scope{ProcessType: "worker"})
+	if err := setupScopeDir(processFile); err != nil {
+		t.Fatalf("setupScopeDir: %v", err)
+	}
+	if err := os.WriteFile(processFile, []byte("LOG_LEVEL=trace\n"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	err = SubValidate("alpha")
+	if err == nil || !strings.Contains(err.Error(), "Invalid value for LOG_LEVEL: expected one of: debug, info (worker process)") {
+		t.Errorf("expected the worker process value to be reported, got %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
+	_ "time/tzdata"
 
 	appjson "github.com/dokku/dokku/plugins/app-json"
 	"github.com/dokku/dokku/plugins/common"
@@ -27,21 +29,43 @@ func ValidateCronCommand(command string) error {
 var (
 	// DefaultProperties is a map of all valid cron properties with corresponding default property values
 	DefaultProperties = map[string]string{
-		"mailfrom":    "",
-		"mailto":      "",
-		"maintenance": "false",
+		"history-retention": strconv.Itoa(DefaultHistoryRetention),
+		"mailfrom":          "",
+		"mailto":            "",
+		"maintenance":       "false",
+		"notify-on":         NotifyOnFailure,
+		"webhook-url":       "",
 	}
 
 	// GlobalProperties is a map of all valid global cron properties
 	GlobalProperties = map[string]bool{
-		"mailfrom":    true,
-		"mailto":      true,
-		"maintenance": true,
+		"history-retention": true,
+		"mailfrom":          true,
+		"mailto":            true,
+		"maintenance":       true,
+		"notify-on":         true,
+		"webhook-url":       true,
 	}
 )
 
+// scheduleParser parses the standard five-field cron schedules and
+// descriptors supported by both the host crontab and k3s
+var scheduleParser = cronparser.NewParser(cronparser.Minute | cronparser.Hour | cronparser.Dom | cronparser.Month | cronparser.Dow | cronparser.Descriptor)
+
 const MaintenancePropertyPrefix = "maintenance."
 
+const (
+	// DefaultRetryBackoffSeconds is the wait before the first retry of a
+	// failed cron task when retry_backoff_seconds is not specified
+	DefaultRetryBackoffSeconds = 10
+
+	// MaxRetries is the maximum number of retries a cron task may specify
+	MaxRetries = 10
+
+	// MaxRetryBackoff caps the wait between two attempts of a cron task
+	MaxRetryBackoff = time.Hour
+)
+
 // DefaultTTLSeconds is how long a cron task may run before it is reaped. The
 // docker-local scheduler stamps this onto the container as the
 // com.dokku.active-deadline-seconds label, while the k3s scheduler renders it
@@ -59,6 +83,24 @@ func validateTTLSeconds(ttlSeconds int64) error {
 	return nil
 }
 
+// ValidateTimezone returns an error if the timezone is not a tz database
+// name. An empty timezone is valid and means the server's timezone.
+func ValidateTimezone(timezone string) error {
+	if timezone == "" {
+		return nil
+	}
+
+	if timezone == "Local" {
+		return fmt.Errorf("timezone must be a tz database name")
+	}
+
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", timezone)
+	}
+
+	return nil
+}
+
 // CronTask is a struct that represents a cron task
 type CronTask struct {
 	// ID is a unique identifier for the cron task
@@ -79,6 +121,20 @@ type CronTask struct {
 	// ConcurrencyPolicy is the concurrency policy for the cron command
 	ConcurrencyPolicy string `json:"concurrency_policy"`
 
+	// Timezone is the tz database name the schedule is evaluated in, or
+	// empty for the server's timezone
+	Timezone string `json:"timezone,omitempty"`
+
+	// Source is where the cron task was defined
+	Source string `json:"source,omitempty"`
+
+	// Retries is the number of times a failed run is retried
+	Retries int `json:"retries,omitempty"`
+
+	// RetryBackoffSeconds is the number of seconds to wait before the first
+	// retry. The wait doubles after each failed attempt.
+	RetryBackoffSeconds int `json:"retry_backoff_seconds,omitempty"`
+
 	// AltCommand is an alternate command to run
 	AltCommand string `json:"-"`
 
@@ -95,6 +151,19 @@ type CronTask struct {
 	Maintenance bool `json:"maintenance"`
 }
 
+// RetryBackoff returns how long to wait before the given retry of a failed
+// run, starting at RetryBackoffSeconds and doubling for each further retry
+func (t CronTask) RetryBackoff(retry int) time.Duration {
+	backoff := time.Duration(t.RetryBackoffSeconds) * time.Second
+	for i := 1; i < retry && backoff < MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxRetryBackoff {
+		backoff = MaxRetryBackoff
+	}
+	return backoff
+}
+
 // DokkuRunCommand returns the dokku run command to execute for a given cron task
 func (t CronTask) DokkuRunCommand() string {
 	if t.AltCommand != "" {
@@ -133,7 +202,22 @@ func FetchCronTasks(input FetchCronTasksInput) ([]CronTask, error) {
 		input.AppJSON = &appJSON
 	}
 
-	if input.AppJSON.Cron == nil {
+	entries := []cronTaskEntry{}
+	for _, c := range input.AppJSON.Cron {
+		entries = append(entries, cronTaskEntry{Task: c, Source: CronTaskSourceAppJSON})
+	}
+
+	if appName != "" {
+		cliTasks, err := sortedCLITasks(appName)
+		if err != nil {
+			return tasks, err
+		}
+		for _, c := range cliTasks {
+			entries = append(entries, cronTaskEntry{Task: c, Source: CronTaskSourceCLI})
+		}
+	}
+
+	if len(entries) == 0 {
 		return tasks, nil
 	}
 
@@ -142,7 +226,8 @@ func FetchCronTasks(input FetchCronTasksInput) ([]CronTask, error) {
 		return tasks, fmt.Errorf("Error getting maintenance properties: %w", err)
 	}
 
-	for i, c := range input.AppJSON.Cron {
+	for i, entry := range entries {
+		c := entry.Task
 		if c.Command == "" {
 			if input.WarnToFailure {
 				return tasks, fmt.Errorf("Missing cron task command for app %s (index %d)", appName, i)
@@ -161,8 +246,7 @@ func FetchCronTasks(input FetchCronTasksInput) ([]CronTask, error) {
 			continue
 		}
 
-		parser := cronparser.NewParser(cronparser.Minute | cronparser.Hour | cronparser.Dom | cronparser.Month | cronparser.Dow | cronparser.Descriptor)
-		_, err := parser.Parse(c.Schedule)
+		_, err := scheduleParser.Parse(c.Schedule)
 		if err != nil {
 			return tasks, fmt.Errorf("Invalid cron schedule for app %s (schedule %s): %s", appName, c.Schedule, err.Error())
 		}
@@ -172,6 +256,9 @@ func FetchCronTasks(input FetchCronTasksInput) ([]CronTask, error) {
 		}
 
 		cronID := GenerateCommandID(appName, c)
+		if entry.Source == CronTaskSourceCLI {
+			cronID = generateCLICommandID(appName, c)
+		}
 		maintenance := c.Maintenance
 		if value, ok := properties[MaintenancePropertyPrefix+cronID]; ok {
 			boolValue, err := strconv.ParseBool(value)
@@ -191,15 +278,35 @@ func FetchCronTasks(input FetchCronTasksInput) ([]CronTask, error) {
 			return tasks, fmt.Errorf("Invalid cron concurrency policy for app %s (schedule %s): %s", appName, c.Schedule, c.ConcurrencyPolicy)
 		}
 
+		if err := ValidateTimezone(c.Timezone); err != nil {
+			return tasks, fmt.Errorf("Invalid cron timezone for app %s (schedule %s): %s", appName, c.Schedule, err.Error())
+		}
+
+		if c.Retries < 0 || c.Retries > MaxRetries {
+			return tasks, fmt.Errorf("Invalid cron retries for app %s (schedule %s): must be between 0 and %d", appName, c.Schedule, MaxRetries)
+		}
+
+		retryBackoffSeconds := DefaultRetryBackoffSeconds
+		if c.RetryBackoffSeconds != nil {
+			retryBackoffSeconds = *c.RetryBackoffSeconds
+		}
+		if retryBackoffSeconds < 0 {
+			return tasks, fmt.Errorf("Invalid cron retry_backoff_seconds for app %s (schedule %s): must not be negative", appName, c.Schedule)
+		}
+
 		tasks = append(tasks, CronTask{
-			App:               appName,
-			Command:           c.Command,
-			Schedule:          c.Schedule,
-			ID:                cronID,
-			ConcurrencyPolicy: c.ConcurrencyPolicy,
-			Maintenance:       isAppCronInMaintenance || maintenance,
-			AppInMaintenance:  isAppCronInMaintenance,
-			TaskInMaintenance: maintenance,
+			App:                 appName,
+			Command:             c.Command,
+			Schedule:            c.Schedule,
+			ID:                  cronID,
+			ConcurrencyPolicy:   c.ConcurrencyPolicy,
+			Timezone:            c.Timezone,
+			Source:              entry.Source,
+			Retries:             c.Retries,
+			RetryBackoffSeconds: retryBackoffSeconds,
+			Maintenance:         isAppCronInMaintenance || maintenance,
+			AppInMaintenance:    isAppCronInMaintenance,
+			TaskInMaintenance:   maintenance,
 		})
 	}
 
@@ -232,6 +339,7 @@ func FetchGlobalCronTasks() ([]CronTask, error) {
 			Schedule:          parts[0],
 			Command:           parts[1],
 			AltCommand:        parts[1],
+			Source:            CronTaskSourcePlugin,
 			Global:            true,
 			Maintenance:       false,
 			TaskInMaintenance: false,
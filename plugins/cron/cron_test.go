@@ -1,8 +1,16 @@
 package cron
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	appjson "github.com/dokku/dokku/plugins/app-json"
 )
 
 func TestDokkuRunCommandAppTaskDispatchesViaCronRun(t *testing.T) {
@@ -152,3 +160,385 @@ func TestValidateTTLSeconds(t *testing.T) {
 		})
 	}
 }
+
+func TestCronRunHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("DOKKU_LIB_ROOT", tmpDir)
+
+	retentionPath := filepath.Join(tmpDir, "config", "cron", "myapp", "history-retention")
+	if err := os.MkdirAll(filepath.Dir(retentionPath), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(retentionPath, []byte("2"), 0644); err != nil {
+		t.Fatalf("write retention: %v", err)
+	}
+
+	task := CronTask{App: "myapp", ID: "abc123", Command: "echo hi"}
+	exitCodes := []int{0, 1, 0}
+	runIDs := []string{}
+	for _, exitCode := range exitCodes {
+		run, err := startRun(task, false, 1)
+		if err != nil {
+			t.Fatalf("startRun: %v", err)
+		}
+		RecordRunContainer("myapp", run.ID, "myapp.cron."+run.ID)
+		if _, err := finishRun("myapp", run.ID, exitCode); err != nil {
+			t.Fatalf("finishRun: %v", err)
+		}
+		runIDs = append(runIDs, run.ID)
+		time.Sleep(time.Millisecond)
+	}
+
+	skipped, err := startRun(CronTask{App: "myapp", ID: "def456", Command: "echo skip"}, false, 1)
+	if err != nil {
+		t.Fatalf("startRun: %v", err)
+	}
+	RecordRunSkipped("myapp", skipped.ID)
+
+	inFlight, err := ReadRun("myapp", skipped.ID)
+	if err != nil {
+		t.Fatalf("ReadRun: %v", err)
+	}
+	if inFlight.Status != CronRunStatusRunning {
+		t.Errorf("in-flight status = %q, want %q", inFlight.Status, CronRunStatusRunning)
+	}
+	if _, err := finishRun("myapp", skipped.ID, 1); err != nil {
+		t.Fatalf("finishRun: %v", err)
+	}
+
+	runs, err := FetchRuns("myapp", "abc123")
+	if err != nil {
+		t.Fatalf("FetchRuns: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("FetchRuns returned %d runs after pruning, want 1", len(runs))
+	}
+	if runs[0].ID != runIDs[2] {
+		t.Errorf("kept run = %s, want newest %s", runs[0].ID, runIDs[2])
+	}
+	if runs[0].Container != "myapp.cron."+runIDs[2] {
+		t.Errorf("container = %q", runs[0].Container)
+	}
+
+	last := LastRuns("myapp")
+	if last["abc123"].Status != CronRunStatusSucceeded {
+		t.Errorf("last abc123 status = %q, want %q", last["abc123"].Status, CronRunStatusSucceeded)
+	}
+	if last["def456"].Status != CronRunStatusSkipped {
+		t.Errorf("last def456 status = %q, want %q", last["def456"].Status, CronRunStatusSkipped)
+	}
+	if last["def456"].ExitCode == nil || *last["def456"].ExitCode != 1 {
+		t.Errorf("last def456 exit code = %v, want 1", last["def456"].ExitCode)
+	}
+
+	// recording against an unknown run must not fail the task
+	RecordRunContainer("myapp", "", "ignored")
+	RecordRunSkipped("myapp", "missing")
+}
+
+func TestValidateTimezone(t *testing.T) {
+	cases := map[string]bool{
+		"":                  true,
+		"UTC":               true,
+		"Europe/Berlin":     true,
+		"America/New_York":  true,
+		"Local":             false,
+		"Mars/Olympus_Mons": false,
+		"../../etc/passwd":  false,
+	}
+	for timezone, valid := range cases {
+		err := ValidateTimezone(timezone)
+		if valid && err != nil {
+			t.Errorf("ValidateTimezone(%q) returned error: %v", timezone, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("ValidateTimezone(%q) returned no error", timezone)
+		}
+	}
+}
+
+func TestCronTemplateTimezoneGroups(t *testing.T) {
+	tasks := []CronTask{
+		{App: "a", ID: "1", Schedule: "@daily", Timezone: "Europe/Berlin"},
+		{App: "a", ID: "2", Schedule: "@hourly"},
+		{App: "b", ID: "3", Schedule: "0 9 * * *", Timezone: "America/New_York"},
+		{App: "b", ID: "4", Schedule: "0 5 * * *", Timezone: "Europe/Berlin"},
+	}
+
+	tmpl, err := getCronTemplate()
+	if err != nil {
+		t.Fatalf("getCronTemplate: %v", err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, map[string]interface{}{
+		"Groups": groupCronTasksByTimezone(tasks),
+	}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"SHELL=/bin/bash",
+		"",
+		"@hourly dokku cron:run a 2",
+		"CRON_TZ=America/New_York",
+		"0 9 * * * dokku cron:run b 3",
+		"CRON_TZ=Europe/Berlin",
+		"@daily dokku cron:run a 1",
+		"0 5 * * * dokku cron:run b 4",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("crontab =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestTimezoneCronTasks(t *testing.T) {
+	tasks := []CronTask{
+		{App: "a", ID: "1", Schedule: "@daily", Timezone: "Europe/Berlin"},
+		{App: "a", ID: "2", Schedule: "@hourly"},
+		{App: "b", ID: "3", Schedule: "0 9 * * *", Timezone: "America/New_York"},
+	}
+
+	timezoned := timezoneCronTasks(tasks)
+	if len(timezoned) != 2 || timezoned[0].ID != "1" || timezoned[1].ID != "3" {
+		t.Errorf("timezoneCronTasks = %v, want tasks 1 and 3", timezoned)
+	}
+	if got := timezoneCronTasks(tasks[1:2]); len(got) != 0 {
+		t.Errorf("timezoneCronTasks = %v, want none", got)
+	}
+}
+
+func TestNextFireTimes(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	from := time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)
+	times, err := NextFireTimes("0 9 * * *", berlin, from, 3)
+	if err != nil {
+		t.Fatalf("NextFireTimes: %v", err)
+	}
+
+	// Berlin switches to summer time on 2024-03-31, moving 09:00 from 08:00 to 07:00 UTC
+	want := []time.Time{
+		time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 1, 7, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 2, 7, 0, 0, 0, time.UTC),
+	}
+	if len(times) != len(want) {
+		t.Fatalf("NextFireTimes returned %d times, want %d", len(times), len(want))
+	}
+	for i := range want {
+		if !times[i].Equal(want[i]) {
+			t.Errorf("NextFireTimes[%d] = %s, want %s", i, times[i].UTC(), want[i])
+		}
+		if times[i].Location() != berlin {
+			t.Errorf("NextFireTimes[%d] location = %s, want Europe/Berlin", i, times[i].Location())
+		}
+	}
+
+	if _, err := NextFireTimes("@nonstandard", time.UTC, from, 1); err == nil {
+		t.Errorf("NextFireTimes accepted an invalid schedule")
+	}
+}
+
+func TestFireTimesForTasksSkipsSuspended(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+	results := fireTimesForTasks([]CronTask{
+		{ID: "active", Schedule: "@hourly"},
+		{ID: "suspended", Schedule: "@hourly", Maintenance: true, TaskInMaintenance: true},
+	}, time.UTC, from, 2)
+
+	if len(results) != 2 {
+		t.Fatalf("fireTimesForTasks returned %d results, want 2", len(results))
+	}
+	if len(results[0].NextRuns) != 2 || !results[0].NextRuns[0].Equal(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("active task next runs = %v", results[0].NextRuns)
+	}
+	if len(results[1].NextRuns) != 0 {
+		t.Errorf("suspended task next runs = %v, want none", results[1].NextRuns)
+	}
+	if got := suspendedLabel(results[1].CronTask); got != "suspended (task)" {
+		t.Errorf("suspendedLabel = %q", got)
+	}
+}
+
+func writeTestProperty(t *testing.T, libRoot string, appName string, property string, value string) {
+	t.Helper()
+	path := filepath.Join(libRoot, "config", "cron", appName, property)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		t.Fatalf("write property: %v", err)
+	}
+}
+
+func TestNotifyRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("DOKKU_LIB_ROOT", tmpDir)
+
+	notifications := []CronNotification{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Dokku-Event") != NotificationEvent {
+			t.Errorf("X-Dokku-Event = %q", r.Header.Get("X-Dokku-Event"))
+		}
+		var notification CronNotification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		notifications = append(notifications, notification)
+	}))
+	defer server.Close()
+
+	task := CronTask{App: "myapp", ID: "abc123", Command: "python3 task.py", Schedule: "@daily"}
+	run := CronRun{ID: "run1", App: "myapp", TaskID: "abc123", StartedAt: time.Now().UTC()}
+
+	// no webhook-url configured
+	NotifyRun(task, run, 1, "", "")
+	if len(notifications) != 0 {
+		t.Fatalf("sent %d notifications without a webhook-url", len(notifications))
+	}
+
+	writeTestProperty(t, tmpDir, "--global", "webhook-url", server.URL)
+
+	// the default notify-on only reports failures
+	NotifyRun(task, run, 0, "ok\n", "")
+	if len(notifications) != 0 {
+		t.Fatalf("sent %d notifications for a successful run", len(notifications))
+	}
+
+	stdout := ""
+	for i := 1; i <= NotificationOutputTailLines+10; i++ {
+		stdout += "line\n"
+	}
+	NotifyRun(task, run, 2, stdout, "boom\n")
+	if len(notifications) != 1 {
+		t.Fatalf("sent %d notifications for a failed run, want 1", len(notifications))
+	}
+	got := notifications[0]
+	if got.TaskID != "abc123" || got.Command != "python3 task.py" || got.ExitCode != 2 || got.Status != CronRunStatusFailed {
+		t.Errorf("unexpected notification: %+v", got)
+	}
+	if lines := strings.Split(got.Output, "\n"); len(lines) != NotificationOutputTailLines || lines[len(lines)-1] != "boom" {
+		t.Errorf("output tail has %d lines ending in %q", len(lines), lines[len(lines)-1])
+	}
+
+	// task level settings override app and global settings
+	writeTestProperty(t, tmpDir, "myapp", "notify-on", NotifyOnNever)
+	writeTestProperty(t, tmpDir, "myapp", taskProperty("notify-on", "abc123"), NotifyOnAlways)
+	NotifyRun(task, run, 0, "ok\n", "")
+	if len(notifications) != 2 {
+		t.Fatalf("sent %d notifications with notify-on always, want 2", len(notifications))
+	}
+
+	NotifyRun(CronTask{App: "myapp", ID: "def456"}, run, 1, "", "")
+	if len(notifications) != 2 {
+		t.Fatalf("sent a notification for a task in an app with notify-on never")
+	}
+}
+
+func TestCronTaskRetries(t *testing.T) {
+	t.Setenv("DOKKU_LIB_ROOT", t.TempDir())
+
+	fetch := func(retries int, backoff *int) (CronTask, error) {
+		tasks, err := FetchCronTasks(FetchCronTasksInput{
+			AppName: "myapp",
+			AppJSON: &appjson.AppJSON{Cron: []appjson.CronTask{{
+				Command:             "python3 task.py",
+				Schedule:            "@daily",
+				Retries:             retries,
+				RetryBackoffSeconds: backoff,
+			}}},
+		})
+		if err != nil {
+			return CronTask{}, err
+		}
+		return tasks[0], nil
+	}
+
+	task, err := fetch(3, nil)
+	if err != nil {
+		t.Fatalf("FetchCronTasks: %v", err)
+	}
+	if task.Retries != 3 || task.RetryBackoffSeconds != DefaultRetryBackoffSeconds {
+		t.Errorf("retries = %d, backoff = %d", task.Retries, task.RetryBackoffSeconds)
+	}
+
+	zero := 0
+	if task, err := fetch(1, &zero); err != nil || task.RetryBackoffSeconds != 0 {
+		t.Errorf("explicit zero backoff: task = %+v, err = %v", task, err)
+	}
+
+	negative := -1
+	for _, c := range []struct {
+		retries int
+		backoff *int
+	}{{-1, nil}, {MaxRetries + 1, nil}, {1, &negative}} {
+		if _, err := fetch(c.retries, c.backoff); err == nil {
+			t.Errorf("FetchCronTasks accepted retries %d", c.retries)
+		}
+	}
+
+	task = CronTask{RetryBackoffSeconds: 30}
+	for retry, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		10: MaxRetryBackoff,
+	} {
+		if got := task.RetryBackoff(retry); got != want {
+			t.Errorf("RetryBackoff(%d) = %s, want %s", retry, got, want)
+		}
+	}
+
+	run, err := startRun(CronTask{App: "myapp", ID: "abc123", Retries: 2}, false, 2)
+	if err != nil {
+		t.Fatalf("startRun: %v", err)
+	}
+	if got := run.AttemptString(); got != "2/3" {
+		t.Errorf("AttemptString() = %q", got)
+	}
+}
+
+func TestFetchCronTasksMergesCLITasks(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("DOKKU_LIB_ROOT", tmpDir)
+
+	task := appjson.CronTask{Command: "python3 task.py", Schedule: "@daily"}
+	body, err := json.Marshal(map[string]string{
+		// a stale key, as left behind when an app is cloned
+		"oldid": `{"command":"python3 task.py","schedule":"@daily"}`,
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	writeTestProperty(t, tmpDir, "myapp", cliTasksProperty, string(body))
+
+	tasks, err := FetchCronTasks(FetchCronTasksInput{
+		AppName: "myapp",
+		AppJSON: &appjson.AppJSON{Cron: []appjson.CronTask{task}},
+	})
+	if err != nil {
+		t.Fatalf("FetchCronTasks: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("FetchCronTasks returned %d tasks, want 2", len(tasks))
+	}
+
+	if tasks[0].Source != CronTaskSourceAppJSON || tasks[0].ID != GenerateCommandID("myapp", task) {
+		t.Errorf("app.json task = %+v", tasks[0])
+	}
+	if tasks[1].Source != CronTaskSourceCLI || tasks[1].ID != generateCLICommandID("myapp", task) {
+		t.Errorf("cli task = %+v", tasks[1])
+	}
+	if tasks[0].ID == tasks[1].ID {
+		t.Errorf("cli task shares the ID of the app.json task: %s", tasks[0].ID)
+	}
+	if tasks[1].ConcurrencyPolicy != "allow" {
+		t.Errorf("cli task concurrency policy = %q, want allow", tasks[1].ConcurrencyPolicy)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	_ "embed"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -31,6 +34,46 @@ func usesHostCron(scheduler string) bool {
 	return results.StdoutContents() == "true"
 }
 
+// hostCronSupportsTimezones reports whether the host cron daemon honors the
+// CRON_TZ variable. cronie does, while the cron daemon shipped by Debian and
+// Ubuntu - as well as busybox crond - ignores it and runs every entry in the
+// server's timezone.
+func hostCronSupportsTimezones() bool {
+	for _, name := range []string{"crond", "cron"} {
+		binary, err := exec.LookPath(name)
+		if err != nil {
+			binary = filepath.Join("/usr/sbin", name)
+			if !common.FileExists(binary) {
+				continue
+			}
+		}
+
+		result, err := common.CallExecCommand(common.ExecCommandInput{
+			Command: binary,
+			Args:    []string{"-V"},
+		})
+		if err != nil {
+			continue
+		}
+		if strings.Contains(result.StdoutContents()+result.StderrContents(), "cronie") {
+			return true
+		}
+	}
+	return false
+}
+
+// timezoneCronTasks returns the tasks that are scheduled in a timezone other
+// than the server's
+func timezoneCronTasks(tasks []CronTask) []CronTask {
+	timezoned := []CronTask{}
+	for _, task := range tasks {
+		if task.Timezone != "" {
+			timezoned = append(timezoned, task)
+		}
+	}
+	return timezoned
+}
+
 // hostCronSchedulers returns a map keyed by every distinct scheduler seen across
 // the given apps plus the global scheduler, with a boolean value indicating
 // whether that scheduler uses the host crontab. Deduplicating up front avoids
@@ -177,11 +220,17 @@ func writeCronTab() error {
 		return deleteCrontab()
 	}
 
+	if timezoned := timezoneCronTasks(tasks); len(timezoned) > 0 && !hostCronSupportsTimezones() {
+		for _, task := range timezoned {
+			common.LogWarn(fmt.Sprintf("The host cron daemon does not support CRON_TZ, task %s for app %s will run in the server's timezone instead of %s", task.ID, task.App, task.Timezone))
+		}
+	}
+
 	mailfrom := common.PropertyGetDefault("cron", "--global", "mailfrom", DefaultProperties["mailfrom"])
 	mailto := common.PropertyGetDefault("cron", "--global", "mailto", DefaultProperties["mailto"])
 
 	data := map[string]interface{}{
-		"Tasks":    tasks,
+		"Groups":   groupCronTasksByTimezone(tasks),
 		"Mailfrom": mailfrom,
 		"Mailto":   mailto,
 	}
@@ -219,6 +268,38 @@ func writeCronTab() error {
 	return nil
 }
 
+// cronTaskGroup is a set of cron tasks whose schedules share a timezone
+type cronTaskGroup struct {
+	// Timezone is the tz database name, or empty for the server's timezone
+	Timezone string
+
+	// Tasks are the tasks scheduled in the timezone
+	Tasks []CronTask
+}
+
+// groupCronTasksByTimezone groups tasks so that each timezone is only declared
+// once in the crontab. CRON_TZ applies to every entry that follows it, so
+// tasks in the server's timezone are always written first, before any
+// CRON_TZ line. The order of tasks within a group is preserved.
+func groupCronTasksByTimezone(tasks []CronTask) []cronTaskGroup {
+	groups := []cronTaskGroup{{}}
+	indexes := map[string]int{"": 0}
+	for _, task := range tasks {
+		i, ok := indexes[task.Timezone]
+		if !ok {
+			i = len(groups)
+			indexes[task.Timezone] = i
+			groups = append(groups, cronTaskGroup{Timezone: task.Timezone})
+		}
+		groups[i].Tasks = append(groups[i].Tasks, task)
+	}
+
+	sort.SliceStable(groups[1:], func(i, j int) bool {
+		return groups[1:][i].Timezone < groups[1:][j].Timezone
+	})
+	return groups
+}
+
 // deleteCrontab removes the dokku user crontab
 func deleteCrontab() error {
 	result, err := common.CallExecCommand(common.ExecCommandInput{
@@ -0,0 +1,317 @@
+package cron
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/multiformats/go-base36"
+)
+
+// DefaultHistoryRetention is the number of finished cron runs kept per app
+const DefaultHistoryRetention = 100
+
+// CronRunStatus is the outcome of a single cron task execution
+type CronRunStatus string
+
+const (
+	// CronRunStatusRunning is set while the task is executing
+	CronRunStatusRunning CronRunStatus = "running"
+
+	// CronRunStatusSucceeded is set when the task exited with a zero exit code
+	CronRunStatusSucceeded CronRunStatus = "succeeded"
+
+	// CronRunStatusFailed is set when the task exited with a non-zero exit code
+	CronRunStatusFailed CronRunStatus = "failed"
+
+	// CronRunStatusSkipped is set when the concurrency policy prevented the task from starting
+	CronRunStatusSkipped CronRunStatus = "skipped"
+
+	// CronRunStatusDetached is set when the task was started in the background
+	CronRunStatusDetached CronRunStatus = "detached"
+)
+
+// CronRun is the persisted record of a single cron task execution
+type CronRun struct {
+	// ID is a sortable identifier for the run
+	ID string `json:"id"`
+
+	// App is the app the cron task belongs to
+	App string `json:"app"`
+
+	// TaskID is the ID of the cron task that was run
+	TaskID string `json:"task_id"`
+
+	// Command is the command the task ran
+	Command string `json:"command"`
+
+	// StartedAt is when the run started
+	StartedAt time.Time `json:"started_at"`
+
+	// FinishedAt is when the run finished
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// ExitCode is the exit code of the task
+	ExitCode *int `json:"exit_code,omitempty"`
+
+	// Container is the name of the container or pod the task ran in
+	Container string `json:"container,omitempty"`
+
+	// Skipped is whether the concurrency policy prevented the task from starting
+	Skipped bool `json:"skipped"`
+
+	// Detached is whether the task was started in the background
+	Detached bool `json:"detached,omitempty"`
+
+	// Attempt is the attempt number of the run, starting at 1
+	Attempt int `json:"attempt,omitempty"`
+
+	// MaxAttempts is the number of attempts the task is allowed
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	// Status is the outcome of the run
+	Status CronRunStatus `json:"status"`
+}
+
+// AttemptString returns the attempt of the run out of the attempts allowed
+func (r CronRun) AttemptString() string {
+	if r.Attempt == 0 {
+		return "1/1"
+	}
+	return fmt.Sprintf("%d/%d", r.Attempt, r.MaxAttempts)
+}
+
+// Duration returns the elapsed time of the run, or the time since it started
+// for runs that are still in progress
+func (r CronRun) Duration() time.Duration {
+	end := time.Now().UTC()
+	if r.FinishedAt != nil {
+		end = *r.FinishedAt
+	}
+	if end.Before(r.StartedAt) {
+		return 0
+	}
+	return end.Sub(r.StartedAt).Round(time.Second)
+}
+
+func historyDirectory(appName string) string {
+	return filepath.Join(common.GetAppDataDirectory("cron", appName), "history")
+}
+
+func runPath(appName string, runID string) string {
+	return filepath.Join(historyDirectory(appName), runID+".json")
+}
+
+// generateRunID returns a sortable id for a cron run
+func generateRunID() string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		suffix = []byte(strconv.FormatInt(time.Now().UnixNano()%1000, 10))
+	}
+	return strconv.FormatInt(time.Now().UTC().UnixNano(), 36) + base36.EncodeToStringLc(suffix)
+}
+
+// WriteRun persists a cron run record
+func WriteRun(run CronRun) error {
+	if err := os.MkdirAll(historyDirectory(run.App), 0755); err != nil {
+		return fmt.Errorf("Unable to create cron history directory: %w", err)
+	}
+
+	body, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := runPath(run.App, run.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadRun loads a cron run record
+func ReadRun(appName string, runID string) (CronRun, error) {
+	var run CronRun
+	body, err := os.ReadFile(runPath(appName, runID))
+	if err != nil {
+		return run, err
+	}
+	if err := json.Unmarshal(body, &run); err != nil {
+		return run, fmt.Errorf("Unable to parse cron run %s: %w", runID, err)
+	}
+	return run, nil
+}
+
+// FetchRuns returns the cron runs for an app, newest first. When taskID is
+// not empty, only runs of that task are returned.
+func FetchRuns(appName string, taskID string) ([]CronRun, error) {
+	runs := []CronRun{}
+	entries, err := os.ReadDir(historyDirectory(appName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return runs, nil
+		}
+		return runs, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		run, err := ReadRun(appName, strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			common.LogWarn(err.Error())
+			continue
+		}
+		if taskID != "" && run.TaskID != taskID {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].ID > runs[j].ID
+	})
+	return runs, nil
+}
+
+// LastRuns returns the most recent run of each task of an app, keyed by task ID
+func LastRuns(appName string) map[string]CronRun {
+	last := map[string]CronRun{}
+	runs, err := FetchRuns(appName, "")
+	if err != nil {
+		return last
+	}
+	for _, run := range runs {
+		if _, ok := last[run.TaskID]; !ok {
+			last[run.TaskID] = run
+		}
+	}
+	return last
+}
+
+// startRun records the start of a cron task execution
+func startRun(task CronTask, detached bool, attempt int) (CronRun, error) {
+	run := CronRun{
+		ID:          generateRunID(),
+		App:         task.App,
+		TaskID:      task.ID,
+		Command:     task.Command,
+		StartedAt:   time.Now().UTC(),
+		Detached:    detached,
+		Attempt:     attempt,
+		MaxAttempts: task.Retries + 1,
+		Status:      CronRunStatusRunning,
+	}
+	return run, WriteRun(run)
+}
+
+// finishRun records the outcome of a cron task execution and prunes the app's
+// history to the configured retention
+func finishRun(appName string, runID string, exitCode int) (CronRun, error) {
+	run, err := ReadRun(appName, runID)
+	if err != nil {
+		return run, err
+	}
+
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.ExitCode = &exitCode
+	switch {
+	case run.Skipped:
+		run.Status = CronRunStatusSkipped
+	case exitCode != 0:
+		run.Status = CronRunStatusFailed
+	case run.Detached:
+		run.Status = CronRunStatusDetached
+	default:
+		run.Status = CronRunStatusSucceeded
+	}
+	if err := WriteRun(run); err != nil {
+		return run, err
+	}
+
+	return run, PruneRuns(appName)
+}
+
+// PruneRuns removes the oldest finished runs beyond the app's history retention
+func PruneRuns(appName string) error {
+	retention := ResolveHistoryRetention(appName)
+	runs, err := FetchRuns(appName, "")
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, run := range runs {
+		if run.Status == CronRunStatusRunning {
+			continue
+		}
+		kept++
+		if kept <= retention {
+			continue
+		}
+		if err := os.Remove(runPath(appName, run.ID)); err != nil && !os.IsNotExist(err) {
+			common.LogWarn(fmt.Sprintf("Unable to remove cron run %s: %s", run.ID, err.Error()))
+		}
+	}
+	return nil
+}
+
+// ResolveHistoryRetention returns the number of finished runs kept for an app
+func ResolveHistoryRetention(appName string) int {
+	value := common.PropertyGet("cron", appName, "history-retention")
+	if value == "" {
+		value = common.PropertyGetDefault("cron", "--global", "history-retention", DefaultProperties["history-retention"])
+	}
+
+	retention, err := strconv.Atoi(value)
+	if err != nil || retention < 1 {
+		common.LogWarn(fmt.Sprintf("Invalid cron history-retention %q, using %d", value, DefaultHistoryRetention))
+		return DefaultHistoryRetention
+	}
+	return retention
+}
+
+// updateRun applies fn to the run in DOKKU_CRON_RUN_ID. Recording history
+// must never fail a task, so errors are only logged.
+func updateRun(appName string, runID string, fn func(run *CronRun)) {
+	if appName == "" || runID == "" {
+		return
+	}
+
+	run, err := ReadRun(appName, runID)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to update cron run %s: %s", runID, err.Error()))
+		return
+	}
+
+	fn(&run)
+	if err := WriteRun(run); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to update cron run %s: %s", runID, err.Error()))
+	}
+}
+
+// RecordRunContainer records the container or pod a cron run executes in
+func RecordRunContainer(appName string, runID string, container string) {
+	updateRun(appName, runID, func(run *CronRun) {
+		run.Container = container
+	})
+}
+
+// RecordRunSkipped records that the concurrency policy prevented a cron run
+// from starting
+func RecordRunSkipped(appName string, runID string) {
+	updateRun(appName, runID, func(run *CronRun) {
+		run.Skipped = true
+	})
+}
@@ -0,0 +1,222 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/ryanuber/columnize"
+)
+
+// CronTaskFireTimes is a cron task along with its upcoming fire times
+type CronTaskFireTimes struct {
+	CronTask
+
+	// NextRuns are the upcoming fire times of the task. It is empty when the
+	// task is suspended.
+	NextRuns []time.Time `json:"next_runs"`
+}
+
+// scheduleLocation returns the location a task's schedule is evaluated in.
+// Tasks without a timezone fall back to defaultLocation.
+func scheduleLocation(task CronTask, defaultLocation *time.Location) (*time.Location, error) {
+	if task.Timezone == "" {
+		return defaultLocation, nil
+	}
+
+	if err := ValidateTimezone(task.Timezone); err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(task.Timezone)
+}
+
+// NextFireTimes returns the next count fire times of a schedule after from,
+// evaluated in the given location
+func NextFireTimes(schedule string, location *time.Location, from time.Time, count int) ([]time.Time, error) {
+	parsed, err := scheduleParser.Parse(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	times := []time.Time{}
+	next := from.In(location)
+	for i := 0; i < count; i++ {
+		next = parsed.Next(next)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+	}
+	return times, nil
+}
+
+// appScheduleLocation returns the location an app's tasks are evaluated in
+// when they do not specify a timezone. The host crontab runs in the server's
+// timezone, while other schedulers - such as k3s - default to UTC.
+func appScheduleLocation(appName string) *time.Location {
+	if usesHostCron(common.GetAppScheduler(appName)) {
+		return time.Local
+	}
+	return time.UTC
+}
+
+// fireTimesForTasks computes the upcoming fire times for each task. Suspended
+// tasks are included without fire times.
+func fireTimesForTasks(tasks []CronTask, defaultLocation *time.Location, from time.Time, count int) []CronTaskFireTimes {
+	results := []CronTaskFireTimes{}
+	for _, task := range tasks {
+		result := CronTaskFireTimes{CronTask: task, NextRuns: []time.Time{}}
+		if !task.Maintenance {
+			location, err := scheduleLocation(task, defaultLocation)
+			if err != nil {
+				common.LogWarn(fmt.Sprintf("Invalid cron timezone for task %s: %s", task.ID, err.Error()))
+				continue
+			}
+
+			times, err := NextFireTimes(task.Schedule, location, from, count)
+			if err != nil {
+				common.LogWarn(fmt.Sprintf("Invalid cron schedule for task %s (schedule %s): %s", task.ID, task.Schedule, err.Error()))
+				continue
+			}
+			result.NextRuns = times
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func suspendedLabel(task CronTask) string {
+	if task.TaskInMaintenance {
+		return "suspended (task)"
+	}
+	if task.AppInMaintenance {
+		return "suspended (app)"
+	}
+	return "suspended"
+}
+
+// CommandListNext lists the next count fire times of each of an app's cron tasks
+func CommandListNext(appName string, format string, count int) error {
+	if count < 1 {
+		return fmt.Errorf("--next must be a positive integer")
+	}
+
+	if appName == "--global" {
+		return commandListNextGlobal(format, count)
+	}
+
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	tasks, err := FetchCronTasks(FetchCronTasksInput{AppName: appName})
+	if err != nil {
+		return err
+	}
+
+	results := fireTimesForTasks(tasks, appScheduleLocation(appName), time.Now(), count)
+	if format == "json" {
+		out, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+		common.Log(string(out))
+		return nil
+	}
+
+	output := []string{"ID | Schedule | Timezone | Next Run | Command"}
+	for _, result := range results {
+		timezone := result.Timezone
+		if timezone == "" {
+			timezone = "server"
+		}
+
+		if result.Maintenance {
+			output = append(output, fmt.Sprintf("%s | %s | %s | %s | %s", result.ID, result.Schedule, timezone, suspendedLabel(result.CronTask), result.Command))
+			continue
+		}
+		for _, next := range result.NextRuns {
+			output = append(output, fmt.Sprintf("%s | %s | %s | %s | %s", result.ID, result.Schedule, timezone, next.Format(time.RFC3339), result.Command))
+		}
+	}
+
+	fmt.Println(columnize.SimpleFormat(output))
+	return nil
+}
+
+// commandListNextGlobal lists the upcoming fire times of the cron tasks of
+// every app along with injected global tasks, in chronological order. Tasks
+// that fire at the same time are counted so that schedules that pile up on
+// the same minute stand out.
+func commandListNextGlobal(format string, count int) error {
+	now := time.Now()
+	results := []CronTaskFireTimes{}
+
+	apps, err := common.DokkuApps()
+	if err != nil && !errors.Is(err, common.NoAppsExist) {
+		return err
+	}
+	for _, appName := range apps {
+		tasks, err := FetchCronTasks(FetchCronTasksInput{AppName: appName})
+		if err != nil {
+			common.LogWarn(err.Error())
+			continue
+		}
+		results = append(results, fireTimesForTasks(tasks, appScheduleLocation(appName), now, count)...)
+	}
+
+	globalTasks, err := FetchGlobalCronTasks()
+	if err != nil {
+		return err
+	}
+	results = append(results, fireTimesForTasks(globalTasks, time.Local, now, count)...)
+
+	if format == "json" {
+		out, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+		common.Log(string(out))
+		return nil
+	}
+
+	type fireTime struct {
+		at   time.Time
+		task CronTask
+	}
+	fireTimes := []fireTime{}
+	suspended := []CronTask{}
+	concurrent := map[int64]int{}
+	for _, result := range results {
+		if result.Maintenance {
+			suspended = append(suspended, result.CronTask)
+			continue
+		}
+		for _, next := range result.NextRuns {
+			fireTimes = append(fireTimes, fireTime{at: next, task: result.CronTask})
+			concurrent[next.Unix()]++
+		}
+	}
+	sort.SliceStable(fireTimes, func(i, j int) bool {
+		return fireTimes[i].at.Before(fireTimes[j].at)
+	})
+
+	output := []string{"Next Run | Concurrent | App | ID | Schedule | Command"}
+	for _, f := range fireTimes {
+		app := f.task.App
+		if f.task.Global {
+			app = "--global"
+		}
+		output = append(output, fmt.Sprintf("%s | %d | %s | %s | %s | %s", f.at.In(time.Local).Format(time.RFC3339), concurrent[f.at.Unix()], app, f.task.ID, f.task.Schedule, f.task.Command))
+	}
+	for _, task := range suspended {
+		output = append(output, fmt.Sprintf("%s | - | %s | %s | %s | %s", suspendedLabel(task), task.App, task.ID, task.Schedule, task.Command))
+	}
+
+	fmt.Println(columnize.SimpleFormat(output))
+	return nil
+}
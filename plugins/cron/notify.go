@@ -0,0 +1,226 @@
+package cron
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+const (
+	// NotifyOnAlways sends a notification after every run
+	NotifyOnAlways = "always"
+
+	// NotifyOnFailure sends a notification after runs that exit non-zero
+	NotifyOnFailure = "failure"
+
+	// NotifyOnNever disables notifications
+	NotifyOnNever = "never"
+
+	// NotificationEvent is the event name sent with cron notifications
+	NotificationEvent = "cron.finished"
+
+	// NotificationOutputTailLines is the number of trailing output lines
+	// included in a notification
+	NotificationOutputTailLines = 50
+)
+
+// NotificationTimeout is how long a notification webhook may take to respond
+var NotificationTimeout = 10 * time.Second
+
+// TaskPropertyPrefixes are the prefixes of cron properties that may be set
+// for an individual task, keyed by the property name
+var TaskPropertyPrefixes = map[string]string{
+	"maintenance": MaintenancePropertyPrefix,
+	"notify-on":   "notify-on.",
+	"webhook-url": "webhook-url.",
+}
+
+// CronNotification is the payload posted to a cron task's webhook-url
+type CronNotification struct {
+	// Event is the notification event name
+	Event string `json:"event"`
+
+	// App is the app the cron task belongs to
+	App string `json:"app"`
+
+	// TaskID is the ID of the cron task
+	TaskID string `json:"task_id"`
+
+	// Command is the command the task ran
+	Command string `json:"command"`
+
+	// Schedule is the cron schedule of the task
+	Schedule string `json:"schedule"`
+
+	// RunID is the ID of the run in the app's cron history
+	RunID string `json:"run_id,omitempty"`
+
+	// Status is the outcome of the run
+	Status CronRunStatus `json:"status"`
+
+	// ExitCode is the exit code of the task
+	ExitCode int `json:"exit_code"`
+
+	// StartedAt is when the run started
+	StartedAt time.Time `json:"started_at"`
+
+	// FinishedAt is when the run finished
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Output is the tail of the task's output
+	Output string `json:"output"`
+}
+
+// ValidateNotifyOn returns an error if the value is not a valid notify-on setting
+func ValidateNotifyOn(value string) error {
+	switch value {
+	case NotifyOnAlways, NotifyOnFailure, NotifyOnNever:
+		return nil
+	}
+	return fmt.Errorf("Invalid notify-on %q: must be one of %s, %s, %s", value, NotifyOnAlways, NotifyOnFailure, NotifyOnNever)
+}
+
+// ValidateWebhookURL returns an error if the value is not an http or https url
+func ValidateWebhookURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Invalid webhook-url %q: must be an http or https url", value)
+	}
+	return nil
+}
+
+// taskProperty returns the property name used to set a property for a
+// single task
+func taskProperty(property string, taskID string) string {
+	return TaskPropertyPrefixes[property] + taskID
+}
+
+// parseTaskProperty splits a per-task property into the property name and
+// the task ID. ok is false when the property is not a per-task property.
+func parseTaskProperty(property string) (name string, taskID string, ok bool) {
+	for name, prefix := range TaskPropertyPrefixes {
+		if strings.HasPrefix(property, prefix) {
+			return name, strings.TrimPrefix(property, prefix), true
+		}
+	}
+	return "", "", false
+}
+
+// resolveTaskProperty returns a cron property for a task, cascading from the
+// task to the app, to the global value, and finally to the default
+func resolveTaskProperty(appName string, taskID string, property string) string {
+	if value := common.PropertyGet("cron", appName, taskProperty(property, taskID)); value != "" {
+		return value
+	}
+	if value := common.PropertyGet("cron", appName, property); value != "" {
+		return value
+	}
+	return common.PropertyGetDefault("cron", "--global", property, DefaultProperties[property])
+}
+
+// shouldNotify reports whether a run with the given exit code triggers a
+// notification under the notify-on setting
+func shouldNotify(notifyOn string, exitCode int) bool {
+	switch notifyOn {
+	case NotifyOnAlways:
+		return true
+	case NotifyOnFailure:
+		return exitCode != 0
+	}
+	return false
+}
+
+// outputTail returns the last lines of a run's output
+func outputTail(stdout string, stderr string, lines int) string {
+	output := strings.TrimRight(stdout, "\n")
+	if stderr = strings.TrimRight(stderr, "\n"); stderr != "" {
+		if output != "" {
+			output += "\n"
+		}
+		output += stderr
+	}
+
+	parts := strings.Split(output, "\n")
+	if len(parts) > lines {
+		parts = parts[len(parts)-lines:]
+	}
+	return strings.Join(parts, "\n")
+}
+
+// NotifyRun posts a notification about a finished run to the task's
+// webhook-url when its notify-on setting calls for one. Notifying must never
+// fail a task, so errors are only logged.
+func NotifyRun(task CronTask, run CronRun, exitCode int, stdout string, stderr string) {
+	notifyOn := resolveTaskProperty(task.App, task.ID, "notify-on")
+	if !shouldNotify(notifyOn, exitCode) {
+		return
+	}
+
+	webhookURL := resolveTaskProperty(task.App, task.ID, "webhook-url")
+	if webhookURL == "" {
+		return
+	}
+
+	// the run record may be incomplete if recording history failed
+	finishedAt := time.Now().UTC()
+	if run.FinishedAt != nil {
+		finishedAt = *run.FinishedAt
+	}
+	status := run.Status
+	if run.FinishedAt == nil {
+		status = CronRunStatusSucceeded
+		if exitCode != 0 {
+			status = CronRunStatusFailed
+		}
+	}
+
+	notification := CronNotification{
+		Event:      NotificationEvent,
+		App:        task.App,
+		TaskID:     task.ID,
+		Command:    task.Command,
+		Schedule:   task.Schedule,
+		RunID:      run.ID,
+		Status:     status,
+		ExitCode:   exitCode,
+		StartedAt:  run.StartedAt,
+		FinishedAt: finishedAt,
+		Output:     outputTail(stdout, stderr, NotificationOutputTailLines),
+	}
+	if err := postNotification(webhookURL, notification); err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to send cron notification for %s: %s", task.ID, err.Error()))
+	}
+}
+
+func postNotification(webhookURL string, notification CronNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Dokku-Webhook")
+	req.Header.Set("X-Dokku-Event", notification.Event)
+
+	client := &http.Client{Timeout: NotificationTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dokku/dokku/plugins/common"
 )
@@ -19,29 +20,51 @@ func ReportSingleApp(appName string, format string, infoFlag string) error {
 	var flags map[string]common.ReportFunc
 	if appName == "--global" {
 		flags = map[string]common.ReportFunc{
-			"--cron-computed-mailfrom":    reportComputedMailfrom,
-			"--cron-computed-mailto":      reportComputedMailto,
-			"--cron-computed-maintenance": reportComputedMaintenance,
-			"--cron-global-mailfrom":      reportGlobalMailfrom,
-			"--cron-global-mailto":        reportGlobalMailto,
-			"--cron-global-maintenance":   reportGlobalMaintenance,
+			"--cron-computed-mailfrom":        reportComputedMailfrom,
+			"--cron-computed-mailto":          reportComputedMailto,
+			"--cron-computed-maintenance":     reportComputedMaintenance,
+			"--cron-global-mailfrom":          reportGlobalMailfrom,
+			"--cron-global-mailto":            reportGlobalMailto,
+			"--cron-global-maintenance":       reportGlobalMaintenance,
+			"--cron-global-history-retention": reportGlobalHistoryRetention,
+			"--cron-global-notify-on":         reportGlobalNotifyOn,
+			"--cron-computed-notify-on":       reportComputedNotifyOn,
+			"--cron-global-webhook-url":       reportGlobalWebhookURL,
+			"--cron-computed-webhook-url":     reportComputedWebhookURL,
 		}
 	} else {
 		flags = map[string]common.ReportFunc{
-			"--cron-computed-mailfrom":    reportComputedMailfrom,
-			"--cron-computed-mailto":      reportComputedMailto,
-			"--cron-computed-maintenance": reportComputedMaintenance,
-			"--cron-global-mailfrom":      reportGlobalMailfrom,
-			"--cron-global-mailto":        reportGlobalMailto,
-			"--cron-global-maintenance":   reportGlobalMaintenance,
-			"--cron-maintenance":          reportMaintenance,
-			"--cron-task-count":           reportTasks,
+			"--cron-computed-mailfrom":          reportComputedMailfrom,
+			"--cron-computed-mailto":            reportComputedMailto,
+			"--cron-computed-maintenance":       reportComputedMaintenance,
+			"--cron-global-mailfrom":            reportGlobalMailfrom,
+			"--cron-global-mailto":              reportGlobalMailto,
+			"--cron-global-maintenance":         reportGlobalMaintenance,
+			"--cron-maintenance":                reportMaintenance,
+			"--cron-history-retention":          reportHistoryRetention,
+			"--cron-global-history-retention":   reportGlobalHistoryRetention,
+			"--cron-computed-history-retention": reportComputedHistoryRetention,
+			"--cron-notify-on":                  reportNotifyOn,
+			"--cron-global-notify-on":           reportGlobalNotifyOn,
+			"--cron-computed-notify-on":         reportComputedNotifyOn,
+			"--cron-webhook-url":                reportWebhookURL,
+			"--cron-global-webhook-url":         reportGlobalWebhookURL,
+			"--cron-computed-webhook-url":       reportComputedWebhookURL,
+			"--cron-task-count":                 reportTasks,
 		}
 
 		extraFlags := addCronMaintenanceFlags(appName, infoFlag)
 		for flag, fn := range extraFlags {
 			flags[flag] = fn
 		}
+
+		for flag, fn := range addCronTaskNotifyFlags(appName) {
+			flags[flag] = fn
+		}
+
+		for flag, fn := range addCronLastRunFlags(appName) {
+			flags[flag] = fn
+		}
 	}
 
 	flagKeys := []string{}
@@ -81,6 +104,59 @@ func addCronMaintenanceFlags(appName string, infoFlag string) map[string]common.
 	return flags
 }
 
+// addCronTaskNotifyFlags adds the notify-on and webhook-url values set for
+// individual tasks
+func addCronTaskNotifyFlags(appName string) map[string]common.ReportFunc {
+	flags := map[string]common.ReportFunc{}
+
+	for _, property := range []string{"notify-on", "webhook-url"} {
+		prefix := TaskPropertyPrefixes[property]
+		properties, err := common.PropertyGetAllByPrefix("cron", appName, prefix)
+		if err != nil {
+			continue
+		}
+
+		for key, value := range properties {
+			value := value
+			flags[fmt.Sprintf("--cron-%s-%s", property, strings.TrimPrefix(key, prefix))] = func(appName string) string {
+				return value
+			}
+		}
+	}
+
+	return flags
+}
+
+// addCronLastRunFlags adds the status and time of the most recent run of each
+// of the app's cron tasks
+func addCronLastRunFlags(appName string) map[string]common.ReportFunc {
+	flags := map[string]common.ReportFunc{}
+
+	tasks, err := FetchCronTasks(FetchCronTasksInput{AppName: appName})
+	if err != nil {
+		return flags
+	}
+
+	lastRuns := LastRuns(appName)
+	for _, task := range tasks {
+		run, ok := lastRuns[task.ID]
+		flags[fmt.Sprintf("--cron-last-status-%s", task.ID)] = func(appName string) string {
+			if !ok {
+				return ""
+			}
+			return string(run.Status)
+		}
+		flags[fmt.Sprintf("--cron-last-run-%s", task.ID)] = func(appName string) string {
+			if !ok {
+				return ""
+			}
+			return run.StartedAt.Format(time.RFC3339)
+		}
+	}
+
+	return flags
+}
+
 func reportGlobalMailfrom(_ string) string {
 	return common.PropertyGet("cron", "--global", "mailfrom")
 }
@@ -118,3 +194,45 @@ func reportComputedMaintenance(appName string) string {
 func reportMaintenance(appName string) string {
 	return common.PropertyGet("cron", appName, "maintenance")
 }
+
+func reportHistoryRetention(appName string) string {
+	return common.PropertyGet("cron", appName, "history-retention")
+}
+
+func reportGlobalHistoryRetention(_ string) string {
+	return common.PropertyGet("cron", "--global", "history-retention")
+}
+
+func reportComputedHistoryRetention(appName string) string {
+	return strconv.Itoa(ResolveHistoryRetention(appName))
+}
+
+func reportNotifyOn(appName string) string {
+	return common.PropertyGet("cron", appName, "notify-on")
+}
+
+func reportGlobalNotifyOn(_ string) string {
+	return common.PropertyGet("cron", "--global", "notify-on")
+}
+
+func reportComputedNotifyOn(appName string) string {
+	if value := common.PropertyGet("cron", appName, "notify-on"); value != "" && appName != "--global" {
+		return value
+	}
+	return common.PropertyGetDefault("cron", "--global", "notify-on", DefaultProperties["notify-on"])
+}
+
+func reportWebhookURL(appName string) string {
+	return common.PropertyGet("cron", appName, "webhook-url")
+}
+
+func reportGlobalWebhookURL(_ string) string {
+	return common.PropertyGet("cron", "--global", "webhook-url")
+}
+
+func reportComputedWebhookURL(appName string) string {
+	if value := common.PropertyGet("cron", appName, "webhook-url"); value != "" && appName != "--global" {
+		return value
+	}
+	return common.PropertyGetDefault("cron", "--global", "webhook-url", DefaultProperties["webhook-url"])
+}
@@ -1,6 +1,10 @@
 package cron
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
 
 func validateSetValue(appName string, key string, value string) error {
 	if key == "mailfrom" && appName != "--global" {
@@ -11,5 +15,28 @@ func validateSetValue(appName string, key string, value string) error {
 		return errors.New("Property cannot be specified on a per-app basis")
 	}
 
+	if name, _, ok := parseTaskProperty(key); ok {
+		key = name
+	}
+
+	if key == "notify-on" && value != "" {
+		if err := ValidateNotifyOn(value); err != nil {
+			return err
+		}
+	}
+
+	if key == "webhook-url" && value != "" {
+		if err := ValidateWebhookURL(value); err != nil {
+			return err
+		}
+	}
+
+	if key == "history-retention" && value != "" {
+		retention, err := strconv.Atoi(value)
+		if err != nil || retention < 1 {
+			return fmt.Errorf("Invalid history-retention %q: must be a positive integer", value)
+		}
+	}
+
 	return nil
 }
@@ -18,7 +18,10 @@ Manage scheduled cron tasks
 Additional commands:`
 
 	helpContent = `
-    cron:list <app> [--format json|stdout], List scheduled cron tasks for an app
+    cron:add <app> <schedule> <command> [--concurrency-policy allow|forbid|replace], Add a cron task to an app
+    cron:history <app> [<cron_id>] [--format json|stdout], List recorded runs of an app's cron tasks
+    cron:list <app> [--format json|stdout] [--next N], List scheduled cron tasks for an app
+    cron:remove <app> <cron_id>, Remove a cron task added via cron:add
     cron:report [<app>] [<flag>], Display report about an app
     cron:resume <app> <cron_id>, Resume a cron task
     cron:run <app> <cron_id> [--detach] [--ttl-seconds SECONDS], Run a cron task on the fly
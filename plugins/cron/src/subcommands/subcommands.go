@@ -18,16 +18,38 @@ func main() {
 
 	var err error
 	switch subcommand {
+	case "add":
+		args := flag.NewFlagSet("cron:add", flag.ExitOnError)
+		concurrencyPolicy := args.String("concurrency-policy", "allow", "--concurrency-policy: allow, forbid or replace")
+		args.Parse(os.Args[2:])
+		appName := args.Arg(0)
+		schedule := args.Arg(1)
+		command := strings.Join(args.Args()[min(2, args.NArg()):], " ")
+		err = cron.CommandAdd(appName, schedule, command, *concurrencyPolicy)
+	case "history":
+		args := flag.NewFlagSet("cron:history", flag.ExitOnError)
+		format := args.String("format", "stdout", "format: [ stdout | json ]")
+		args.Parse(os.Args[2:])
+		appName := args.Arg(0)
+		cronID := args.Arg(1)
+		err = cron.CommandHistory(appName, cronID, *format)
 	case "list":
 		args := flag.NewFlagSet("cron:list", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: set a global property")
 		format := args.String("format", "stdout", "format: [ stdout | json ]")
+		next := args.Int("next", 0, "--next: number of upcoming fire times to list for each task")
 		args.Parse(os.Args[2:])
 		appName := args.Arg(0)
 		if *global {
 			appName = "--global"
 		}
-		err = cron.CommandList(appName, *format)
+		err = cron.CommandList(appName, *format, *next)
+	case "remove":
+		args := flag.NewFlagSet("cron:remove", flag.ExitOnError)
+		args.Parse(os.Args[2:])
+		appName := args.Arg(0)
+		cronID := args.Arg(1)
+		err = cron.CommandRemove(appName, cronID)
 	case "report":
 		args := flag.NewFlagSet("cron:report", flag.ExitOnError)
 		format := args.String("format", "stdout", "format: [ stdout | json ]")
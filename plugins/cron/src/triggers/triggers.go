@@ -31,6 +31,12 @@ func main() {
 			property = flag.Arg(0)
 		}
 		err = cron.TriggerCronGetProperty(appName, property)
+	case "cron-run-record":
+		appName := flag.Arg(0)
+		runID := flag.Arg(1)
+		field := flag.Arg(2)
+		value := flag.Arg(3)
+		err = cron.TriggerCronRunRecord(appName, runID, field, value)
 	case "install":
 		err = cron.TriggerInstall()
 	case "post-app-clone-setup":
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/dokku/dokku/plugins/common"
 
@@ -14,8 +14,8 @@ import (
 	"mvdan.cc/sh/v3/shell"
 )
 
-// CommandList lists all scheduled cron tasks for a given app
-func CommandList(appName string, format string) error {
+// CommandHistory lists the recorded runs of an app's cron tasks
+func CommandHistory(appName string, cronID string, format string) error {
 	if format == "" {
 		format = "stdout"
 	}
@@ -24,6 +24,57 @@ func CommandList(appName string, format string) error {
 		return fmt.Errorf("Invalid format specified, supported formats: json, stdout")
 	}
 
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	runs, err := FetchRuns(appName, cronID)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		out, err := json.Marshal(runs)
+		if err != nil {
+			return err
+		}
+		common.Log(string(out))
+		return nil
+	}
+
+	output := []string{"Run ID | Cron ID | Status | Attempt | Exit Code | Started | Duration | Container"}
+	for _, run := range runs {
+		exitCode := "-"
+		if run.ExitCode != nil {
+			exitCode = strconv.Itoa(*run.ExitCode)
+		}
+		container := run.Container
+		if container == "" {
+			container = "-"
+		}
+		output = append(output, fmt.Sprintf("%s | %s | %s | %s | %s | %s | %s | %s", run.ID, run.TaskID, run.Status, run.AttemptString(), exitCode, run.StartedAt.Format(time.RFC3339), run.Duration(), container))
+	}
+
+	result := columnize.SimpleFormat(output)
+	fmt.Println(result)
+	return nil
+}
+
+// CommandList lists all scheduled cron tasks for a given app. When next is
+// set, the upcoming fire times of each task are listed instead.
+func CommandList(appName string, format string, next int) error {
+	if format == "" {
+		format = "stdout"
+	}
+
+	if format != "stdout" && format != "json" {
+		return fmt.Errorf("Invalid format specified, supported formats: json, stdout")
+	}
+
+	if next != 0 {
+		return CommandListNext(appName, format, next)
+	}
+
 	var tasks []CronTask
 	if appName == "--global" {
 		var err error
@@ -43,7 +94,7 @@ func CommandList(appName string, format string) error {
 	}
 
 	if format == "stdout" {
-		output := []string{"ID | Schedule | Concurrency | Maintenance | Command"}
+		output := []string{"ID | Source | Schedule | Timezone | Concurrency | Maintenance | Command"}
 		for _, task := range tasks {
 			maintenance := "false"
 			if task.Maintenance {
@@ -53,7 +104,11 @@ func CommandList(appName string, format string) error {
 					maintenance = "true (app)"
 				}
 			}
-			output = append(output, fmt.Sprintf("%s | %s | %s | %s | %s", task.ID, task.Schedule, task.ConcurrencyPolicy, maintenance, task.Command))
+			timezone := task.Timezone
+			if timezone == "" {
+				timezone = "server"
+			}
+			output = append(output, fmt.Sprintf("%s | %s | %s | %s | %s | %s | %s", task.ID, task.Source, task.Schedule, timezone, task.ConcurrencyPolicy, maintenance, task.Command))
 		}
 
 		result := columnize.SimpleFormat(output)
@@ -116,15 +171,19 @@ func CommandRun(appName string, cronID string, detached bool, ttlSeconds int64)
 		return fmt.Errorf("Please specify a Cron ID from the output of 'dokku cron:list %s'", appName)
 	}
 
-	command := ""
-	concurrencyPolicy := "allow"
-	for _, task := range tasks {
-		if task.ID == cronID {
-			command = task.Command
-			concurrencyPolicy = task.ConcurrencyPolicy
+	var task CronTask
+	for _, t := range tasks {
+		if t.ID == cronID {
+			task = t
 		}
 	}
 
+	command := task.Command
+	concurrencyPolicy := "allow"
+	if task.ConcurrencyPolicy != "" {
+		concurrencyPolicy = task.ConcurrencyPolicy
+	}
+
 	if command == "" {
 		return fmt.Errorf("No matching Cron ID found. Please specify a Cron ID from the output of 'dokku cron:list %s'", appName)
 	}
@@ -145,13 +204,35 @@ func CommandRun(appName string, cronID string, detached bool, ttlSeconds int64)
 	os.Setenv("DOKKU_CRON_ID", cronID)
 	os.Setenv("DOKKU_RM_CONTAINER", "1")
 	os.Setenv("DOKKU_RUN_TTL_SECONDS", strconv.FormatInt(ttlSeconds, 10))
+
 	scheduler := common.GetAppScheduler(appName)
 	args := append([]string{scheduler, appName, "0", "--"}, fields...)
-	_, err = common.CallPlugnTrigger(common.PlugnTriggerInput{
-		Trigger:     "scheduler-run",
-		Args:        args,
-		StreamStdio: true,
-	})
+
+	maxAttempts := task.Retries + 1
+	var run CronRun
+	var result common.ExecCommandResponse
+	exitCode := 0
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			backoff := task.RetryBackoff(attempt - 1)
+			common.LogWarn(fmt.Sprintf("Cron task %s failed with exit code %d, retrying in %s (attempt %d/%d)", cronID, exitCode, backoff, attempt, maxAttempts))
+			time.Sleep(backoff)
+		}
+
+		run, result, err = runAttempt(task, detached, attempt, args)
+		exitCode = result.ExitCode
+		if err != nil && exitCode == 0 {
+			exitCode = 1
+		}
+
+		// detached runs report no exit code and skipped runs would be
+		// skipped again, so neither is retried
+		if exitCode == 0 || detached || run.Skipped {
+			break
+		}
+	}
+	NotifyRun(task, run, exitCode, result.Stdout, result.Stderr)
+
 	if err != nil {
 		// return an error with an empty message to avoid
 		// printing the error message twice
@@ -160,6 +241,39 @@ func CommandRun(appName string, cronID string, detached bool, ttlSeconds int64)
 	return err
 }
 
+// runAttempt runs a single attempt of a cron task via the scheduler and
+// records it in the app's cron history
+func runAttempt(task CronTask, detached bool, attempt int, args []string) (CronRun, common.ExecCommandResponse, error) {
+	run, historyErr := startRun(task, detached, attempt)
+	if historyErr != nil {
+		common.LogWarn(fmt.Sprintf("Unable to record cron run: %s", historyErr.Error()))
+		os.Unsetenv("DOKKU_CRON_RUN_ID")
+	} else {
+		os.Setenv("DOKKU_CRON_RUN_ID", run.ID)
+	}
+
+	result, err := common.CallPlugnTrigger(common.PlugnTriggerInput{
+		Trigger:     "scheduler-run",
+		Args:        args,
+		StreamStdio: true,
+	})
+
+	if historyErr == nil {
+		exitCode := result.ExitCode
+		if err != nil && exitCode == 0 {
+			exitCode = 1
+		}
+		finished, historyErr := finishRun(task.App, run.ID, exitCode)
+		if historyErr != nil {
+			common.LogWarn(fmt.Sprintf("Unable to record cron run: %s", historyErr.Error()))
+		} else {
+			run = finished
+		}
+	}
+
+	return run, result, err
+}
+
 // CommandSet set or clear a cron property for an app
 func CommandSet(appName string, property string, value string) error {
 	if err := validateSetValue(appName, property, value); err != nil {
@@ -168,14 +282,13 @@ func CommandSet(appName string, property string, value string) error {
 
 	validProperties := DefaultProperties
 	globalProperties := GlobalProperties
-	if strings.HasPrefix(property, MaintenancePropertyPrefix) {
+	if name, cronTaskID, ok := parseTaskProperty(property); ok {
 		if appName == "--global" {
-			return fmt.Errorf("Task maintenance properties cannot be set globally")
+			return fmt.Errorf("Task %s properties cannot be set globally", name)
 		}
 
-		cronTaskID := strings.TrimPrefix(property, MaintenancePropertyPrefix)
 		if cronTaskID == "" {
-			return fmt.Errorf("Invalid task maintenance property, missing ID")
+			return fmt.Errorf("Invalid task %s property, missing ID", name)
 		}
 
 		tasks, err := FetchCronTasks(FetchCronTasksInput{AppName: appName})
@@ -192,7 +305,7 @@ func CommandSet(appName string, property string, value string) error {
 		}
 
 		if _, ok := validProperties[property]; !ok {
-			return fmt.Errorf("Invalid task maintenance property, no matching task ID found: %s", property)
+			return fmt.Errorf("Invalid task %s property, no matching task ID found: %s", name, property)
 		}
 	}
 
@@ -0,0 +1,204 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	appjson "github.com/dokku/dokku/plugins/app-json"
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/multiformats/go-base36"
+)
+
+const (
+	// CronTaskSourceAppJSON marks tasks defined in the app's app.json
+	CronTaskSourceAppJSON = "app.json"
+
+	// CronTaskSourceCLI marks tasks added via cron:add
+	CronTaskSourceCLI = "cli"
+
+	// CronTaskSourcePlugin marks tasks injected via the cron-entries trigger
+	CronTaskSourcePlugin = "plugin"
+
+	// cliTasksProperty is the map property holding the tasks added via cron:add,
+	// keyed by cron ID
+	cliTasksProperty = "tasks"
+)
+
+// cronTaskEntry is a cron task definition along with where it was defined
+type cronTaskEntry struct {
+	// Task is the cron task definition
+	Task appjson.CronTask
+
+	// Source is where the task was defined
+	Source string
+}
+
+// generateCLICommandID creates a unique ID for a task added via cron:add. The
+// ID differs from that of an app.json task with the same command and schedule
+// so that removing one never affects the other.
+func generateCLICommandID(appName string, c appjson.CronTask) string {
+	return base36.EncodeToStringLc([]byte(appName + "===" + c.Command + "===" + c.Schedule + "===" + CronTaskSourceCLI))
+}
+
+// fetchCLITasks returns the tasks added via cron:add for an app, keyed by
+// their cron ID. Keys are recomputed rather than read from the property, as
+// a cloned or renamed app inherits the property of the original app.
+func fetchCLITasks(appName string) (map[string]appjson.CronTask, error) {
+	tasks := map[string]appjson.CronTask{}
+	entries, err := common.PropertyMapGet("cron", appName, cliTasksProperty)
+	if err != nil {
+		return tasks, fmt.Errorf("Unable to read cron tasks added via cron:add: %w", err)
+	}
+
+	for key, value := range entries {
+		var task appjson.CronTask
+		if err := json.Unmarshal([]byte(value), &task); err != nil {
+			common.LogWarn(fmt.Sprintf("Invalid cron task %s for app %s: %s", key, appName, err.Error()))
+			continue
+		}
+		tasks[generateCLICommandID(appName, task)] = task
+	}
+
+	return tasks, nil
+}
+
+// sortedCLITasks returns the tasks added via cron:add for an app, sorted by
+// cron ID
+func sortedCLITasks(appName string) ([]appjson.CronTask, error) {
+	tasks, err := fetchCLITasks(appName)
+	if err != nil {
+		return []appjson.CronTask{}, err
+	}
+
+	ids := make([]string, 0, len(tasks))
+	for id := range tasks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	sorted := make([]appjson.CronTask, 0, len(ids))
+	for _, id := range ids {
+		sorted = append(sorted, tasks[id])
+	}
+	return sorted, nil
+}
+
+// writeCLITasks persists the tasks added via cron:add for an app
+func writeCLITasks(appName string, tasks map[string]appjson.CronTask) error {
+	entries := map[string]string{}
+	for id, task := range tasks {
+		body, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		entries[id] = string(body)
+	}
+
+	return common.PropertyMapWrite("cron", appName, cliTasksProperty, entries)
+}
+
+// CommandAdd adds a cron task to an app without changing its app.json
+func CommandAdd(appName string, schedule string, command string, concurrencyPolicy string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	if schedule == "" {
+		return fmt.Errorf("Please specify a schedule")
+	}
+
+	if command == "" {
+		return fmt.Errorf("Please specify a command")
+	}
+
+	task := appjson.CronTask{
+		Command:           command,
+		Schedule:          schedule,
+		ConcurrencyPolicy: concurrencyPolicy,
+	}
+
+	// validate the task the same way app.json tasks are validated
+	if _, err := FetchCronTasks(FetchCronTasksInput{
+		AppName:       appName,
+		AppJSON:       &appjson.AppJSON{Cron: []appjson.CronTask{task}},
+		WarnToFailure: true,
+	}); err != nil {
+		return err
+	}
+
+	cronID := generateCLICommandID(appName, task)
+	existing, err := fetchCLITasks(appName)
+	if err != nil {
+		return err
+	}
+	if _, ok := existing[cronID]; ok {
+		return fmt.Errorf("A cron task with the same schedule and command already exists: %s", cronID)
+	}
+
+	existing[cronID] = task
+	if err := writeCLITasks(appName, existing); err != nil {
+		return err
+	}
+
+	common.LogInfo1(fmt.Sprintf("Added cron task %s", cronID))
+	return writeAppCron(appName)
+}
+
+// CommandRemove removes a cron task added via cron:add
+func CommandRemove(appName string, cronID string) error {
+	if err := common.VerifyAppName(appName); err != nil {
+		return err
+	}
+
+	if cronID == "" {
+		return fmt.Errorf("Please specify a Cron ID from the output of 'dokku cron:list %s'", appName)
+	}
+
+	existing, err := fetchCLITasks(appName)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := existing[cronID]; !ok {
+		tasks, err := FetchCronTasks(FetchCronTasksInput{AppName: appName})
+		if err == nil {
+			for _, task := range tasks {
+				if task.ID == cronID {
+					return fmt.Errorf("Cron task %s is defined in %s and can only be removed by changing it", cronID, task.Source)
+				}
+			}
+		}
+		return fmt.Errorf("No matching Cron ID found. Please specify a Cron ID from the output of 'dokku cron:list %s'", appName)
+	}
+
+	delete(existing, cronID)
+	if err := writeCLITasks(appName, existing); err != nil {
+		return err
+	}
+
+	for _, prefix := range TaskPropertyPrefixes {
+		if err := common.PropertyDelete("cron", appName, prefix+cronID); err != nil {
+			common.LogWarn(fmt.Sprintf("Unable to remove property %s%s: %s", prefix, cronID, err.Error()))
+		}
+	}
+
+	common.LogInfo1(fmt.Sprintf("Removed cron task %s", cronID))
+	return writeAppCron(appName)
+}
+
+// writeAppCron applies an app's current cron tasks to its scheduler
+func writeAppCron(appName string) error {
+	scheduler := common.GetAppScheduler(appName)
+	if !usesHostCron(scheduler) {
+		common.LogWarn("The change will take effect on the next deploy of the app")
+	}
+
+	_, err := common.CallPlugnTrigger(common.PlugnTriggerInput{
+		Trigger:     "scheduler-cron-write",
+		Args:        []string{scheduler, appName},
+		StreamStdio: true,
+	})
+	return err
+}
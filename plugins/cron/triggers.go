@@ -35,7 +35,7 @@ func TriggerAppJSONIsValid(appName string, appJSONPath string) error {
 		return err
 	}
 
-	_, err = FetchCronTasks(FetchCronTasksInput{
+	tasks, err := FetchCronTasks(FetchCronTasksInput{
 		AppName:       appName,
 		AppJSON:       &appJSON,
 		WarnToFailure: true,
@@ -44,6 +44,32 @@ func TriggerAppJSONIsValid(appName string, appJSONPath string) error {
 		return err
 	}
 
+	timezoned := timezoneCronTasks(tasks)
+	if len(timezoned) == 0 || !usesHostCron(common.GetAppScheduler(appName)) {
+		return nil
+	}
+
+	if !hostCronSupportsTimezones() {
+		return fmt.Errorf("Cron task %s sets a timezone of %s, but the host cron daemon does not support CRON_TZ and would run it in the server's timezone. Install a cron daemon that supports CRON_TZ - such as cronie - or remove the timezone", timezoned[0].ID, timezoned[0].Timezone)
+	}
+
+	return nil
+}
+
+// TriggerCronRunRecord records details reported by the scheduler onto the
+// in-flight cron run
+func TriggerCronRunRecord(appName string, runID string, field string, value string) error {
+	switch field {
+	case "container":
+		RecordRunContainer(appName, runID, value)
+	case "skipped":
+		if common.ToBool(value) {
+			RecordRunSkipped(appName, runID)
+		}
+	default:
+		return fmt.Errorf("Invalid cron run field specified: %s", field)
+	}
+
 	return nil
 }
 
@@ -72,6 +98,10 @@ func TriggerPostAppRenameSetup(oldAppName string, newAppName string) error {
 		return err
 	}
 
+	if err := common.MigrateAppDataDirectory("cron", oldAppName, newAppName); err != nil {
+		return fmt.Errorf("Unable to move cron history: %w", err)
+	}
+
 	if err := common.PropertyDestroy("cron", oldAppName); err != nil {
 		return err
 	}
@@ -95,6 +125,10 @@ func TriggerPostDelete(appName string) error {
 		return err
 	}
 
+	if err := common.RemoveAppDataDirectory("cron", appName); err != nil {
+		return fmt.Errorf("Unable to remove cron history: %w", err)
+	}
+
 	return nil
 }
 
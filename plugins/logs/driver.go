@@ -0,0 +1,102 @@
+package logs
+
+import (
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	dockeroptions "github.com/dokku/dokku/plugins/docker-options"
+)
+
+// LogDriverSource describes where the log driver of an app's containers comes from
+type LogDriverSource string
+
+const (
+	// LogDriverSourceDaemon is used when the docker daemon's default log driver applies
+	LogDriverSourceDaemon LogDriverSource = "daemon"
+
+	// LogDriverSourceDockerOptions is used when a --log-driver docker option is set for the deploy phase
+	LogDriverSourceDockerOptions LogDriverSource = "docker-options"
+
+	// LogDriverSourceProperty is used when the log-driver property is set for the app or globally
+	LogDriverSourceProperty LogDriverSource = "property"
+)
+
+// SupportedLogDrivers are the values accepted by the log-driver property
+var SupportedLogDrivers = []string{"journald", "json-file", "local"}
+
+// rotatingLogDrivers are the log drivers that support the max-size, max-file
+// and compress log options
+var rotatingLogDrivers = map[string]bool{
+	"json-file": true,
+	"local":     true,
+}
+
+// getComputedProperty returns the app value of a logs property, falling back
+// to the global value and then to the property default
+func getComputedProperty(appName string, property string) string {
+	value := common.PropertyGet("logs", appName, property)
+	if value == "" {
+		value = common.PropertyGetDefault("logs", "--global", property, DefaultProperties[property])
+	}
+	return value
+}
+
+// resolveLogDriver returns the log driver an app's containers are deployed with
+// along with where it was configured. A --log-driver docker option takes
+// precedence over the log-driver property, which in turn takes precedence over
+// the docker daemon's default log driver.
+func resolveLogDriver(appName string) (string, LogDriverSource, error) {
+	options, err := dockeroptions.GetDockerOptionsForPhase(appName, "deploy")
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, option := range options {
+		if logDriver, ok := strings.CutPrefix(option, "--log-driver="); ok {
+			return logDriver, LogDriverSourceDockerOptions, nil
+		}
+	}
+
+	if logDriver := getComputedProperty(appName, "log-driver"); logDriver != "" {
+		return logDriver, LogDriverSourceProperty, nil
+	}
+
+	return getDaemonLogDriver(), LogDriverSourceDaemon, nil
+}
+
+// getDaemonLogDriver returns the default log driver of the docker daemon
+func getDaemonLogDriver() string {
+	result, _ := common.CallExecCommand(common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    []string{"system", "info", "--format", "{{ .LoggingDriver }}"},
+	})
+	return result.StdoutContents()
+}
+
+// logDriverArgs returns the docker args injected for an app's log driver
+// configuration. The rotation options are only emitted for log drivers that
+// support them.
+func logDriverArgs(appName string, logDriver string, source LogDriverSource) []string {
+	args := []string{}
+	if source == LogDriverSourceProperty {
+		args = append(args, "--log-driver="+logDriver)
+	}
+
+	if !rotatingLogDrivers[logDriver] {
+		return args
+	}
+
+	if maxSize := getComputedProperty(appName, "max-size"); maxSize != "unlimited" {
+		args = append(args, "--log-opt=max-size="+maxSize)
+	}
+
+	if maxFile := getComputedProperty(appName, "max-file"); maxFile != "" {
+		args = append(args, "--log-opt=max-file="+maxFile)
+	}
+
+	if compress := getComputedProperty(appName, "compress"); compress != "" {
+		args = append(args, "--log-opt=compress="+compress)
+	}
+
+	return args
+}
@@ -0,0 +1,82 @@
+package logs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+func TestLogDriverArgs(t *testing.T) {
+	setupScopesTest(t, []string{"myapp"})
+
+	writeProperty := func(appName string, property string, value string) {
+		t.Helper()
+		if err := common.PropertyWrite("logs", appName, property, value); err != nil {
+			t.Fatalf("PropertyWrite %s %s: %v", appName, property, err)
+		}
+	}
+
+	got := logDriverArgs("myapp", "json-file", LogDriverSourceDaemon)
+	want := []string{"--log-opt=max-size=10m"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default args = %v, want %v", got, want)
+	}
+
+	writeProperty("--global", "max-file", "3")
+	writeProperty("myapp", "max-file", "5")
+	writeProperty("myapp", "compress", "true")
+	got = logDriverArgs("myapp", "local", LogDriverSourceProperty)
+	want = []string{"--log-driver=local", "--log-opt=max-size=10m", "--log-opt=max-file=5", "--log-opt=compress=true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("property args = %v, want %v", got, want)
+	}
+
+	// the docker option already selects the driver, so it is not repeated
+	writeProperty("myapp", "max-size", "unlimited")
+	got = logDriverArgs("myapp", "json-file", LogDriverSourceDockerOptions)
+	want = []string{"--log-opt=max-file=5", "--log-opt=compress=true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("docker-options args = %v, want %v", got, want)
+	}
+
+	// journald does not support the rotation options
+	got = logDriverArgs("myapp", "journald", LogDriverSourceProperty)
+	want = []string{"--log-driver=journald"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("journald args = %v, want %v", got, want)
+	}
+
+	got = logDriverArgs("myapp", "syslog", LogDriverSourceDaemon)
+	if len(got) != 0 {
+		t.Errorf("syslog args = %v, want none", got)
+	}
+}
+
+func TestValidateLogDriverSettings(t *testing.T) {
+	valid := map[string][]string{
+		"compress":   {"", "true", "false"},
+		"log-driver": {"", "journald", "json-file", "local"},
+		"max-file":   {"", "1", "10"},
+	}
+	for key, values := range valid {
+		for _, value := range values {
+			if err := validateSetValue("myapp", key, value); err != nil {
+				t.Errorf("validateSetValue(%s, %q) error = %v", key, value, err)
+			}
+		}
+	}
+
+	invalid := map[string][]string{
+		"compress":   {"yes", "1"},
+		"log-driver": {"syslog", "none"},
+		"max-file":   {"0", "-1", "three"},
+	}
+	for key, values := range invalid {
+		for _, value := range values {
+			if err := validateSetValue("myapp", key, value); err == nil {
+				t.Errorf("validateSetValue(%s, %q) expected an error", key, value)
+			}
+		}
+	}
+}
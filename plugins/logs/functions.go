@@ -215,6 +215,9 @@ func stopVectorContainer() error {
 // vectorAppSinks holds the resolved sink configuration for a single app or for
 // the global scope
 type vectorAppSinks struct {
+	// AppName is the app the scope collects logs for, or --global
+	AppName string
+
 	// SourceID is the vector source component id
 	SourceID string
 
@@ -251,6 +254,38 @@ type vectorAppSinks struct {
 
 	// CronSink is the DSN for cron task logs, empty when unset
 	CronSink string
+
+	// ProcessSinks holds the DSN for the logs of individual process types,
+	// keyed by process type
+	ProcessSinks map[string]string
+}
+
+// processRouteName returns the route output carrying a process type's logs
+func processRouteName(processType string) string {
+	return fmt.Sprintf("process-%s", processType)
+}
+
+// processSinkID returns the component id for the sink receiving a process
+// type's logs
+func (scope vectorAppSinks) processSinkID(processType string) string {
+	return fmt.Sprintf("%s:%s", scope.SinkID, processType)
+}
+
+// processRelabelID returns the component id for the remap transform renaming
+// the app label on a process type's branch
+func (scope vectorAppSinks) processRelabelID(processType string) string {
+	return fmt.Sprintf("%s:%s", scope.RelabelID, processType)
+}
+
+// sortedProcessTypes returns the process types with a sink of their own, so
+// that the generated config is stable
+func (scope vectorAppSinks) sortedProcessTypes() []string {
+	processTypes := make([]string, 0, len(scope.ProcessSinks))
+	for processType := range scope.ProcessSinks {
+		processTypes = append(processTypes, processType)
+	}
+	sort.Strings(processTypes)
+	return processTypes
 }
 
 // vectorLabelAliasOverride is the alias a single app ships its name under when
@@ -263,42 +298,57 @@ type vectorLabelAliasOverride struct {
 	Alias string
 }
 
-// cronRouteTransforms returns the route and remap pair that splits a source
-// into cron and non-cron branches. The remap flattens the cron labels into
-// top-level fields because vector drops any event whose sink template
-// references a missing field, and a nested quoted path is awkward to template.
+// routeTransform returns the route splitting a source into a cron branch and
+// a branch per process type with a sink of its own. Everything else falls
+// through to the reserved _unmatched output.
 //
-// The route carries a single condition, so an event either matches it or falls
-// through to the reserved _unmatched output. A second route added later would
-// need a mutually exclusive condition, since route fans out to every match.
+// Route fans out to every matching route, so the conditions must be mutually
+// exclusive: process type routes never match cron task containers, which keep
+// going to the cron sink or - when there is none - to the plain sink.
+func routeTransform(scope vectorAppSinks) vectorRouteTransform {
+	route := map[string]vectorCondition{}
+	if scope.CronSink != "" {
+		route[CronRouteName] = vectorCondition{
+			Type:   "vrl",
+			Source: fmt.Sprintf("%s == %q", vrlLabelPath(ContainerTypeLabel), CronContainerType),
+		}
+	}
+
+	for _, processType := range scope.sortedProcessTypes() {
+		route[processRouteName(processType)] = vectorCondition{
+			Type: "vrl",
+			Source: fmt.Sprintf("%s == %q && %s != %q",
+				vrlLabelPath(ProcessTypeLabel), processType, vrlLabelPath(ContainerTypeLabel), CronContainerType),
+		}
+	}
+
+	return vectorRouteTransform{
+		Type:             "route",
+		Inputs:           []string{scope.SourceID},
+		RerouteUnmatched: scope.Sink != "",
+		Route:            route,
+	}
+}
+
+// cronRemapTransform returns the remap on the cron branch. The remap flattens
+// the cron labels into top-level fields because vector drops any event whose
+// sink template references a missing field, and a nested quoted path is
+// awkward to template.
 //
 // Any label rename is appended to the remap rather than given a component of
 // its own, so that dokku_app is captured from the literal label before the
 // rename runs and keeps its value regardless of the configured alias.
-func cronRouteTransforms(scope vectorAppSinks, relabel string) map[string]any {
+func cronRemapTransform(scope vectorAppSinks, relabel string) vectorRemapTransform {
 	source := fmt.Sprintf(".dokku_app = to_string(%s) ?? \"\"\n.dokku_cron_id = to_string(%s) ?? \"\"",
 		vrlLabelPath(AppLabelAlias), vrlLabelPath(CronIDLabel))
 	if relabel != "" {
 		source = fmt.Sprintf("%s\n%s", source, relabel)
 	}
 
-	return map[string]any{
-		scope.RouterID: vectorRouteTransform{
-			Type:             "route",
-			Inputs:           []string{scope.SourceID},
-			RerouteUnmatched: scope.Sink != "",
-			Route: map[string]vectorCondition{
-				CronRouteName: {
-					Type:   "vrl",
-					Source: fmt.Sprintf("%s == %q", vrlLabelPath(ContainerTypeLabel), CronContainerType),
-				},
-			},
-		},
-		scope.CronRemapID: vectorRemapTransform{
-			Type:   "remap",
-			Inputs: []string{fmt.Sprintf("%s.%s", scope.RouterID, CronRouteName)},
-			Source: source,
-		},
+	return vectorRemapTransform{
+		Type:   "remap",
+		Inputs: []string{fmt.Sprintf("%s.%s", scope.RouterID, CronRouteName)},
+		Source: source,
 	}
 }
 
@@ -403,7 +453,7 @@ func buildVectorConfig(scopes []vectorAppSinks) (vectorConfig, error) {
 	}
 
 	for _, scope := range scopes {
-		if scope.Sink == "" && scope.CronSink == "" {
+		if scope.Sink == "" && scope.CronSink == "" && len(scope.ProcessSinks) == 0 {
 			continue
 		}
 
@@ -415,15 +465,16 @@ func buildVectorConfig(scopes []vectorAppSinks) (vectorConfig, error) {
 		relabel := relabelVRL(scope)
 
 		sinkInputs := []string{scope.SourceID}
-		if scope.CronSink != "" {
+		if scope.CronSink != "" || len(scope.ProcessSinks) > 0 {
 			if data.Transforms == nil {
 				data.Transforms = map[string]any{}
 			}
-			for id, transform := range cronRouteTransforms(scope, relabel) {
-				data.Transforms[id] = transform
-			}
-
+			data.Transforms[scope.RouterID] = routeTransform(scope)
 			sinkInputs = []string{fmt.Sprintf("%s._unmatched", scope.RouterID)}
+		}
+
+		if scope.CronSink != "" {
+			data.Transforms[scope.CronRemapID] = cronRemapTransform(scope, relabel)
 
 			cronSink, err := SinkValueToConfig(SinkValueToConfigInput{
 				SinkValue: scope.CronSink,
@@ -436,6 +487,29 @@ func buildVectorConfig(scopes []vectorAppSinks) (vectorConfig, error) {
 			data.Sinks[scope.CronSinkID] = cronSink
 		}
 
+		for _, processType := range scope.sortedProcessTypes() {
+			processInputs := []string{fmt.Sprintf("%s.%s", scope.RouterID, processRouteName(processType))}
+			if relabel != "" {
+				data.Transforms[scope.processRelabelID(processType)] = vectorRemapTransform{
+					Type:   "remap",
+					Inputs: processInputs,
+					Source: relabel,
+				}
+
+				processInputs = []string{scope.processRelabelID(processType)}
+			}
+
+			processSink, err := SinkValueToConfig(SinkValueToConfigInput{
+				SinkValue: scope.ProcessSinks[processType],
+				Inputs:    processInputs,
+			})
+			if err != nil {
+				return data, err
+			}
+
+			data.Sinks[scope.processSinkID(processType)] = processSink
+		}
+
 		if scope.Sink != "" {
 			// the cron branch renames within its own remap, so this transform
 			// only exists when there is a non-cron sink downstream to consume it
@@ -506,6 +580,7 @@ func vectorScopes() []vectorAppSinks {
 		}
 
 		scopes = append(scopes, vectorAppSinks{
+			AppName:       appName,
 			SourceID:      fmt.Sprintf("docker-source:%s", inflectedAppName),
 			IncludeLabels: []string{fmt.Sprintf("%s=%s", AppLabelAlias, appName)},
 			SinkID:        fmt.Sprintf("docker-sink:%s", inflectedAppName),
@@ -516,6 +591,7 @@ func vectorScopes() []vectorAppSinks {
 			LabelAlias:    appAlias,
 			Sink:          common.PropertyGet("logs", appName, "vector-sink"),
 			CronSink:      common.PropertyGet("logs", appName, "vector-cron-sink"),
+			ProcessSinks:  processSinks(appName),
 		})
 	}
 
@@ -524,6 +600,7 @@ func vectorScopes() []vectorAppSinks {
 	})
 
 	return append(scopes, vectorAppSinks{
+		AppName:             "--global",
 		SourceID:            "docker-global-source",
 		IncludeLabels:       []string{AppLabelAlias},
 		SinkID:              "docker-global-sink",
@@ -535,9 +612,29 @@ func vectorScopes() []vectorAppSinks {
 		LabelAliasOverrides: overrides,
 		Sink:                common.PropertyGet("logs", "--global", "vector-sink"),
 		CronSink:            common.PropertyGet("logs", "--global", "vector-cron-sink"),
+		ProcessSinks:        processSinks("--global"),
 	})
 }
 
+// processSinks returns the sinks set for individual process types of an app,
+// or globally, keyed by process type
+func processSinks(appName string) map[string]string {
+	sinks := map[string]string{}
+	properties, err := common.PropertyGetAllByPrefix("logs", appName, ProcessSinkPropertyPrefix)
+	if err != nil {
+		common.LogWarn(fmt.Sprintf("Unable to read process type sinks for %s: %s", appName, err.Error()))
+		return sinks
+	}
+
+	for property, value := range properties {
+		if value == "" {
+			continue
+		}
+		sinks[strings.TrimPrefix(property, ProcessSinkPropertyPrefix)] = value
+	}
+	return sinks
+}
+
 // regenerateVectorConfig rewrites the generated vector config so that it matches
 // the current set of apps and their properties. App lifecycle triggers call this
 // instead of writeVectorConfig because the config is derived state: failing to
@@ -549,19 +646,29 @@ func regenerateVectorConfig() {
 	}
 }
 
-func writeVectorConfig() error {
-	data, err := buildVectorConfig(vectorScopes())
+// renderVectorConfig returns the vector config generated for the supplied
+// scopes, exactly as it is written to disk
+func renderVectorConfig(scopes []vectorAppSinks) ([]byte, error) {
+	data, err := buildVectorConfig(scopes)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	b, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	b = bytes.Replace(b, []byte("\\u0026"), []byte("&"), -1)
 	b = bytes.Replace(b, []byte("\\u002B"), []byte("+"), -1)
+	return b, nil
+}
+
+func writeVectorConfig() error {
+	b, err := renderVectorConfig(vectorScopes())
+	if err != nil {
+		return err
+	}
 
 	vectorConfig := filepath.Join(common.GetDataDirectory("logs"), "vector.json")
 	if err := common.WriteBytesToFile(common.WriteBytesToFileInput{
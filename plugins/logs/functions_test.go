@@ -279,6 +279,89 @@ func TestBuildVectorConfigAliasCronSinkOnly(t *testing.T) {
 	}
 }
 
+func TestBuildVectorConfigProcessSinks(t *testing.T) {
+	scope := appScope("console://?encoding[codec]=json", "console://?encoding[codec]=text")
+	scope.ProcessSinks = map[string]string{
+		"web":    "http://?uri=https://apm.example.com&encoding[codec]=json",
+		"worker": "blackhole://?print_interval_secs=1",
+	}
+	_, decoded := marshalConfig(t, []vectorAppSinks{scope})
+
+	condition := lookup(t, decoded, "transforms", "docker-router:myapp", "route", "process-worker", "source")
+	want := `.label."com.dokku.process-type" == "worker" && .label."com.dokku.container-type" != "cron"`
+	if condition != want {
+		t.Errorf("route condition = %v, want %v", condition, want)
+	}
+
+	// the cron route is untouched by the process type routes
+	lookup(t, decoded, "transforms", "docker-router:myapp", "route", "cron")
+
+	for processType, sinkType := range map[string]string{"web": "http", "worker": "blackhole"} {
+		sinkID := "docker-sink:myapp:" + processType
+		if got := lookup(t, decoded, "sinks", sinkID, "type"); got != sinkType {
+			t.Errorf("%s type = %v, want %s", sinkID, got, sinkType)
+		}
+
+		inputs := lookup(t, decoded, "sinks", sinkID, "inputs")
+		if got := inputs.([]interface{})[0]; got != "docker-router:myapp.process-"+processType {
+			t.Errorf("%s inputs[0] = %v, want docker-router:myapp.process-%s", sinkID, got, processType)
+		}
+	}
+
+	inputs := lookup(t, decoded, "sinks", "docker-sink:myapp", "inputs")
+	if got := inputs.([]interface{})[0]; got != "docker-router:myapp._unmatched" {
+		t.Errorf("plain sink inputs[0] = %v, want docker-router:myapp._unmatched", got)
+	}
+}
+
+func TestBuildVectorConfigProcessSinkOnly(t *testing.T) {
+	scope := appScope("", "")
+	scope.ProcessSinks = map[string]string{"worker": "blackhole://?print_interval_secs=1"}
+	_, decoded := marshalConfig(t, []vectorAppSinks{scope})
+
+	lookup(t, decoded, "sources", "docker-source:myapp")
+
+	if got := lookup(t, decoded, "transforms", "docker-router:myapp", "reroute_unmatched"); got != false {
+		t.Errorf("reroute_unmatched = %v, want false", got)
+	}
+
+	routes := lookup(t, decoded, "transforms", "docker-router:myapp", "route").(map[string]interface{})
+	if _, ok := routes["cron"]; ok {
+		t.Error("cron route should not exist without a cron sink")
+	}
+
+	transforms := lookup(t, decoded, "transforms").(map[string]interface{})
+	if _, ok := transforms["docker-cron-remap:myapp"]; ok {
+		t.Error("cron remap should not exist without a cron sink")
+	}
+}
+
+func TestBuildVectorConfigProcessSinkRelabel(t *testing.T) {
+	scope := aliasScope("", "", "app_name")
+	scope.ProcessSinks = map[string]string{"web": "blackhole://?print_interval_secs=1"}
+	_, decoded := marshalConfig(t, []vectorAppSinks{scope})
+
+	source := lookup(t, decoded, "transforms", "docker-relabel:myapp:web", "source")
+	if source != `.label."app_name" = del(.label."com.dokku.app-name")` {
+		t.Errorf("process relabel source = %v", source)
+	}
+
+	inputs := lookup(t, decoded, "sinks", "docker-sink:myapp:web", "inputs")
+	if got := inputs.([]interface{})[0]; got != "docker-relabel:myapp:web" {
+		t.Errorf("process sink inputs[0] = %v, want docker-relabel:myapp:web", got)
+	}
+}
+
+func TestVectorScopesProcessSinks(t *testing.T) {
+	setupScopesTest(t, []string{"myapp"})
+	setLogsProperty(t, "myapp", "vector-sink.worker", "blackhole://?print_interval_secs=1")
+	setLogsProperty(t, "--global", "vector-sink.web", "console://?encoding[codec]=json")
+
+	decoded := readVectorConfig(t)
+	lookup(t, decoded, "sinks", "docker-sink:myapp:worker")
+	lookup(t, decoded, "sinks", "docker-global-sink:web")
+}
+
 func setupScopesTest(t *testing.T, apps []string) {
 	t.Helper()
 	t.Setenv("PLUGIN_PATH", "/var/lib/dokku/plugins")
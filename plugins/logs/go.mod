@@ -3,6 +3,7 @@ module github.com/dokku/dokku/plugins/logs
 go 1.26.2
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/dokku/dokku/plugins/common v0.0.0-00010101000000-000000000000
 	github.com/dokku/dokku/plugins/docker-options v0.0.0-00010101000000-000000000000
 	github.com/fastfishio/qson v1.0.2
@@ -34,10 +34,20 @@ const CronIDLabel = "com.dokku.cron-id"
 // CronRouteName is the vector route output carrying cron task logs
 const CronRouteName = "cron"
 
+// ProcessTypeLabel is the docker label holding the process type of a dokku container
+const ProcessTypeLabel = "com.dokku.process-type"
+
+// ProcessSinkPropertyPrefix is the prefix of the properties holding the sink
+// for a single process type, e.g. vector-sink.worker
+const ProcessSinkPropertyPrefix = "vector-sink."
+
 var (
 	// DefaultProperties is a map of all valid logs properties with corresponding default property values
 	DefaultProperties = map[string]string{
 		"app-label-alias":  AppLabelAlias,
+		"compress":         "",
+		"log-driver":       "",
+		"max-file":         "",
 		"max-size":         MaxSize,
 		"vector-cron-sink": "",
 		"vector-sink":      "",
@@ -46,6 +56,9 @@ var (
 	// GlobalProperties is a map of all valid global logs properties
 	GlobalProperties = map[string]bool{
 		"app-label-alias":  true,
+		"compress":         true,
+		"log-driver":       true,
+		"max-file":         true,
 		"max-size":         true,
 		"vector-cron-sink": true,
 		"vector-image":     true,
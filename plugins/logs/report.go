@@ -21,12 +21,18 @@ func ReportSingleApp(appName string, format string, infoFlag string) error {
 	if appName == "--global" {
 		flags = map[string]common.ReportFunc{
 			"--logs-computed-app-label-alias":  reportComputedAppLabelAlias,
+			"--logs-computed-compress":         reportComputedCompress,
+			"--logs-computed-log-driver":       reportComputedLogDriver,
+			"--logs-computed-max-file":         reportComputedMaxFile,
 			"--logs-computed-max-size":         reportComputedMaxSize,
 			"--logs-computed-vector-cron-sink": reportComputedVectorCronSink,
 			"--logs-computed-vector-image":     reportComputedVectorImage,
 			"--logs-computed-vector-networks":  reportComputedVectorNetworks,
 			"--logs-computed-vector-sink":      reportComputedVectorSink,
 			"--logs-global-app-label-alias":    reportGlobalAppLabelAlias,
+			"--logs-global-compress":           reportGlobalCompress,
+			"--logs-global-log-driver":         reportGlobalLogDriver,
+			"--logs-global-max-file":           reportGlobalMaxFile,
 			"--logs-global-max-size":           reportGlobalMaxSize,
 			"--logs-global-vector-cron-sink":   reportGlobalVectorCronSink,
 			"--logs-global-vector-image":       reportGlobalVectorImage,
@@ -36,24 +42,42 @@ func ReportSingleApp(appName string, format string, infoFlag string) error {
 	} else {
 		flags = map[string]common.ReportFunc{
 			"--logs-app-label-alias":           reportAppLabelAlias,
+			"--logs-compress":                  reportCompress,
 			"--logs-computed-app-label-alias":  reportComputedAppLabelAlias,
+			"--logs-computed-compress":         reportComputedCompress,
+			"--logs-computed-log-driver":       reportComputedLogDriver,
+			"--logs-computed-max-file":         reportComputedMaxFile,
 			"--logs-computed-max-size":         reportComputedMaxSize,
 			"--logs-computed-vector-cron-sink": reportComputedVectorCronSink,
 			"--logs-computed-vector-image":     reportComputedVectorImage,
 			"--logs-computed-vector-networks":  reportComputedVectorNetworks,
 			"--logs-computed-vector-sink":      reportComputedVectorSink,
 			"--logs-global-app-label-alias":    reportGlobalAppLabelAlias,
+			"--logs-global-compress":           reportGlobalCompress,
+			"--logs-global-log-driver":         reportGlobalLogDriver,
+			"--logs-global-max-file":           reportGlobalMaxFile,
 			"--logs-global-max-size":           reportGlobalMaxSize,
 			"--logs-global-vector-cron-sink":   reportGlobalVectorCronSink,
 			"--logs-global-vector-image":       reportGlobalVectorImage,
 			"--logs-global-vector-networks":    reportGlobalVectorNetworks,
 			"--logs-global-vector-sink":        reportGlobalVectorSink,
+			"--logs-log-driver":                reportLogDriver,
+			"--logs-max-file":                  reportMaxFile,
 			"--logs-max-size":                  reportMaxSize,
 			"--logs-vector-cron-sink":          reportVectorCronSink,
 			"--logs-vector-sink":               reportVectorSink,
 		}
 	}
 
+	for flag, fn := range addProcessSinkFlags("--global", "--logs-global-vector-sink-") {
+		flags[flag] = fn
+	}
+	if appName != "--global" {
+		for flag, fn := range addProcessSinkFlags(appName, "--logs-vector-sink-") {
+			flags[flag] = fn
+		}
+	}
+
 	flagKeys := []string{}
 	for flagKey := range flags {
 		flagKeys = append(flagKeys, flagKey)
@@ -73,6 +97,20 @@ func ReportSingleApp(appName string, format string, infoFlag string) error {
 	})
 }
 
+// addProcessSinkFlags adds the sinks set for individual process types, either
+// for the app or globally
+func addProcessSinkFlags(appName string, flagPrefix string) map[string]common.ReportFunc {
+	flags := map[string]common.ReportFunc{}
+	for processType, value := range processSinks(appName) {
+		flag := flagPrefix + processType
+		value := value
+		flags[flag] = func(appName string) string {
+			return redactedSink(value, flag)
+		}
+	}
+	return flags
+}
+
 func reportComputedAppLabelAlias(appName string) string {
 	value := reportAppLabelAlias(appName)
 	if value == "" {
@@ -93,6 +131,56 @@ func reportAppLabelAlias(appName string) string {
 	return common.PropertyGet("logs", appName, "app-label-alias")
 }
 
+func reportCompress(appName string) string {
+	return common.PropertyGet("logs", appName, "compress")
+}
+
+func reportComputedCompress(appName string) string {
+	return getComputedProperty(appName, "compress")
+}
+
+func reportGlobalCompress(appName string) string {
+	return common.PropertyGet("logs", "--global", "compress")
+}
+
+func reportLogDriver(appName string) string {
+	return common.PropertyGet("logs", appName, "log-driver")
+}
+
+// reportComputedLogDriver returns the log driver containers are deployed
+// with, which may come from the docker options or the docker daemon rather
+// than the log-driver property
+func reportComputedLogDriver(appName string) string {
+	if appName == "--global" {
+		if value := reportGlobalLogDriver(appName); value != "" {
+			return value
+		}
+		return getDaemonLogDriver()
+	}
+
+	logDriver, _, err := resolveLogDriver(appName)
+	if err != nil {
+		return ""
+	}
+	return logDriver
+}
+
+func reportGlobalLogDriver(appName string) string {
+	return common.PropertyGet("logs", "--global", "log-driver")
+}
+
+func reportMaxFile(appName string) string {
+	return common.PropertyGet("logs", appName, "max-file")
+}
+
+func reportComputedMaxFile(appName string) string {
+	return getComputedProperty(appName, "max-file")
+}
+
+func reportGlobalMaxFile(appName string) string {
+	return common.PropertyGet("logs", "--global", "max-file")
+}
+
 func reportComputedMaxSize(appName string) string {
 	value := reportMaxSize(appName)
 	if value == "" {
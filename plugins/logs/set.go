@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -15,6 +16,18 @@ func validateSetValue(appName string, key string, value string) error {
 		return validateAppLabelAlias(appName, value)
 	}
 
+	if key == "compress" {
+		return validateCompress(appName, value)
+	}
+
+	if key == "log-driver" {
+		return validateLogDriver(appName, value)
+	}
+
+	if key == "max-file" {
+		return validateMaxFile(appName, value)
+	}
+
 	if key == "max-size" {
 		return validateMaxSize(appName, value)
 	}
@@ -31,6 +44,13 @@ func validateSetValue(appName string, key string, value string) error {
 		return validateVectorSink(appName, value)
 	}
 
+	if processType, ok := strings.CutPrefix(key, ProcessSinkPropertyPrefix); ok {
+		if err := validateProcessType(processType); err != nil {
+			return err
+		}
+		return validateVectorSink(appName, value)
+	}
+
 	return nil
 }
 
@@ -50,6 +70,62 @@ func validateAppLabelAlias(appName string, value string) error {
 	return nil
 }
 
+// processTypePattern matches the process type names allowed in a Procfile
+var processTypePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+func validateProcessType(processType string) error {
+	if processType == "" {
+		return errors.New("Invalid vector-sink property, missing process type")
+	}
+
+	if !processTypePattern.MatchString(processType) {
+		return fmt.Errorf("Invalid process type %q, must start with a letter or number and contain only letters, numbers, and any of [_, -]", processType)
+	}
+
+	return nil
+}
+
+func validateCompress(appName string, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if value != "true" && value != "false" {
+		return errors.New("Invalid compress value, must be either true or false")
+	}
+
+	return nil
+}
+
+func validateLogDriver(appName string, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if !slices.Contains(SupportedLogDrivers, value) {
+		return fmt.Errorf("Invalid log-driver value, must be one of [%s]", strings.Join(SupportedLogDrivers, ", "))
+	}
+
+	return nil
+}
+
+func validateMaxFile(appName string, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	maxFile, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Invalid max-file value, unable to convert number to int: %s", err.Error())
+	}
+
+	if maxFile < 1 {
+		return errors.New("Invalid max-file value, must be a positive integer")
+	}
+
+	return nil
+}
+
 func validateMaxSize(appName string, value string) error {
 	if value == "" {
 		return nil
@@ -22,13 +22,14 @@ Manage log integration for an app
 Additional commands:`
 
 	helpContent = `
-    logs [-h|--help] [-t|--tail] [-n|--num num] [-q|--quiet] [-p|--ps process] <app>, Display recent log output
+    logs [-h|--help] [-t|--tail] [-n|--num num] [-q|--quiet] [-p|--ps process] [--since time] [--until time] [--grep regex] [--format text|json] <app>, Display recent log output
     logs:failed [--all|<app>], Shows the last failed deploy logs
     logs:report [<app>] [<flag>], Displays a logs report for one or more apps
-    logs:set [--global|<app>] <key> <value>, Set or clear a logs property for an app
+    logs:set [--global|<app>] [--validate] <key> <value>, Set or clear a logs property for an app
     logs:vector-logs [--num num] [--tail], Display vector log output
     logs:vector-start, Start the vector logging container
-    logs:vector-stop, Stop the vector logging container`
+    logs:vector-stop, Stop the vector logging container
+    logs:vector-validate [--format json|toml] [--vector-image image], Validate the generated vector config`
 )
 
 func main() {
@@ -44,14 +45,28 @@ func main() {
 		ps := args.StringP("ps", "p", "", "only display logs from the given process")
 		tail := args.BoolP("tail", "t", false, "continually stream logs")
 		quiet := args.BoolP("quiet", "q", false, "display raw logs without colors, time and names")
+		since := args.String("since", "", "only display logs written after a duration (e.g. 1h) or RFC3339 timestamp")
+		until := args.String("until", "", "only display logs written before a duration (e.g. 1h) or RFC3339 timestamp")
+		grep := args.String("grep", "", "only display logs matching the given regular expression")
+		format := args.String("format", "text", "format: [ text | json ]")
 		args.Parse(os.Args[2:])
 		if *help {
 			usage()
 			return
 		}
 
+		// a time window should not be cut short by the default number of lines
+		if *since != "" && !args.Changed("num") {
+			*num = -1
+		}
+
 		appName := args.Arg(0)
-		err := logs.CommandDefault(appName, *num, *ps, *tail, *quiet)
+		filter := logs.LogFilterInput{
+			Since: *since,
+			Until: *until,
+			Grep:  *grep,
+		}
+		err := logs.CommandDefault(appName, *num, *ps, *tail, *quiet, filter, *format)
 		if err != nil {
 			common.LogFailWithError(err)
 		}
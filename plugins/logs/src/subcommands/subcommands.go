@@ -39,6 +39,7 @@ func main() {
 	case "set":
 		args := flag.NewFlagSet("logs:set", flag.ExitOnError)
 		global := args.Bool("global", false, "--global: set a global property")
+		validate := args.Bool("validate", false, "--validate: validate the generated vector config before setting a sink")
 		args.Parse(os.Args[2:])
 		appName := args.Arg(0)
 		property := args.Arg(1)
@@ -48,7 +49,7 @@ func main() {
 			property = args.Arg(0)
 			value = args.Arg(1)
 		}
-		err = logs.CommandSet(appName, property, value)
+		err = logs.CommandSet(appName, property, value, *validate)
 	case "vector-logs":
 		args := flag.NewFlagSet("logs:vector-logs", flag.ExitOnError)
 		num := args.Int("num", 100, "the number of lines to display")
@@ -60,6 +61,12 @@ func main() {
 		vectorImage := args.String("vector-image", "", "--vector-image: the name of the docker image to run for vector")
 		args.Parse(os.Args[2:])
 		err = logs.CommandVectorStart(*vectorImage)
+	case "vector-validate":
+		args := flag.NewFlagSet("logs:vector-validate", flag.ExitOnError)
+		format := args.String("format", "json", "format: [ json | toml ]")
+		vectorImage := args.String("vector-image", "", "--vector-image: the name of the docker image to validate the config with")
+		args.Parse(os.Args[2:])
+		err = logs.CommandVectorValidate(*format, *vectorImage)
 	case "vector-stop":
 		args := flag.NewFlagSet("logs:vector-stop", flag.ExitOnError)
 		args.Parse(os.Args[2:])
@@ -0,0 +1,210 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// LogLine is a single line of app output, as emitted by the scheduler-logs
+// trigger when structured output is requested
+type LogLine struct {
+	// Timestamp is when the line was written
+	Timestamp time.Time `json:"timestamp"`
+
+	// App is the app the line belongs to
+	App string `json:"app"`
+
+	// ProcessType is the process type of the container that wrote the line
+	ProcessType string `json:"process_type"`
+
+	// ContainerIndex is the index of the container within its process type
+	ContainerIndex int `json:"container_index"`
+
+	// Stream is the stream the line was written to, one of stdout, stderr or
+	// combined when the scheduler does not distinguish between them
+	Stream string `json:"stream"`
+
+	// Message is the line itself
+	Message string `json:"message"`
+}
+
+// LogFilterInput holds the filters applied to an app's log output
+type LogFilterInput struct {
+	// Since only includes lines written after this time. It may be a duration
+	// relative to now, such as 1h, or an RFC3339 timestamp.
+	Since string
+
+	// Until only includes lines written before this time. It may be a
+	// duration relative to now, such as 1h, or an RFC3339 timestamp.
+	Until string
+
+	// Grep only includes lines whose message matches this regular expression
+	Grep string
+}
+
+// IsSet returns whether any filter is specified
+func (f LogFilterInput) IsSet() bool {
+	return f.Since != "" || f.Until != "" || f.Grep != ""
+}
+
+// logFilter is a parsed LogFilterInput
+type logFilter struct {
+	since time.Time
+	until time.Time
+	grep  *regexp.Regexp
+}
+
+// parseLogTime parses a --since or --until value, either a duration relative
+// to now or an RFC3339 timestamp
+func parseLogTime(flag string, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration < 0 {
+			return time.Time{}, fmt.Errorf("Invalid --%s %q: duration must not be negative", flag, value)
+		}
+		return now.Add(-duration), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid --%s %q: must be a duration (e.g. 1h) or an RFC3339 timestamp", flag, value)
+}
+
+func parseLogFilter(input LogFilterInput, now time.Time) (logFilter, error) {
+	filter := logFilter{}
+	var err error
+	if filter.since, err = parseLogTime("since", input.Since, now); err != nil {
+		return filter, err
+	}
+
+	if filter.until, err = parseLogTime("until", input.Until, now); err != nil {
+		return filter, err
+	}
+
+	if !filter.since.IsZero() && !filter.until.IsZero() && !filter.since.Before(filter.until) {
+		return filter, fmt.Errorf("--since must be before --until")
+	}
+
+	if input.Grep != "" {
+		if filter.grep, err = regexp.Compile(input.Grep); err != nil {
+			return filter, fmt.Errorf("Invalid --grep expression: %s", err.Error())
+		}
+	}
+
+	return filter, nil
+}
+
+// matches returns whether a line passes the filter. Lines without a timestamp
+// are only filtered on their message.
+func (f logFilter) matches(line LogLine) bool {
+	if !line.Timestamp.IsZero() {
+		if !f.since.IsZero() && line.Timestamp.Before(f.since) {
+			return false
+		}
+		if !f.until.IsZero() && line.Timestamp.After(f.until) {
+			return false
+		}
+	}
+
+	return f.grep == nil || f.grep.MatchString(line.Message)
+}
+
+// structuredLogWriter reads the structured output of the scheduler-logs
+// trigger, filters it, and writes each remaining line in the given format
+type structuredLogWriter struct {
+	// AppName is the app the logs belong to
+	AppName string
+
+	// Filter is applied to every line
+	Filter logFilter
+
+	// Format is either text or json
+	Format string
+
+	// Quiet only outputs the message of each line in text format
+	Quiet bool
+
+	// Out is where the formatted lines are written
+	Out io.Writer
+
+	buffer []byte
+}
+
+// Write buffers partial lines so that each line is handled whole
+func (w *structuredLogWriter) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := w.buffer[:i]
+		w.buffer = w.buffer[i+1:]
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush handles any trailing line without a newline
+func (w *structuredLogWriter) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	line := w.buffer
+	w.buffer = nil
+	return w.writeLine(line)
+}
+
+func (w *structuredLogWriter) writeLine(raw []byte) error {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	// schedulers that do not support structured output emit plain lines,
+	// which are kept rather than dropped
+	var line LogLine
+	if err := json.Unmarshal(raw, &line); err != nil {
+		line = LogLine{App: w.AppName, Stream: "combined", Message: string(raw)}
+	}
+
+	if !w.Filter.matches(line) {
+		return nil
+	}
+
+	if w.Format == "json" {
+		encoder := json.NewEncoder(w.Out)
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(line)
+	}
+
+	_, err := fmt.Fprintln(w.Out, formatLogLine(line, w.Quiet))
+	return err
+}
+
+// formatLogLine renders a line the way the scheduler-logs trigger renders
+// unstructured output
+func formatLogLine(line LogLine, quiet bool) string {
+	if quiet || line.Timestamp.IsZero() {
+		return line.Message
+	}
+
+	dyno := line.ProcessType
+	if line.ContainerIndex > 0 {
+		dyno = fmt.Sprintf("%s.%d", line.ProcessType, line.ContainerIndex)
+	}
+	return fmt.Sprintf("%s app[%s]: %s", line.Timestamp.Format(time.RFC3339Nano), dyno, line.Message)
+}
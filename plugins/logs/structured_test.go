@@ -0,0 +1,88 @@
+package logs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseLogTime("since", "90m", now)
+	if err != nil {
+		t.Fatalf("parseLogTime(90m) error = %v", err)
+	}
+	if want := now.Add(-90 * time.Minute); !got.Equal(want) {
+		t.Errorf("parseLogTime(90m) = %v, want %v", got, want)
+	}
+
+	got, err = parseLogTime("since", "2024-05-01T10:00:00+02:00", now)
+	if err != nil {
+		t.Fatalf("parseLogTime(rfc3339) error = %v", err)
+	}
+	if want := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("parseLogTime(rfc3339) = %v, want %v", got, want)
+	}
+
+	for _, value := range []string{"yesterday", "-1h", "2024-05-01"} {
+		if _, err := parseLogTime("since", value, now); err == nil {
+			t.Errorf("parseLogTime(%q) expected an error", value)
+		}
+	}
+
+	if _, err := parseLogFilter(LogFilterInput{Since: "1h", Until: "2h"}, now); err == nil {
+		t.Error("parseLogFilter() expected an error when --since is after --until")
+	}
+
+	if _, err := parseLogFilter(LogFilterInput{Grep: "("}, now); err == nil {
+		t.Error("parseLogFilter() expected an error for an invalid --grep expression")
+	}
+}
+
+func TestStructuredLogWriter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	filter, err := parseLogFilter(LogFilterInput{Since: "1h", Until: "10m", Grep: "GET /"}, now)
+	if err != nil {
+		t.Fatalf("parseLogFilter() error = %v", err)
+	}
+
+	input := strings.Join([]string{
+		`{"timestamp":"2024-05-01T10:30:00Z","app":"myapp","process_type":"web","container_index":1,"stream":"stdout","message":"GET / too early"}`,
+		`{"timestamp":"2024-05-01T11:30:00Z","app":"myapp","process_type":"web","container_index":1,"stream":"stdout","message":"GET /health 200"}`,
+		`{"timestamp":"2024-05-01T11:31:00Z","app":"myapp","process_type":"web","container_index":2,"stream":"stderr","message":"POST /login 500"}`,
+		`{"timestamp":"2024-05-01T11:55:00Z","app":"myapp","process_type":"web","container_index":1,"stream":"stdout","message":"GET / too late"}`,
+		`plain GET / line`,
+	}, "\n")
+
+	var out bytes.Buffer
+	writer := &structuredLogWriter{AppName: "myapp", Filter: filter, Format: "text", Out: &out}
+	// split the input mid-line to exercise the buffering
+	if _, err := writer.Write([]byte(input[:50])); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := writer.Write([]byte(input[50:])); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := "2024-05-01T11:30:00Z app[web.1]: GET /health 200\nplain GET / line\n"
+	if out.String() != want {
+		t.Errorf("text output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	writer = &structuredLogWriter{AppName: "myapp", Filter: filter, Format: "json", Out: &out}
+	if _, err := writer.Write([]byte(input + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want = `{"timestamp":"2024-05-01T11:30:00Z","app":"myapp","process_type":"web","container_index":1,"stream":"stdout","message":"GET /health 200"}` + "\n" +
+		`{"timestamp":"0001-01-01T00:00:00Z","app":"myapp","process_type":"","container_index":0,"stream":"combined","message":"plain GET / line"}` + "\n"
+	if out.String() != want {
+		t.Errorf("json output = %q, want %q", out.String(), want)
+	}
+}
@@ -3,19 +3,31 @@ package logs
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dokku/dokku/plugins/common"
 )
 
-// CommandDefault displays recent log output
-func CommandDefault(appName string, num int64, process string, tail, quiet bool) error {
+// CommandDefault displays recent log output. When a filter or json format is
+// specified, the scheduler is asked for structured output, which is then
+// filtered and formatted here so that every scheduler behaves the same.
+func CommandDefault(appName string, num int64, process string, tail, quiet bool, filter LogFilterInput, format string) error {
 	if err := common.VerifyAppName(appName); err != nil {
 		return err
 	}
 
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" {
+		return fmt.Errorf("Invalid format specified, supported formats: json, text")
+	}
+
 	if !common.IsDeployed(appName) {
 		return fmt.Errorf("App %s has not been deployed", appName)
 	}
@@ -25,12 +37,51 @@ func CommandDefault(appName string, num int64, process string, tail, quiet bool)
 	q := strconv.FormatBool(quiet)
 	n := strconv.FormatInt(num, 10)
 
-	_, err := common.CallPlugnTrigger(common.PlugnTriggerInput{
-		Args:               []string{s, appName, process, t, q, n},
+	if !filter.IsSet() && format == "text" {
+		_, err := common.CallPlugnTrigger(common.PlugnTriggerInput{
+			Args:               []string{s, appName, process, t, q, n},
+			DisableStdioBuffer: true,
+			StreamStdio:        true,
+			Trigger:            "scheduler-logs",
+		})
+		return err
+	}
+
+	parsedFilter, err := parseLogFilter(filter, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	if tail && !parsedFilter.until.IsZero() {
+		return errors.New("--until cannot be combined with --tail")
+	}
+
+	since := ""
+	if !parsedFilter.since.IsZero() {
+		since = parsedFilter.since.Format(time.RFC3339)
+	}
+	until := ""
+	if !parsedFilter.until.IsZero() {
+		until = parsedFilter.until.Format(time.RFC3339)
+	}
+
+	writer := &structuredLogWriter{
+		AppName: appName,
+		Filter:  parsedFilter,
+		Format:  format,
+		Quiet:   quiet,
+		Out:     os.Stdout,
+	}
+	_, err = common.CallPlugnTrigger(common.PlugnTriggerInput{
+		Args:               []string{s, appName, process, t, q, n, since, until, "json"},
 		DisableStdioBuffer: true,
-		StreamStdio:        true,
+		StdoutWriter:       writer,
+		StreamStderr:       true,
 		Trigger:            "scheduler-logs",
 	})
+	if flushErr := writer.Flush(); err == nil {
+		err = flushErr
+	}
 	return err
 }
 
@@ -69,13 +120,34 @@ func CommandReport(appName string, format string, infoFlag string) error {
 	return ReportSingleApp(appName, format, infoFlag)
 }
 
-// CommandSet sets or clears a logs property for an app
-func CommandSet(appName string, property string, value string) error {
+// CommandSet sets or clears a logs property for an app. When validate is
+// true, a sink is only persisted if the resulting vector config is valid.
+func CommandSet(appName string, property string, value string, validate bool) error {
 	if err := validateSetValue(appName, property, value); err != nil {
 		return err
 	}
 
-	common.CommandPropertySet("logs", appName, property, value, DefaultProperties, GlobalProperties)
+	if validate && isSinkProperty(property) {
+		if appName != "--global" {
+			if err := common.VerifyAppName(appName); err != nil {
+				return err
+			}
+		}
+
+		if err := validateVectorSinkChange(appName, property, value); err != nil {
+			return err
+		}
+	}
+
+	validProperties := maps.Clone(DefaultProperties)
+	globalProperties := maps.Clone(GlobalProperties)
+	isProcessSink := strings.HasPrefix(property, ProcessSinkPropertyPrefix)
+	if isProcessSink {
+		validProperties[property] = ""
+		globalProperties[property] = true
+	}
+
+	common.CommandPropertySet("logs", appName, property, value, validProperties, globalProperties)
 
 	vectorProperties := map[string]bool{
 		"app-label-alias":  true,
@@ -83,7 +155,7 @@ func CommandSet(appName string, property string, value string) error {
 		"vector-sink":      true,
 	}
 
-	if _, ok := vectorProperties[property]; ok {
+	if _, ok := vectorProperties[property]; ok || isProcessSink {
 		common.LogVerboseQuiet(fmt.Sprintf("Writing updated vector config to %s", filepath.Join(common.GetDataDirectory("logs"), "vector.json")))
 		return writeVectorConfig()
 	}
@@ -106,6 +178,34 @@ func CommandVectorLogs(lines int, tail bool) error {
 	return nil
 }
 
+// CommandVectorValidate renders the vector config without writing it and
+// validates it with vector
+func CommandVectorValidate(format string, vectorImage string) error {
+	config, err := renderVectorConfig(vectorScopes())
+	if err != nil {
+		return err
+	}
+
+	output, err := formatVectorConfig(config, format)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(strings.TrimSpace(string(output)))
+
+	if vectorImage == "" {
+		vectorImage = getComputedVectorImage()
+	}
+
+	common.LogInfo2Quiet(fmt.Sprintf("Validating vector config with %s", vectorImage))
+	if err := validateVectorConfig(config, vectorImage); err != nil {
+		return err
+	}
+
+	common.LogVerboseQuiet("Vector config is valid")
+	return nil
+}
+
 // CommandVectorStart starts a new vector container
 // or starts an existing one if it already exists
 func CommandVectorStart(vectorImage string) error {
@@ -6,10 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/dokku/dokku/plugins/common"
-	dockeroptions "github.com/dokku/dokku/plugins/docker-options"
 )
 
 // TriggerDockerArgsProcessDeploy outputs the logs plugin docker options for an app
@@ -19,51 +17,13 @@ func TriggerDockerArgsProcessDeploy(appName string) error {
 		return err
 	}
 
-	allowedDrivers := map[string]bool{
-		"local":     true,
-		"json-file": true,
-	}
-
-	ignoreMaxSize := false
-	options, err := dockeroptions.GetDockerOptionsForPhase(appName, "deploy")
+	logDriver, source, err := resolveLogDriver(appName)
 	if err != nil {
 		return err
 	}
 
-	hasDriverOpt := false
-	for _, option := range options {
-		if !strings.HasPrefix(option, "--log-driver=") {
-			continue
-		}
-
-		hasDriverOpt = true
-		logDriver := strings.TrimPrefix(option, "--log-driver=")
-		if !allowedDrivers[logDriver] {
-			ignoreMaxSize = true
-		}
-		break
-	}
-
-	if !hasDriverOpt {
-		result, _ := common.CallExecCommand(common.ExecCommandInput{
-			Command: common.DockerBin(),
-			Args:    []string{"system", "info", "--format", "{{ .LoggingDriver }}"},
-		})
-
-		if !allowedDrivers[result.StdoutContents()] {
-			ignoreMaxSize = true
-		}
-	}
-
-	if !ignoreMaxSize {
-		maxSize := common.PropertyGet("logs", appName, "max-size")
-		if maxSize == "" {
-			maxSize = common.PropertyGetDefault("logs", "--global", "max-size", MaxSize)
-		}
-
-		if maxSize != "unlimited" {
-			fmt.Printf(" --log-opt=max-size=%s ", maxSize)
-		}
+	for _, arg := range logDriverArgs(appName, logDriver, source) {
+		fmt.Printf(" %s ", arg)
 	}
 
 	fmt.Print(string(stdin))
@@ -93,16 +53,17 @@ func TriggerInstall() error {
 
 // TriggerLogsGetProperty writes the logs key to stdout for a given app container
 func TriggerLogsGetProperty(appName string, key string) error {
-	if key != "max-size" {
-		return errors.New("Invalid logs property specified")
+	validProperties := map[string]bool{
+		"compress":   true,
+		"log-driver": true,
+		"max-file":   true,
+		"max-size":   true,
 	}
-
-	value := common.PropertyGet("logs", appName, "max-size")
-	if value == "" {
-		value = common.PropertyGetDefault("logs", "--global", "max-size", MaxSize)
+	if !validProperties[key] {
+		return errors.New("Invalid logs property specified")
 	}
 
-	fmt.Println(value)
+	fmt.Println(getComputedProperty(appName, key))
 	return nil
 }
 
@@ -0,0 +1,134 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/BurntSushi/toml"
+)
+
+// isSinkProperty returns whether a logs property holds a vector sink DSN
+func isSinkProperty(property string) bool {
+	if property == "vector-sink" || property == "vector-cron-sink" {
+		return true
+	}
+	return strings.HasPrefix(property, ProcessSinkPropertyPrefix)
+}
+
+// overrideSink returns a copy of the scopes with a sink property of a single
+// scope replaced, so that a value can be validated before it is persisted
+func overrideSink(scopes []vectorAppSinks, appName string, property string, value string) []vectorAppSinks {
+	overridden := make([]vectorAppSinks, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope.AppName != appName {
+			overridden = append(overridden, scope)
+			continue
+		}
+
+		switch property {
+		case "vector-sink":
+			scope.Sink = value
+		case "vector-cron-sink":
+			scope.CronSink = value
+		default:
+			processSinks := map[string]string{}
+			for processType, sink := range scope.ProcessSinks {
+				processSinks[processType] = sink
+			}
+
+			processType := strings.TrimPrefix(property, ProcessSinkPropertyPrefix)
+			if value == "" {
+				delete(processSinks, processType)
+			} else {
+				processSinks[processType] = value
+			}
+			scope.ProcessSinks = processSinks
+		}
+		overridden = append(overridden, scope)
+	}
+	return overridden
+}
+
+// formatVectorConfig converts a rendered vector config to the given format
+func formatVectorConfig(config []byte, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return config, nil
+	case "toml":
+		var data map[string]any
+		if err := json.Unmarshal(config, &data); err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if err := toml.NewEncoder(&b).Encode(data); err != nil {
+			return nil, fmt.Errorf("Unable to convert vector config to toml: %w", err)
+		}
+		return b.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("Invalid format specified, supported formats: json, toml")
+}
+
+// validateVectorConfig runs vector validate against a rendered config in a
+// throwaway container of the given image. The config is written to the logs
+// data directory as that directory is already known to the docker host via
+// DOKKU_LIB_HOST_ROOT.
+func validateVectorConfig(config []byte, vectorImage string) error {
+	tmpFile, err := os.CreateTemp(common.GetDataDirectory("logs"), "vector-validate-*.json")
+	if err != nil {
+		return fmt.Errorf("Unable to create temporary file: %s", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(config); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("Unable to write temporary vector config: %s", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("Unable to write temporary vector config: %s", err)
+	}
+
+	dokkuLibRoot := os.Getenv("DOKKU_LIB_HOST_ROOT")
+	if dokkuLibRoot == "" {
+		dokkuLibRoot = os.Getenv("DOKKU_LIB_ROOT")
+	}
+
+	// --no-environment skips the healthchecks that would require network
+	// access to every sink and a docker socket for the docker_logs sources
+	result, err := common.CallExecCommand(common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args: []string{
+			"container", "run", "--rm",
+			"--volume", fmt.Sprintf("%s/data/logs:/etc/vector:ro", dokkuLibRoot),
+			vectorImage,
+			"validate", "--no-environment",
+			filepath.Join("/etc/vector", filepath.Base(tmpFile.Name())),
+		},
+		StreamStdio: true,
+	})
+	if err != nil || result.ExitCode != 0 {
+		return errors.New("Vector config is invalid")
+	}
+
+	return nil
+}
+
+// validateVectorSinkChange validates the vector config that would be
+// generated if a sink property were set to the given value
+func validateVectorSinkChange(appName string, property string, value string) error {
+	config, err := renderVectorConfig(overrideSink(vectorScopes(), appName, property, value))
+	if err != nil {
+		return err
+	}
+
+	common.LogVerboseQuiet("Validating vector config")
+	return validateVectorConfig(config, getComputedVectorImage())
+}
@@ -0,0 +1,65 @@
+package logs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverrideSink(t *testing.T) {
+	scopes := []vectorAppSinks{appScope("", ""), globalScope("", "")}
+	scopes[0].AppName = "myapp"
+	scopes[1].AppName = "--global"
+
+	overridden := overrideSink(scopes, "myapp", "vector-sink", "console://?encoding[codec]=json")
+	if overridden[0].Sink != "console://?encoding[codec]=json" {
+		t.Errorf("app sink = %q, want the override", overridden[0].Sink)
+	}
+	if overridden[1].Sink != "" {
+		t.Errorf("global sink = %q, want it untouched", overridden[1].Sink)
+	}
+	if scopes[0].Sink != "" {
+		t.Error("overrideSink should not modify the supplied scopes")
+	}
+
+	overridden = overrideSink(scopes, "--global", "vector-sink.worker", "blackhole://?print_interval_secs=1")
+	if overridden[1].ProcessSinks["worker"] != "blackhole://?print_interval_secs=1" {
+		t.Errorf("global worker sink = %q, want the override", overridden[1].ProcessSinks["worker"])
+	}
+	if len(scopes[1].ProcessSinks) != 0 {
+		t.Error("overrideSink should not modify the supplied process sinks")
+	}
+
+	cleared := overrideSink(overridden, "--global", "vector-sink.worker", "")
+	if _, ok := cleared[1].ProcessSinks["worker"]; ok {
+		t.Error("an empty value should clear the process sink")
+	}
+}
+
+func TestFormatVectorConfig(t *testing.T) {
+	config, err := renderVectorConfig([]vectorAppSinks{appScope("console://?encoding[codec]=json", "")})
+	if err != nil {
+		t.Fatalf("renderVectorConfig() error = %v", err)
+	}
+
+	output, err := formatVectorConfig(config, "json")
+	if err != nil {
+		t.Fatalf("formatVectorConfig(json) error = %v", err)
+	}
+	if string(output) != string(config) {
+		t.Error("json output should be the rendered config as-is")
+	}
+
+	output, err = formatVectorConfig(config, "toml")
+	if err != nil {
+		t.Fatalf("formatVectorConfig(toml) error = %v", err)
+	}
+	for _, fragment := range []string{`[sinks."docker-sink:myapp"]`, `type = "console"`, `[sources."docker-source:myapp"]`} {
+		if !strings.Contains(string(output), fragment) {
+			t.Errorf("toml output missing %q:\n%s", fragment, output)
+		}
+	}
+
+	if _, err := formatVectorConfig(config, "yaml"); err == nil {
+		t.Error("formatVectorConfig(yaml) expected an error")
+	}
+}
@@ -26,6 +26,8 @@ require (
 	golang.org/x/sys v0.47.0 // indirect
 )
 
+replace github.com/dokku/dokku/plugins/app-json => ../app-json
+
 replace github.com/dokku/dokku/plugins/common => ../common
 
 replace github.com/dokku/dokku/plugins/config => ../config
@@ -29,6 +29,8 @@ require (
 	mvdan.cc/sh/v3 v3.13.1 // indirect
 )
 
+replace github.com/dokku/dokku/plugins/app-json => ../app-json
+
 replace github.com/dokku/dokku/plugins/common => ../common
 
 replace github.com/dokku/dokku/plugins/config => ../config
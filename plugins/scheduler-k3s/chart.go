@@ -405,6 +405,7 @@ func BuildAppChart(ctx context.Context, appName, imageTag string, opts BuildOpti
 			Annotations:  annotations,
 			Autoscaling:  autoscaling,
 			Args:         args,
+			ConfigSecret: GetScopedConfigSecretName(appName, config.Scope{ProcessType: processType}),
 			DeploymentID: resolveProcessDeploymentID(processType, deploymentId, opts.RestartProcessType, opts.PriorProcessDeploymentIDs),
 			Healthchecks: processHealthchecks,
 			Labels:       labels,
@@ -584,6 +585,8 @@ func BuildAppChart(ctx context.Context, appName, imageTag string, opts BuildOpti
 				Suspend:               cronTask.Maintenance,
 				ConcurrencyPolicy:     ProcessCronConcurrencyPolicy(concurrencyPolicy),
 				ActiveDeadlineSeconds: cron.DefaultTTLSeconds,
+				TimeZone:              cronTask.Timezone,
+				BackoffLimit:          cronTask.Retries,
 			},
 			Labels:      labels,
 			ProcessType: ProcessType_Cron,
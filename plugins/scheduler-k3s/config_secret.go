@@ -2,12 +2,16 @@ package scheduler_k3s
 
 import (
 	"context"
+	"crypto/sha1"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
 )
 
 // ConfigSecretValues contains the values for the config secret helm chart
@@ -17,11 +21,12 @@ type ConfigSecretValues struct {
 
 // ConfigSecretGlobalValues contains the global values for the config secret chart
 type ConfigSecretGlobalValues struct {
-	Annotations map[string]string `yaml:"annotations,omitempty"`
-	AppName     string            `yaml:"app_name"`
-	Labels      map[string]string `yaml:"labels,omitempty"`
-	Namespace   string            `yaml:"namespace"`
-	Secrets     map[string]string `yaml:"secrets,omitempty"`
+	Annotations   map[string]string            `yaml:"annotations,omitempty"`
+	AppName       string                       `yaml:"app_name"`
+	Labels        map[string]string            `yaml:"labels,omitempty"`
+	Namespace     string                       `yaml:"namespace"`
+	Secrets       map[string]string            `yaml:"secrets,omitempty"`
+	ScopedSecrets map[string]map[string]string `yaml:"scoped_secrets,omitempty"`
 }
 
 // GetConfigSecretReleaseName returns the helm release name for the config secret
@@ -34,6 +39,28 @@ func GetConfigSecretName(appName string) string {
 	return fmt.Sprintf("config-%s", appName)
 }
 
+// GetScopedConfigSecretName returns the kubernetes secret name for the phase or process type scoped config env
+func GetScopedConfigSecretName(appName string, scope config.Scope) string {
+	if scope.ProcessType != "" {
+		return fmt.Sprintf("config-%s-process-%s", appName, processTypeSecretSlug(scope.ProcessType))
+	}
+	return fmt.Sprintf("config-%s-phase-%s", appName, scope.Phase)
+}
+
+// processTypeSecretSlug returns the process type as used in a kubernetes secret name.
+// Process types may contain uppercase letters and underscores, which are not valid in
+// DNS-1123 names, so those are lowercased and dashed, with a short hash of the original
+// process type appended to keep names for differently-cased process types apart.
+func processTypeSecretSlug(processType string) string {
+	slug := strings.ReplaceAll(strings.ToLower(processType), "_", "-")
+	if slug == processType && !strings.HasSuffix(slug, "-") {
+		return slug
+	}
+
+	sum := sha1.Sum([]byte(processType))
+	return fmt.Sprintf("%s-%s", strings.TrimSuffix(slug, "-"), hex.EncodeToString(sum[:])[:8])
+}
+
 // CreateOrUpdateConfigSecretInput contains the inputs to CreateOrUpdateConfigSecret
 type CreateOrUpdateConfigSecretInput struct {
 	AppName     string
@@ -104,18 +131,24 @@ func CreateOrUpdateConfigSecret(ctx context.Context, input CreateOrUpdateConfigS
 		common.CatFile(filename)
 	}
 
-	encodedSecrets := map[string]string{}
-	for key, value := range input.Env {
-		encodedSecrets[key] = base64.StdEncoding.EncodeToString([]byte(value))
+	env, err := config.ResolveSecretRefs(appName, input.Env)
+	if err != nil {
+		return fmt.Errorf("error resolving secret references: %w", err)
+	}
+
+	scopedSecrets, err := getScopedConfigSecrets(appName)
+	if err != nil {
+		return err
 	}
 
 	values := &ConfigSecretValues{
 		Global: ConfigSecretGlobalValues{
-			Annotations: input.Annotations,
-			AppName:     appName,
-			Labels:      input.Labels,
-			Namespace:   namespace,
-			Secrets:     encodedSecrets,
+			Annotations:   input.Annotations,
+			AppName:       appName,
+			Labels:        input.Labels,
+			Namespace:     namespace,
+			Secrets:       encodeSecretValues(env),
+			ScopedSecrets: scopedSecrets,
 		},
 	}
 
@@ -186,3 +219,40 @@ func DeleteConfigSecret(ctx context.Context, appName string) error {
 
 	return nil
 }
+
+// getScopedConfigSecrets returns the encoded config of every phase and process type scope of an app, keyed by secret name
+func getScopedConfigSecrets(appName string) (map[string]map[string]string, error) {
+	scopes, err := config.ListAppScopes(appName)
+	if err != nil {
+		return nil, fmt.Errorf("error listing config scopes: %w", err)
+	}
+
+	scopedSecrets := map[string]map[string]string{}
+	for _, scope := range scopes {
+		scoped, err := config.LoadScopedAppEnv(appName, scope)
+		if err != nil {
+			return nil, fmt.Errorf("error loading scoped config: %w", err)
+		}
+
+		env, err := config.ResolveSecretRefs(appName, scoped.Map())
+		if err != nil {
+			return nil, fmt.Errorf("error resolving secret references: %w", err)
+		}
+
+		if len(env) == 0 {
+			continue
+		}
+		scopedSecrets[GetScopedConfigSecretName(appName, scope)] = encodeSecretValues(env)
+	}
+
+	return scopedSecrets, nil
+}
+
+// encodeSecretValues base64 encodes env values for use as kubernetes secret data
+func encodeSecretValues(env map[string]string) map[string]string {
+	encoded := map[string]string{}
+	for key, value := range env {
+		encoded[key] = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	return encoded
+}
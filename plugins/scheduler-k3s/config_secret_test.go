@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"testing"
 
+	"github.com/dokku/dokku/plugins/config"
 	"gopkg.in/yaml.v3"
 )
 
@@ -63,3 +64,22 @@ func TestConfigSecretValuesEncoding(t *testing.T) {
 		t.Errorf("DATABASE_URL decoded = %q, want \"postgres://localhost/db\"", string(decoded))
 	}
 }
+
+func TestGetScopedConfigSecretName(t *testing.T) {
+	tests := []struct {
+		scope config.Scope
+		want  string
+	}{
+		{config.Scope{Phase: "deploy"}, "config-myapp-phase-deploy"},
+		{config.Scope{Phase: "run"}, "config-myapp-phase-run"},
+		{config.Scope{ProcessType: "worker"}, "config-myapp-process-worker"},
+		{config.Scope{ProcessType: "Worker_1"}, "config-myapp-process-worker-1-f2adf632"},
+		{config.Scope{ProcessType: "worker-"}, "config-myapp-process-worker-ed006995"},
+	}
+
+	for _, test := range tests {
+		if got := GetScopedConfigSecretName("myapp", test.scope); got != test.want {
+			t.Errorf("GetScopedConfigSecretName(\"myapp\", %v) = %q, want %q", test.scope, got, test.want)
+		}
+	}
+}
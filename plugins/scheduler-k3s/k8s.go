@@ -17,6 +17,7 @@ import (
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/logs"
 	"github.com/fatih/color"
 	"github.com/go-openapi/jsonpointer"
 	kedav1alpha1 "github.com/kedacore/keda/v2/apis/keda/v1alpha1"
@@ -1098,12 +1099,18 @@ func (k KubernetesClient) SetDeploymentImagePullSecrets(ctx context.Context, inp
 }
 
 type StreamLogsInput struct {
+	// AppName is the app the logs belong to, used for structured output
+	AppName string
+
 	// ContainerName is the Kubernetes container name
 	ContainerName string
 
 	// Follow is whether to follow the logs
 	Follow bool
 
+	// Format is the output format, either text or json
+	Format string
+
 	// LabelSelector is the Kubernetes label selector
 	LabelSelector []string
 
@@ -1116,8 +1123,14 @@ type StreamLogsInput struct {
 	// SinceSeconds is the number of seconds to go back
 	SinceSeconds int64
 
+	// SinceTime only includes logs written after this time
+	SinceTime *time.Time
+
 	// TailLines is the number of lines to tail
 	TailLines int64
+
+	// UntilTime only includes logs written before this time
+	UntilTime *time.Time
 }
 
 func (k KubernetesClient) StreamLogs(ctx context.Context, input StreamLogsInput) error {
@@ -1177,6 +1190,13 @@ func (k KubernetesClient) StreamLogs(ctx context.Context, input StreamLogsInput)
 		sec := int64(time.Duration(input.SinceSeconds * int64(time.Second)).Seconds())
 		logOptions.SinceSeconds = &sec
 	}
+	if input.SinceTime != nil {
+		sinceTime := metav1.NewTime(*input.SinceTime)
+		logOptions.SinceTime = &sinceTime
+	}
+	if input.Format == "json" || input.UntilTime != nil {
+		logOptions.Timestamps = true
+	}
 
 	requests := make([]rest.ResponseWrapper, len(pods))
 	for i := 0; i < len(pods); i++ {
@@ -1201,6 +1221,7 @@ func (k KubernetesClient) StreamLogs(ctx context.Context, input StreamLogsInput)
 		color.FgMagenta,
 	}
 
+	containerIndexes := map[string]int{}
 	for i := 0; i < len(requests); i++ {
 		request := requests[i]
 		podName := pods[i].Name
@@ -1208,11 +1229,36 @@ func (k KubernetesClient) StreamLogs(ctx context.Context, input StreamLogsInput)
 		dynoText := color.New(podColor).SprintFunc()
 		prefix := dynoText(fmt.Sprintf("app[%s]: ", podName))
 
-		go func(ctx context.Context, request rest.ResponseWrapper, prefix string) {
+		processType := pods[i].Labels["app.kubernetes.io/name"]
+		containerIndexes[processType]++
+		var out io.Writer
+		if input.Format == "json" {
+			out = &jsonLogWriter{
+				AppName:        input.AppName,
+				ProcessType:    processType,
+				ContainerIndex: containerIndexes[processType],
+				Writer:         writer,
+			}
+		} else {
+			out = k.addPrefixingWriter(writer, prefix, input.Quiet)
+		}
+		if input.UntilTime != nil {
+			out = &untilLogWriter{
+				Until:          *input.UntilTime,
+				KeepTimestamps: input.Format == "json",
+				Writer:         out,
+			}
+		}
+
+		go func(ctx context.Context, request rest.ResponseWrapper, out io.Writer) {
 			defer wg.Done()
 
-			out := k.addPrefixingWriter(writer, prefix, input.Quiet)
 			if err := streamLogsFromRequest(ctx, request, out); err != nil {
+				// the remaining lines of this pod were written after --until
+				if errors.Is(err, errLogsUntilReached) {
+					return
+				}
+
 				// check if error is context canceled
 				if errors.Is(err, context.Canceled) {
 					writer.Close()
@@ -1222,7 +1268,7 @@ func (k KubernetesClient) StreamLogs(ctx context.Context, input StreamLogsInput)
 				writer.CloseWithError(err)
 				return
 			}
-		}(ctx, request, prefix)
+		}(ctx, request, out)
 	}
 
 	go func() {
@@ -1245,6 +1291,86 @@ func (k KubernetesClient) addPrefixingWriter(writer *io.PipeWriter, prefix strin
 	}
 }
 
+// jsonLogWriter converts timestamped pod log lines into the structured
+// output of the scheduler-logs trigger. Kubernetes does not distinguish
+// between stdout and stderr, so every line is written to the combined stream.
+type jsonLogWriter struct {
+	AppName        string
+	ProcessType    string
+	ContainerIndex int
+	Writer         io.Writer
+}
+
+// Write expects a single "<timestamp> <message>" line per call
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	line := logs.LogLine{
+		App:            w.AppName,
+		ProcessType:    w.ProcessType,
+		ContainerIndex: w.ContainerIndex,
+		Stream:         "combined",
+		Message:        strings.TrimRight(string(p), "\r\n"),
+	}
+	if timestamp, message, ok := strings.Cut(line.Message, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+			line.Timestamp = t
+			line.Message = message
+		}
+	}
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		return 0, err
+	}
+
+	// a single write per line keeps concurrent pods from interleaving
+	if _, err := w.Writer.Write(append(b, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// errLogsUntilReached is returned by untilLogWriter once a line is past its
+// until time
+var errLogsUntilReached = errors.New("log line is past the until time")
+
+// untilLogWriter stops a pod's log stream at the first timestamped line
+// written after Until. Pod logs are ordered, so no later line can be in range.
+// Timestamps are removed again unless KeepTimestamps is set.
+type untilLogWriter struct {
+	Until          time.Time
+	KeepTimestamps bool
+	Writer         io.Writer
+}
+
+// Write expects a single "<timestamp> <message>" line per call
+func (w *untilLogWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n == 0 {
+		return 0, nil
+	}
+
+	timestamp, message, ok := strings.Cut(string(p), " ")
+	if ok {
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+			if t.After(w.Until) {
+				return 0, errLogsUntilReached
+			}
+			if !w.KeepTimestamps {
+				p = []byte(message)
+			}
+		}
+	}
+
+	if _, err := w.Writer.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
 func streamLogsFromRequest(ctx context.Context, request rest.ResponseWrapper, out io.Writer) error {
 	readCloser, err := request.Stream(ctx)
 	if err != nil {
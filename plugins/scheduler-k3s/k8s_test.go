@@ -1,9 +1,12 @@
 package scheduler_k3s
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -116,3 +119,30 @@ func TestGetLowestNodeVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestUntilLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &untilLogWriter{
+		Until:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Writer: &out,
+	}
+
+	if _, err := w.Write([]byte("2024-01-01T11:59:59.5Z before\n")); err != nil {
+		t.Fatalf("write before until: %v", err)
+	}
+	if _, err := w.Write([]byte("2024-01-01T12:00:00.5Z after\n")); !errors.Is(err, errLogsUntilReached) {
+		t.Fatalf("write after until = %v, want errLogsUntilReached", err)
+	}
+	if got := out.String(); got != "before\n" {
+		t.Errorf("output = %q, want the line before until without its timestamp", got)
+	}
+
+	out.Reset()
+	w.KeepTimestamps = true
+	if _, err := w.Write([]byte("2024-01-01T11:00:00Z kept\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := out.String(); got != "2024-01-01T11:00:00Z kept\n" {
+		t.Errorf("output = %q, want the timestamp kept", got)
+	}
+}
@@ -111,7 +111,10 @@ func main() {
 			numLines = 0
 		}
 
-		err = scheduler_k3s.TriggerSchedulerLogs(scheduler, appName, processType, tail, quiet, numLines)
+		since := flag.Arg(6)
+		until := flag.Arg(7)
+		format := flag.Arg(8)
+		err = scheduler_k3s.TriggerSchedulerLogs(scheduler, appName, processType, tail, quiet, numLines, since, until, format)
 	case "scheduler-proxy-config":
 		scheduler := flag.Arg(0)
 		appName := flag.Arg(1)
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/dokku/dokku/plugins/common"
+	"github.com/dokku/dokku/plugins/config"
 	"gopkg.in/yaml.v3"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -107,6 +108,7 @@ type ProcessValues struct {
 	Annotations  ProcessAnnotations  `yaml:"annotations,omitempty"`
 	Args         []string            `yaml:"args,omitempty"`
 	Autoscaling  ProcessAutoscaling  `yaml:"autoscaling,omitempty"`
+	ConfigSecret string              `yaml:"config_secret,omitempty"`
 	Cron         ProcessCron         `yaml:"cron,omitempty"`
 	DeploymentID string              `yaml:"deployment_id,omitempty"`
 	Healthchecks ProcessHealthchecks `yaml:"healthchecks,omitempty"`
@@ -313,6 +315,8 @@ type ProcessCron struct {
 	Suspend               bool                         `yaml:"suspend"`
 	ConcurrencyPolicy     ProcessCronConcurrencyPolicy `yaml:"concurrency_policy"`
 	ActiveDeadlineSeconds int64                        `yaml:"active_deadline_seconds"`
+	TimeZone              string                       `yaml:"time_zone,omitempty"`
+	BackoffLimit          int                          `yaml:"backoff_limit"`
 }
 
 type ProcessCronConcurrencyPolicy string
@@ -472,6 +476,7 @@ func templateKubernetesJob(input Job) (batchv1.Job, error) {
 
 	maps.Copy(labels, input.Labels)
 	secretName := GetConfigSecretName(input.AppName)
+	runSecretName := GetScopedConfigSecretName(input.AppName, config.Scope{Phase: "run"})
 
 	env := []corev1.EnvVar{}
 	for key, value := range input.Env {
@@ -549,6 +554,14 @@ func templateKubernetesJob(input Job) (batchv1.Job, error) {
 										Optional: ptr.To(true),
 									},
 								},
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{
+											Name: runSecretName,
+										},
+										Optional: ptr.To(true),
+									},
+								},
 							},
 							Image:           input.Image,
 							ImagePullPolicy: corev1.PullAlways,
@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/dokku/dokku/plugins/common"
 	"github.com/dokku/dokku/plugins/config"
@@ -753,11 +754,29 @@ func TriggerSchedulerEnter(scheduler string, appName string, processType string,
 }
 
 // TriggerSchedulerLogs displays logs for a given application
-func TriggerSchedulerLogs(scheduler string, appName string, processType string, tail bool, quiet bool, numLines int64) error {
+func TriggerSchedulerLogs(scheduler string, appName string, processType string, tail bool, quiet bool, numLines int64, since string, until string, format string) error {
 	if scheduler != "k3s" {
 		return nil
 	}
 
+	var sinceTime *time.Time
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return fmt.Errorf("Error parsing since time: %w", err)
+		}
+		sinceTime = &t
+	}
+
+	var untilTime *time.Time
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return fmt.Errorf("Error parsing until time: %w", err)
+		}
+		untilTime = &t
+	}
+
 	clientset, err := NewKubernetesClient()
 	if err != nil {
 		return fmt.Errorf("Error creating kubernetes client: %w", err)
@@ -770,6 +789,10 @@ func TriggerSchedulerLogs(scheduler string, appName string, processType string,
 		TailLines:     numLines,
 		Follow:        tail,
 		Quiet:         quiet,
+		AppName:       appName,
+		SinceTime:     sinceTime,
+		UntilTime:     untilTime,
+		Format:        format,
 	})
 }
 
@@ -1026,6 +1049,7 @@ func TriggerSchedulerRun(scheduler string, appName string, envCount int, args []
 				return fmt.Errorf("Error listing pods: %w", err)
 			}
 			if len(pods) > 0 {
+				cron.RecordRunSkipped(appName, os.Getenv("DOKKU_CRON_RUN_ID"))
 				return fmt.Errorf("There is a running pod with the same dokku.com/cron-hash label")
 			}
 		case "replace":
@@ -1205,6 +1229,7 @@ func TriggerSchedulerRun(scheduler string, appName string, envCount int, args []
 	if err != nil {
 		return fmt.Errorf("Error waiting for pod to exist: %w", err)
 	}
+	cron.RecordRunContainer(appName, os.Getenv("DOKKU_CRON_RUN_ID"), pods[0].Name)
 	if !attachToPod {
 		fmt.Println(pods[0].Name)
 		return nil
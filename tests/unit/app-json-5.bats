@@ -196,3 +196,61 @@ teardown() {
   assert_success
   assert_output "preset_value"
 }
+
+@test "(app-json) app.json env type validation" {
+  run /bin/bash -c "dokku app-json:set $TEST_APP appjson-path app-env-typed.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP TYPED_LOG_LEVEL=trace"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid value for TYPED_LOG_LEVEL: expected one of: debug, info"
+
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP TYPED_LOG_LEVEL=debug"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP TYPED_PORT=abc"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid value for TYPED_PORT: expected an integer"
+  assert_output_not_contains "abc"
+
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP TYPED_KEY=pk_123"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid value for TYPED_KEY: expected a value matching the pattern sk_[a-z0-9]+"
+
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP TYPED_PORT=8080 TYPED_KEY=sk_123"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku config:validate $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "All config vars are valid"
+
+  run /bin/bash -c "dokku config:set --no-restart --process worker $TEST_APP TYPED_PORT=abc"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid value for TYPED_PORT: expected an integer"
+}
@@ -37,7 +37,7 @@ EOF
 }
 
 write_running_record() {
-  local app="$1" id="$2" pid="$3" source="${4:-git-hook}" kind="${5:-build}"
+  local app="$1" id="$2" pid="$3" source="${4:-git-hook}" kind="${5:-build}" status="${6:-running}"
   local dir="$DOKKU_LIB_ROOT/data/builds/$app"
   mkdir -p "$dir"
   local now
@@ -49,7 +49,7 @@ write_running_record() {
   "kind": "$kind",
   "pid": $pid,
   "started_at": "$now",
-  "status": "running",
+  "status": "$status",
   "source": "$source"
 }
 EOF
@@ -133,6 +133,141 @@ EOF
   assert_output_contains "Invalid --kind"
 }
 
+@test "(builds:list) filters by --sha, --builder and --user" {
+  write_finished_record "$TEST_APP" "prov01" "succeeded" "git-hook" 1001 "build"
+  write_finished_record "$TEST_APP" "prov02" "succeeded" "git-hook" 1002 "build"
+  local dir="$DOKKU_LIB_ROOT/data/builds/$TEST_APP"
+  jq '. + {"git_sha": "aaaaaaa1111", "builder": "herokuish", "user": "alice"}' "$dir/prov01.json" >"$dir/prov01.json.tmp" && mv "$dir/prov01.json.tmp" "$dir/prov01.json"
+  jq '. + {"git_sha": "bbbbbbb2222", "builder": "dockerfile", "user": "bob"}' "$dir/prov02.json" >"$dir/prov02.json.tmp" && mv "$dir/prov02.json.tmp" "$dir/prov02.json"
+  chown -R dokku:dokku "$dir"
+
+  run /bin/bash -c "dokku builds:list $TEST_APP --sha aaaaaaa"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "prov01"
+  assert_output_not_contains "prov02"
+
+  run /bin/bash -c "dokku builds:list $TEST_APP --builder dockerfile"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "prov02"
+  assert_output_not_contains "prov01"
+
+  run /bin/bash -c "dokku builds:list $TEST_APP --user alice --format json | jq -r '.[].id'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "prov01"
+}
+
+@test "(builds:info) shows provenance recorded during a deploy" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  build_id="$(dokku builds:report $TEST_APP --build-id)"
+  run /bin/bash -c "dokku builds:info $TEST_APP $build_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Git SHA:"
+  assert_output_contains "Builder:"
+  assert_output_contains "User:"
+  assert_output_contains "Image ID:"
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --build-git-sha"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "$(cd "$DOKKU_ROOT/$TEST_APP" && git rev-parse HEAD)"
+}
+
+@test "(builds:info) records per-phase timing during a deploy" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  build_id="$(dokku builds:report $TEST_APP --build-id)"
+  run /bin/bash -c "dokku builds:info $TEST_APP $build_id --format json | jq -r '.phases[].name' | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "extract build release scheduler-deploy healthchecks proxy-rebuild"
+
+  run /bin/bash -c "dokku builds:info $TEST_APP $build_id --format json | jq -r '[.phases[].outcome] | unique | join(\",\")'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "succeeded"
+
+  run /bin/bash -c "dokku builds:info $TEST_APP $build_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Phase"
+  assert_output_contains "scheduler-deploy"
+}
+
+@test "(builds:info) marks the failing phase of a failed deploy" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP add_failing_predeploy
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  build_id="$(dokku builds:report $TEST_APP --build-id)"
+  run /bin/bash -c "dokku builds:info $TEST_APP $build_id --format json | jq -r '.phases[] | .name + \" \" + .outcome' | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "extract succeeded build succeeded predeploy failed"
+}
+
+@test "(builds:diff) compares deploy inputs without printing env values" {
+  run /bin/bash -c "dokku config:set --no-restart $TEST_APP SECRET_KEY=first-secret"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  first_id="$(dokku builds:report $TEST_APP --build-id)"
+
+  run /bin/bash -c "dokku config:set $TEST_APP SECRET_KEY=second-secret FEATURE_FLAG=on"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  second_id="$(dokku builds:report $TEST_APP --build-id)"
+
+  run /bin/bash -c "dokku builds:diff $TEST_APP $first_id $second_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "SECRET_KEY"
+  assert_output_contains "changed"
+  assert_output_contains "FEATURE_FLAG"
+  assert_output_contains "added"
+  assert_output_not_contains "first-secret"
+  assert_output_not_contains "second-secret"
+
+  run /bin/bash -c "dokku builds:diff $TEST_APP $first_id $second_id --format json | jq -r '.env[] | select(.key == \"SECRET_KEY\") | .status'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "changed"
+
+  run /bin/bash -c "dokku builds:diff $TEST_APP $first_id missing"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "No build record found"
+}
+
 @test "(builds:set) writes a per-app retention" {
   run /bin/bash -c "dokku builds:set $TEST_APP retention 5"
   echo "output: $output"
@@ -256,6 +391,83 @@ EOF
   assert_output_contains "failed"
 }
 
+@test "(builds:set) validates queue-mode and queue-coalesce" {
+  run /bin/bash -c "dokku builds:set $TEST_APP queue-mode true"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku builds:set $TEST_APP queue-coalesce maybe"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "must be true or false"
+}
+
+@test "(builds:list) shows queued builds and builds:cancel --build-id drops them" {
+  write_running_record "$TEST_APP" "queue01" "$$" "git-hook" "build" "queued"
+
+  run /bin/bash -c "dokku builds:list $TEST_APP --status queued"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "queue01"
+
+  run /bin/bash -c "dokku builds:list"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "queue01"
+
+  run /bin/bash -c "dokku builds:cancel $TEST_APP --build-id queue01"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Removing build queue01 from the deploy queue"
+
+  run /bin/bash -c "dokku builds:info $TEST_APP queue01 --format json | jq -r .status"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "canceled"
+}
+
+@test "(builds) queue-mode deploys a push that races the deploy lock instead of failing" {
+  run /bin/bash -c "dokku builds:set $TEST_APP queue-mode true"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  mkdir -p "$DOKKU_LIB_ROOT/data/apps/$TEST_APP"
+  touch "$DOKKU_LIB_ROOT/data/apps/$TEST_APP/.deploy.lock"
+  chown dokku:dokku "$DOKKU_LIB_ROOT/data/apps/$TEST_APP/.deploy.lock"
+  (
+    exec 200>>"$DOKKU_LIB_ROOT/data/apps/$TEST_APP/.deploy.lock"
+    flock 200
+    sleep 10
+  ) &
+  local holder=$!
+  sleep 1
+
+  run /bin/bash -c "dokku ps:rebuild $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  wait "$holder" || true
+  assert_success
+  assert_output_contains "Queued build"
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --build-status"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "succeeded"
+}
+
 @test "(builds:output) cats the log file for a finished build" {
   write_finished_record "$TEST_APP" "out001" "succeeded" "git-hook" 1234 "build"
   echo "build log line" >"$DOKKU_LIB_ROOT/data/builds/$TEST_APP/out001.log"
@@ -268,6 +480,229 @@ EOF
   assert_output_contains "build log line"
 }
 
+@test "(builds:output --follow) streams a running build until it is finalized" {
+  sleep 30 &
+  local pid=$!
+  write_running_record "$TEST_APP" "out002" "$pid" "git-hook"
+  echo "first line" >"$DOKKU_LIB_ROOT/data/builds/$TEST_APP/out002.log"
+  chown dokku:dokku "$DOKKU_LIB_ROOT/data/builds/$TEST_APP/out002.log"
+
+  (
+    sleep 3
+    echo "second line" >>"$DOKKU_LIB_ROOT/data/builds/$TEST_APP/out002.log"
+    dokku plugin:trigger builds-record-finalize "$TEST_APP" out002 0
+  ) &
+
+  run /bin/bash -c "timeout 20 dokku builds:output $TEST_APP out002 --follow"
+  echo "output: $output"
+  echo "status: $status"
+  kill "$pid" || true
+  assert_success
+  assert_output_contains "first line"
+  assert_output_contains "second line"
+}
+
+@test "(builds:output) follows a running build by default" {
+  sleep 30 &
+  local pid=$!
+  write_running_record "$TEST_APP" "out003" "$pid" "git-hook"
+  echo "first line" >"$DOKKU_LIB_ROOT/data/builds/$TEST_APP/out003.log"
+  chown dokku:dokku "$DOKKU_LIB_ROOT/data/builds/$TEST_APP/out003.log"
+
+  (
+    sleep 3
+    echo "second line" >>"$DOKKU_LIB_ROOT/data/builds/$TEST_APP/out003.log"
+    dokku plugin:trigger builds-record-finalize "$TEST_APP" out003 0
+  ) &
+
+  run /bin/bash -c "timeout 20 dokku builds:output $TEST_APP out003"
+  echo "output: $output"
+  echo "status: $status"
+  kill "$pid" || true
+  assert_success
+  assert_output_contains "first line"
+  assert_output_contains "second line"
+}
+
+@test "(builds:output) renders and emits jsonl logs" {
+  run /bin/bash -c "dokku builds:set $TEST_APP log-format invalid"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku builds:set $TEST_APP log-format jsonl"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  build_id="$(dokku builds:report $TEST_APP --build-id)"
+  run /bin/bash -c "dokku builds:output $TEST_APP $build_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Releasing $TEST_APP"
+  assert_output_not_contains '"stream"'
+
+  run /bin/bash -c "dokku builds:output $TEST_APP $build_id --format json | jq -r 'select(.line | contains(\"Checking for release task\")) | .stream + \" \" + .phase'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "stdout release"
+
+  run /bin/bash -c "dokku builds:output $TEST_APP $build_id --format json | jq -r '.timestamp' | head -n1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "T"
+}
+
+@test "(builds:stats) aggregates outcomes by kind and source" {
+  write_finished_record "$TEST_APP" "stat01" "succeeded" "git-hook" 1001 "build"
+  write_finished_record "$TEST_APP" "stat02" "succeeded" "git-hook" 1002 "build"
+  write_finished_record "$TEST_APP" "stat03" "failed" "git-hook" 1003 "build" 1
+  write_finished_record "$TEST_APP" "stat04" "canceled" "ps:restart" 1004 "deploy" -1
+  write_running_record "$TEST_APP" "stat05" "$$" "git-hook"
+
+  run /bin/bash -c "dokku builds:stats $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "kind:build"
+  assert_output_contains "source:ps:restart"
+  assert_output_contains "50.0%"
+
+  run /bin/bash -c "dokku builds:stats $TEST_APP --format json | jq -r '[.all.total, .all.failed, .by_kind.build.total, .by_source[\"ps:restart\"].canceled] | join(\",\")'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "4,1,3,1"
+
+  run /bin/bash -c "dokku builds:stats --format json | jq -r '.all.total >= 4'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "true"
+
+  run /bin/bash -c "dokku builds:stats $TEST_APP --since 2099-01-01T00:00:00Z --format json | jq -r .all.total"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "0"
+
+  run /bin/bash -c "dokku builds:stats $TEST_APP --since yesterday"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid --since"
+}
+
+@test "(builds:wait) exits with the recorded exit code of a finished build" {
+  write_finished_record "$TEST_APP" "wait01" "succeeded" "git-hook" 1234 "build" 0
+  write_finished_record "$TEST_APP" "wait02" "failed" "git-hook" 1234 "build" 3
+
+  run /bin/bash -c "dokku builds:wait $TEST_APP wait01"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku builds:wait $TEST_APP wait02"
+  echo "output: $output"
+  echo "status: $status"
+  assert_equal "$status" 3
+  assert_output_contains "failed with exit code 3"
+}
+
+@test "(builds:wait) uses distinct exit codes for canceled, abandoned and timed out builds" {
+  write_finished_record "$TEST_APP" "wait03" "canceled" "git-hook" 1234 "build" -1
+  write_running_record "$TEST_APP" "wait04" 99999 "git-hook"
+  write_running_record "$TEST_APP" "wait05" "$$" "git-hook"
+
+  run /bin/bash -c "dokku builds:wait $TEST_APP wait03"
+  echo "output: $output"
+  echo "status: $status"
+  assert_equal "$status" 125
+
+  run /bin/bash -c "dokku builds:wait $TEST_APP wait04"
+  echo "output: $output"
+  echo "status: $status"
+  assert_equal "$status" 126
+
+  run /bin/bash -c "dokku builds:wait $TEST_APP wait05 --timeout 2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_equal "$status" 124
+}
+
+@test "(builds:wait --stream) prints the build log" {
+  write_finished_record "$TEST_APP" "wait06" "succeeded" "git-hook" 1234 "build"
+  echo "streamed log line" >"$DOKKU_LIB_ROOT/data/builds/$TEST_APP/wait06.log"
+  chown dokku:dokku "$DOKKU_LIB_ROOT/data/builds/$TEST_APP/wait06.log"
+
+  run /bin/bash -c "dokku builds:wait $TEST_APP wait06 --stream"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "streamed log line"
+}
+
+@test "(builds:set) validates webhook-url" {
+  run /bin/bash -c "dokku builds:set $TEST_APP webhook-url not-a-url"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid webhook-url"
+
+  run /bin/bash -c "dokku builds:set $TEST_APP webhook-url http://127.0.0.1:9/hook"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --builds-computed-webhook-url"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "http://127.0.0.1:9/hook"
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --builds-webhook-signed"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "false"
+}
+
+@test "(builds:webhooks) records failed deliveries for retry" {
+  run /bin/bash -c "dokku builds:set $TEST_APP webhook-url http://127.0.0.1:9/hook"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  build_id="$(dokku builds:report $TEST_APP --build-id)"
+  sleep 2
+
+  run /bin/bash -c "dokku builds:webhooks $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "build.started"
+  assert_output_contains "build.finished"
+  assert_output_contains "retrying"
+
+  run /bin/bash -c "dokku builds:webhooks $TEST_APP --format json | jq -r '.[] | select(.event == \"build.finished\") | .build_id'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "$build_id"
+}
+
 @test "(builds:prune) prunes an app's records to retention" {
   dokku builds:set "$TEST_APP" retention 2
 
@@ -286,6 +721,73 @@ EOF
   [[ "$count" -le 2 ]] || (echo "expected <=2 records, got $count" && false)
 }
 
+@test "(builds:set) validates retention-max-age, retention-max-bytes and compress-after" {
+  for value in "retention-max-age 30" "retention-max-age 0d" "retention-max-bytes 1.5G" "retention-max-bytes 0" "compress-after -1" "compress-after soon"; do
+    run /bin/bash -c "dokku builds:set $TEST_APP $value"
+    echo "output: $output"
+    echo "status: $status"
+    assert_failure
+  done
+
+  for value in "retention-max-age 30d" "retention-max-bytes 500M" "compress-after 5" "compress-after 7d"; do
+    run /bin/bash -c "dokku builds:set $TEST_APP $value"
+    echo "output: $output"
+    echo "status: $status"
+    assert_success
+  done
+
+  run /bin/bash -c "dokku builds:set --global retention-max-age 90d"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --builds-computed-retention-max-age"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "30d"
+
+  dokku builds:set --global retention-max-age
+}
+
+@test "(builds:prune) compresses old logs and builds:output reads them" {
+  dokku builds:set "$TEST_APP" compress-after 1
+
+  for i in 1 2 3; do
+    write_finished_record "$TEST_APP" "gz$i" "succeeded" "git-hook" $((1000 + i)) "build"
+    echo "log of gz$i" >"$DOKKU_LIB_ROOT/data/builds/$TEST_APP/gz$i.log"
+    sleep 1
+  done
+  chown -R dokku:dokku "$DOKKU_LIB_ROOT/data/builds/$TEST_APP"
+
+  run /bin/bash -c "dokku builds:prune $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  [[ -f "$DOKKU_LIB_ROOT/data/builds/$TEST_APP/gz3.log" ]]
+  [[ -f "$DOKKU_LIB_ROOT/data/builds/$TEST_APP/gz1.log.gz" ]]
+  [[ ! -f "$DOKKU_LIB_ROOT/data/builds/$TEST_APP/gz1.log" ]]
+
+  run /bin/bash -c "dokku builds:output $TEST_APP gz1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "log of gz1"
+
+  run /bin/bash -c "dokku builds:info $TEST_APP gz1 --format json | jq -r .log_path"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "$DOKKU_LIB_ROOT/data/builds/$TEST_APP/gz1.log.gz"
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --builds-disk-usage"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  [[ "$output" -gt 0 ]]
+}
+
 @test "(builds:prune) reaps an abandoned record and finalizes it as failed" {
   write_running_record "$TEST_APP" "ghost02" 99999 "git-hook"
 
@@ -317,6 +819,59 @@ EOF
   assert_output_contains "running"
 }
 
+@test "(builds:rollback) fails when there is no previous successful build" {
+  write_finished_record "$TEST_APP" "rb001" "succeeded" "git-hook" 1234 "build"
+
+  run /bin/bash -c "dokku builds:rollback $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "No previous successful build"
+
+  run /bin/bash -c "dokku builds:rollback $TEST_APP missing"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "No build record found"
+}
+
+@test "(builds:rollback) redeploys the image of the previous successful build" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  first_id="$(dokku builds:report $TEST_APP --build-id)"
+  run /bin/bash -c "dokku builds:info $TEST_APP $first_id --format json | jq -r .image"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "dokku/$TEST_APP:build-$first_id"
+
+  run /bin/bash -c "dokku ps:rebuild $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku builds:rollback $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Rolling back $TEST_APP to build $first_id"
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --build-source"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "builds:rollback"
+
+  run /bin/bash -c "dokku builds:report $TEST_APP --build-status"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "succeeded"
+}
+
 @test "(builds) [storage] directory is removed on apps:destroy" {
   write_finished_record "$TEST_APP" "del01" "succeeded" "git-hook" 1234 "build"
   [[ -d "$DOKKU_LIB_ROOT/data/builds/$TEST_APP" ]]
@@ -350,3 +905,19 @@ EOF
   assert_success
   assert_output "true"
 }
+
+add_failing_predeploy() {
+  local APP="$1"
+  local APP_REPO_DIR="$2"
+  [[ -z "$APP" ]] && local APP="$TEST_APP"
+
+  cat >"$APP_REPO_DIR/app.json" <<EOF
+{
+  "scripts": {
+    "dokku": {
+      "predeploy": "exit 1"
+    }
+  }
+}
+EOF
+}
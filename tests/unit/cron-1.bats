@@ -94,6 +94,58 @@ teardown() {
   assert_failure
 }
 
+@test "(cron) invalid [timezone]" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_invalid_timezone
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "unknown timezone"
+}
+
+@test "(cron) create [timezone-unsupported]" {
+  if host_cron_is_cronie; then
+    skip "the host cron daemon supports CRON_TZ"
+  fi
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid_timezone
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "the host cron daemon does not support CRON_TZ"
+}
+
+@test "(cron) create [timezone]" {
+  if ! host_cron_is_cronie; then
+    skip "the host cron daemon does not support CRON_TZ"
+  fi
+
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid_timezone
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  cron_id="$(dokku cron:list $TEST_APP --format json | jq -r '.[0].id')"
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --format json | jq -r '.[0].timezone'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "Europe/Berlin"
+
+  run /bin/bash -c "dokku cron:list $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Europe/Berlin"
+
+  run /bin/bash -c "cat /var/spool/cron/crontabs/dokku"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "CRON_TZ=Europe/Berlin"
+  assert_output_contains "dokku cron:run $TEST_APP $cron_id"
+}
+
 @test "(cron) create [single-verbose]" {
   run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid_single
   echo "output: $output"
@@ -228,6 +280,52 @@ template_cron_file_invalid_schedule_seconds() {
 EOF
 }
 
+host_cron_is_cronie() {
+  local binary
+  for binary in crond cron; do
+    if command -v "$binary" >/dev/null && "$binary" -V 2>&1 | grep -q cronie; then
+      return 0
+    fi
+  done
+  return 1
+}
+
+template_cron_file_invalid_timezone() {
+  local APP="$1"
+  local APP_REPO_DIR="$2"
+  [[ -z "$APP" ]] && local APP="$TEST_APP"
+  echo "injecting invalid cron app.json -> $APP_REPO_DIR/app.json"
+  cat <<EOF >"$APP_REPO_DIR/app.json"
+{
+  "cron": [
+    {
+      "command": "python3 task.py",
+      "schedule": "@daily",
+      "timezone": "Mars/Olympus_Mons"
+    }
+  ]
+}
+EOF
+}
+
+template_cron_file_valid_timezone() {
+  local APP="$1"
+  local APP_REPO_DIR="$2"
+  [[ -z "$APP" ]] && local APP="$TEST_APP"
+  echo "injecting valid cron app.json -> $APP_REPO_DIR/app.json"
+  cat <<EOF >"$APP_REPO_DIR/app.json"
+{
+  "cron": [
+    {
+      "command": "python3 task.py schedule",
+      "schedule": "0 9 * * *",
+      "timezone": "Europe/Berlin"
+    }
+  ]
+}
+EOF
+}
+
 template_cron_file_valid_single() {
   local APP="$1"
   local APP_REPO_DIR="$2"
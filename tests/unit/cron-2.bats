@@ -239,6 +239,65 @@ teardown() {
   assert_output_contains "Task maintenance properties cannot be set globally"
 }
 
+@test "(cron:set) notify-on and webhook-url" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:report $TEST_APP --cron-computed-notify-on"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "failure"
+
+  run /bin/bash -c "dokku cron:set $TEST_APP notify-on sometimes"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid notify-on"
+
+  run /bin/bash -c "dokku cron:set --global webhook-url ftp://example.com"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid webhook-url"
+
+  run /bin/bash -c "dokku cron:set --global webhook-url https://example.com/hooks/cron"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:report $TEST_APP --cron-computed-webhook-url"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "https://example.com/hooks/cron"
+
+  cron_id="$(dokku cron:list $TEST_APP --format json | jq -r '.[0].id')"
+  run /bin/bash -c "dokku cron:set $TEST_APP notify-on.$cron_id always"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:report $TEST_APP --cron-notify-on-$cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "always"
+
+  run /bin/bash -c "dokku cron:set $TEST_APP notify-on.fakeid always"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid task notify-on property, no matching task ID found"
+
+  run /bin/bash -c "dokku cron:set --global webhook-url"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+}
+
 @test "(cron) create [multiple]" {
   run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid_multiple
   echo "output: $output"
@@ -278,6 +337,138 @@ teardown() {
   assert_output_exists
 }
 
+@test "(cron) cron:list --next" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --next 3 --format json | jq -r '.[0].next_runs | length'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "3"
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --next 2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Next Run"
+  assert_output_contains "5 5 5 5 5" 2
+
+  cron_id="$(dokku cron:list $TEST_APP --format json | jq -r '.[0].id')"
+  run /bin/bash -c "dokku cron:suspend $TEST_APP $cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --next 2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "suspended (task)"
+  assert_output_contains "5 5 5 5 5" 1
+
+  run /bin/bash -c "dokku cron:list --global --next 2"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Concurrent"
+  assert_output_contains "$TEST_APP"
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --next -1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "--next must be a positive integer"
+}
+
+@test "(cron:add) cron:remove" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:add $TEST_APP '@nonstandard' 'python3 task.py repair'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku cron:add $TEST_APP '0 3 * * *' 'python3 task.py repair' --concurrency-policy sometimes"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid cron concurrency policy"
+
+  run /bin/bash -c "dokku cron:add $TEST_APP '0 3 * * *' 'python3 task.py repair' --concurrency-policy forbid"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Added cron task"
+
+  run /bin/bash -c "dokku cron:add $TEST_APP '0 3 * * *' 'python3 task.py repair'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "already exists"
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --format json | jq -r 'length'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "3"
+
+  cron_id="$(dokku cron:list $TEST_APP --format json | jq -r '.[] | select(.source == "cli") | .id')"
+  run /bin/bash -c "dokku cron:list $TEST_APP --format json | jq -r '.[] | select(.source == \"cli\") | .concurrency_policy'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "forbid"
+
+  run /bin/bash -c "dokku cron:list $TEST_APP"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Source"
+  assert_output_contains "app.json" 2
+
+  run /bin/bash -c "cat /var/spool/cron/crontabs/dokku"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "0 3 * * * dokku cron:run $TEST_APP $cron_id"
+
+  run /bin/bash -c "dokku cron:run $TEST_APP $cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "['task.py', 'repair']"
+
+  appjson_id="$(dokku cron:list $TEST_APP --format json | jq -r '.[0].id')"
+  run /bin/bash -c "dokku cron:remove $TEST_APP $appjson_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "is defined in app.json"
+
+  run /bin/bash -c "dokku cron:remove $TEST_APP $cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:list $TEST_APP --format json | jq -r 'length'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "2"
+
+  run /bin/bash -c "cat /var/spool/cron/crontabs/dokku"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "$cron_id" 0
+}
+
 @test "(cron) cron:run" {
   run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_valid
   echo "output: $output"
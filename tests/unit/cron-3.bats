@@ -142,6 +142,117 @@ teardown() {
   assert_failure
 }
 
+@test "(cron:history) records runs and concurrency policy skips" {
+  run deploy_app dockerfile dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_concurrency_forbid
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  cron_id="$(dokku cron:list $TEST_APP --format json | jq -r '.[0].id')"
+
+  run /bin/bash -c "dokku cron:history $TEST_APP --format json | jq -r 'length'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "0"
+
+  run /bin/bash -c "dokku cron:run $TEST_APP $cron_id --detach"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:run $TEST_APP $cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku cron:history $TEST_APP $cron_id --format json | jq -r '.[0].status'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "skipped"
+
+  run /bin/bash -c "dokku cron:history $TEST_APP $cron_id --format json | jq -r '.[1].status'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "detached"
+
+  run /bin/bash -c "dokku cron:history $TEST_APP $cron_id --format json | jq -r '.[1].container'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "$TEST_APP.cron."
+
+  run /bin/bash -c "dokku cron:report $TEST_APP --cron-last-status-$cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "skipped"
+
+  run /bin/bash -c "dokku cron:set $TEST_APP history-retention 0"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku cron:set $TEST_APP history-retention 1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku cron:run $TEST_APP $cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+
+  run /bin/bash -c "dokku cron:history $TEST_APP --format json | jq -r 'length'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "1"
+}
+
+@test "(cron:run) retries failed attempts" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_retries
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  cron_id="$(dokku cron:list $TEST_APP --format json | jq -r '.[0].id')"
+  run /bin/bash -c "dokku cron:list $TEST_APP --format json | jq -r '.[0].retries'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "2"
+
+  run /bin/bash -c "dokku cron:run $TEST_APP $cron_id"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "retrying in 0s (attempt 2/3)"
+  assert_output_contains "retrying in 0s (attempt 3/3)"
+
+  run /bin/bash -c "dokku cron:history $TEST_APP $cron_id --format json | jq -r '[.[] | .attempt] | join(\",\")'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "3,2,1"
+
+  run /bin/bash -c "dokku cron:history $TEST_APP $cron_id --format json | jq -r '[.[] | .status] | unique | join(\",\")'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "failed"
+}
+
+@test "(cron) invalid [retries]" {
+  run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_invalid_retries
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid cron retries"
+}
+
 @test "(cron) container labels regression" {
   run deploy_app python dokku@$DOKKU_DOMAIN:$TEST_APP template_cron_file_long_running
   echo "output: $output"
@@ -292,6 +403,43 @@ template_cron_file_concurrency_forbid() {
 EOF
 }
 
+template_cron_file_retries() {
+  local APP="$1"
+  local APP_REPO_DIR="$2"
+  [[ -z "$APP" ]] && local APP="$TEST_APP"
+  echo "injecting retrying cron app.json -> $APP_REPO_DIR/app.json"
+  cat <<EOF >"$APP_REPO_DIR/app.json"
+{
+  "cron": [
+    {
+      "command": "false",
+      "schedule": "0 0 * * *",
+      "retries": 2,
+      "retry_backoff_seconds": 0
+    }
+  ]
+}
+EOF
+}
+
+template_cron_file_invalid_retries() {
+  local APP="$1"
+  local APP_REPO_DIR="$2"
+  [[ -z "$APP" ]] && local APP="$TEST_APP"
+  echo "injecting invalid cron app.json -> $APP_REPO_DIR/app.json"
+  cat <<EOF >"$APP_REPO_DIR/app.json"
+{
+  "cron": [
+    {
+      "command": "python3 task.py",
+      "schedule": "0 0 * * *",
+      "retries": -1
+    }
+  ]
+}
+EOF
+}
+
 template_cron_file_long_running() {
   local APP="$1"
   local APP_REPO_DIR="$2"
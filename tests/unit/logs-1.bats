@@ -64,7 +64,7 @@ teardown() {
   echo "status: $status"
   assert_failure
   assert_output_contains "$TEST_APP logs information" 0
-  assert_output_contains "Invalid flag passed, valid flags: --logs-app-label-alias, --logs-computed-app-label-alias, --logs-computed-max-size, --logs-computed-vector-cron-sink, --logs-computed-vector-image, --logs-computed-vector-networks, --logs-computed-vector-sink, --logs-global-app-label-alias, --logs-global-max-size, --logs-global-vector-cron-sink, --logs-global-vector-image, --logs-global-vector-networks, --logs-global-vector-sink, --logs-max-size, --logs-vector-cron-sink, --logs-vector-sink"
+  assert_output_contains "Invalid flag passed, valid flags: --logs-app-label-alias, --logs-compress, --logs-computed-app-label-alias, --logs-computed-compress, --logs-computed-log-driver, --logs-computed-max-file, --logs-computed-max-size, --logs-computed-vector-cron-sink, --logs-computed-vector-image, --logs-computed-vector-networks, --logs-computed-vector-sink, --logs-global-app-label-alias, --logs-global-compress, --logs-global-log-driver, --logs-global-max-file, --logs-global-max-size, --logs-global-vector-cron-sink, --logs-global-vector-image, --logs-global-vector-networks, --logs-global-vector-sink, --logs-log-driver, --logs-max-file, --logs-max-size, --logs-vector-cron-sink, --logs-vector-sink"
 
   run /bin/bash -c "dokku logs:report $TEST_APP --logs-vector-sink 2>&1"
   echo "output: $output"
@@ -86,7 +86,7 @@ teardown() {
   echo "output: $output"
   echo "status: $status"
   assert_failure
-  assert_output_contains "Invalid flag passed, valid flags: --logs-computed-app-label-alias, --logs-computed-max-size, --logs-computed-vector-cron-sink, --logs-computed-vector-image, --logs-computed-vector-networks, --logs-computed-vector-sink, --logs-global-app-label-alias, --logs-global-max-size, --logs-global-vector-cron-sink, --logs-global-vector-image, --logs-global-vector-networks, --logs-global-vector-sink"
+  assert_output_contains "Invalid flag passed, valid flags: --logs-computed-app-label-alias, --logs-computed-compress, --logs-computed-log-driver, --logs-computed-max-file, --logs-computed-max-size, --logs-computed-vector-cron-sink, --logs-computed-vector-image, --logs-computed-vector-networks, --logs-computed-vector-sink, --logs-global-app-label-alias, --logs-global-compress, --logs-global-log-driver, --logs-global-max-file, --logs-global-max-size, --logs-global-vector-cron-sink, --logs-global-vector-image, --logs-global-vector-networks, --logs-global-vector-sink"
 }
 
 @test "(logs) logs:set [error]" {
@@ -117,13 +117,13 @@ teardown() {
   echo "output: $output"
   echo "status: $status"
   assert_failure
-  assert_output_contains "Invalid property specified, valid properties include: app-label-alias, max-size, vector-cron-sink, vector-image, vector-networks, vector-sink"
+  assert_output_contains "Invalid property specified, valid properties include: app-label-alias, compress, log-driver, max-file, max-size, vector-cron-sink, vector-image, vector-networks, vector-sink"
 
   run /bin/bash -c "dokku logs:set $TEST_APP invalid value" 2>&1
   echo "output: $output"
   echo "status: $status"
   assert_failure
-  assert_output_contains "Invalid property specified, valid properties include: app-label-alias, max-size, vector-cron-sink, vector-image, vector-networks, vector-sink"
+  assert_output_contains "Invalid property specified, valid properties include: app-label-alias, compress, log-driver, max-file, max-size, vector-cron-sink, vector-image, vector-networks, vector-sink"
 
   run /bin/bash -c "dokku logs:set $TEST_APP vector-image timberio/vector:latest-debian 2>&1"
   echo "output: $output"
@@ -692,6 +692,72 @@ teardown() {
   assert_success
 }
 
+@test "(logs) vector.json process type routing" {
+  run create_app
+  assert_success
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink. console://?encoding[codec]=json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid vector-sink property, missing process type"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink.web console://?encoding[codec]=json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Setting vector-sink.web"
+  assert_output_contains "Writing updated vector config to /var/lib/dokku/data/logs/vector.json"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink console://?encoding[codec]=text"
+  assert_success
+
+  run /bin/bash -c "jq -r '.transforms[\"docker-router:$TEST_APP\"].route[\"process-web\"].source' /var/lib/dokku/data/logs/vector.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains 'com.dokku.process-type" == "web"'
+
+  run /bin/bash -c "jq -r '.sinks[\"docker-sink:$TEST_APP:web\"].inputs[0]' /var/lib/dokku/data/logs/vector.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "docker-router:$TEST_APP.process-web"
+
+  run /bin/bash -c "jq -r '.sinks[\"docker-sink:$TEST_APP\"].inputs[0]' /var/lib/dokku/data/logs/vector.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "docker-router:$TEST_APP._unmatched"
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-vector-sink-web 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "console://?encoding[codec]=json"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink.web"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Unsetting vector-sink.web"
+
+  run /bin/bash -c "jq -r '.sinks[\"docker-sink:$TEST_APP:web\"]' /var/lib/dokku/data/logs/vector.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "null"
+
+  run /bin/bash -c "jq -r '.transforms' /var/lib/dokku/data/logs/vector.json"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "null"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink"
+  assert_success
+}
+
 @test "(logs:report) global-vector-image and global-vector-networks raw" {
   run create_app
   assert_success
@@ -1235,6 +1301,107 @@ teardown() {
   assert_output "--restart=on-failure:10"
 }
 
+@test "(logs) logs:set max-file log-driver compress" {
+  run create_app
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku logs:set $TEST_APP max-file 0" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid max-file value, must be a positive integer"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP compress yes" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid compress value, must be either true or false"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP log-driver syslog" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid log-driver value, must be one of [journald, json-file, local]"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP log-driver local" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Setting log-driver"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP max-file 5" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Setting max-file"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP compress true" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Setting compress"
+
+  run /bin/bash -c "echo '' | dokku plugin:trigger docker-args-process-deploy $TEST_APP 2>&1 | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "--log-driver=local --log-opt=max-size=10m --log-opt=max-file=5 --log-opt=compress=true --restart=on-failure:10"
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-computed-log-driver 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "local"
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-computed-max-file 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "5"
+
+  run /bin/bash -c "dokku docker-options:add $TEST_APP deploy --log-driver=json-file" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "echo '' | dokku plugin:trigger docker-args-process-deploy $TEST_APP 2>&1 | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "--log-opt=max-size=10m --log-opt=max-file=5 --log-opt=compress=true --restart=on-failure:10"
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-computed-log-driver 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "json-file"
+
+  run /bin/bash -c "dokku docker-options:remove $TEST_APP deploy --log-driver=json-file" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku logs:set $TEST_APP log-driver journald" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Setting log-driver"
+
+  run /bin/bash -c "echo '' | dokku plugin:trigger docker-args-process-deploy $TEST_APP 2>&1 | xargs"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "--log-driver=journald --restart=on-failure:10"
+
+  run /bin/bash -c "dokku plugin:trigger logs-get-property $TEST_APP max-file" 2>&1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "5"
+}
+
 @test "(logs:report) emits new stripped JSON keys alongside legacy" {
   run create_app
   assert_success
@@ -78,6 +78,59 @@ teardown() {
   assert_success
 }
 
+@test "(logs) logs:vector-validate" {
+  run create_app
+  assert_success
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink console://?encoding[codec]=json"
+  assert_success
+
+  run /bin/bash -c "dokku logs:vector-validate 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "docker-sink:$TEST_APP"
+  assert_output_contains "Vector config is valid"
+
+  run /bin/bash -c "dokku logs:vector-validate --format toml 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "[sinks.\"docker-sink:$TEST_APP\"]"
+
+  # vector rejects unknown sink options, which the DSN parser passes through
+  run /bin/bash -c "dokku logs:set --validate $TEST_APP vector-sink 'console://?encoding[codec]=json&not_an_option=true' 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Vector config is invalid"
+
+  run /bin/bash -c "dokku logs:report $TEST_APP --logs-vector-sink 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "console://?encoding[codec]=json"
+
+  # without --validate the sink is persisted, and the rendered config fails
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink 'console://?encoding[codec]=json&not_an_option=true' 2>&1"
+  assert_success
+
+  run /bin/bash -c "dokku logs:vector-validate 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Vector config is invalid"
+
+  run /bin/bash -c "dokku logs:set --validate $TEST_APP vector-sink console://?encoding[codec]=text 2>&1"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Setting vector-sink"
+
+  run /bin/bash -c "dokku logs:set $TEST_APP vector-sink"
+  assert_success
+}
+
 # the regression test for the alias silently disabling collection: the source
 # has to keep filtering on the label dokku applies, while the event that comes
 # out the other end carries the alias instead
@@ -315,6 +368,54 @@ teardown() {
   assert_success
 }
 
+@test "(logs) logs --format json --grep --since" {
+  run deploy_app python
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku logs $TEST_APP --format yaml"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid format specified"
+
+  run /bin/bash -c "dokku logs $TEST_APP --since yesterday"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Invalid --since"
+
+  run /bin/bash -c "dokku logs $TEST_APP --tail --until 1m"
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "--until cannot be combined with --tail"
+
+  run /bin/bash -c "dokku logs $TEST_APP --format json -p web | jq -e -s 'length > 0 and all(.app == \"$TEST_APP\" and .process_type == \"web\" and .container_index == 1 and (.stream == \"stdout\" or .stream == \"stderr\"))'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+
+  run /bin/bash -c "dokku logs $TEST_APP --format json --grep 'Arg: first' | jq -r '.message'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output "Arg: first.Procfile"
+
+  run /bin/bash -c "dokku logs $TEST_APP --since 1h --grep 'Arg: web'"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "app[web.1]: Arg: web.py"
+
+  run /bin/bash -c "dokku logs $TEST_APP --since 1h --until 1m --grep 'Arg: web' --quiet"
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output ""
+}
+
 wait_for_vector_route() {
   declare desc="waits for the vector config on disk to contain the app's cron router"
   declare APP="$1"